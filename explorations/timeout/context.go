@@ -2,32 +2,71 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"time"
+
+	"github.com/fabricioandreis/design-patterns-go/patterns/behavioral"
 )
 
 type response struct {
 	url     string
 	status  string
 	latency time.Duration
+	err     error
 }
 
-func get(c context.Context, url string, ch chan<- response) {
+func get(c context.Context, url string, retrier *behavioral.Retrier, ch chan<- response) {
 	start := time.Now()
-	req, err := http.NewRequestWithContext(c, "GET", url, nil)
-	if err != nil {
-		log.Fatalf("Error when creating request with context: %s", err.Error())
-		return
-	}
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		log.Fatal("error when getting url "+url, err)
+	var status string
+	err := retrier.Do(c, func(c context.Context) error {
+		req, err := http.NewRequestWithContext(c, "GET", url, nil)
+		if err != nil {
+			return behavioral.Permanent(fmt.Errorf("creating request: %w", err))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		status = resp.Status
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return fmt.Errorf("server error: %s", resp.Status)
+		}
+		return nil
+	})
+	ch <- response{url: url, status: status, latency: time.Since(start).Round(time.Millisecond), err: err}
+}
+
+func retryPolicy(name string, retries int) (behavioral.RetryPolicy, error) {
+	switch name {
+	case "none":
+		return behavioral.ConstantBackoff{}, nil
+	case "constant":
+		return behavioral.ConstantBackoff{Delay: 200 * time.Millisecond, MaxRetries: retries}, nil
+	case "linear":
+		return behavioral.LinearBackoff{Initial: 100 * time.Millisecond, Increment: 100 * time.Millisecond, MaxRetries: retries}, nil
+	case "exponential":
+		return behavioral.ExponentialBackoff{Initial: 100 * time.Millisecond, Max: time.Second, MaxRetries: retries}, nil
+	case "jitter":
+		return behavioral.DecorrelatedJitter{Base: 100 * time.Millisecond, Cap: time.Second, MaxRetries: retries}, nil
 	}
-	ch <- response{url: url, status: resp.Status, latency: time.Since(start).Round(time.Millisecond)}
+	return nil, fmt.Errorf("unknown retry policy %q", name)
 }
 
 func main() {
+	policyName := flag.String("retry", "exponential", "retry policy: none, constant, linear, exponential or jitter")
+	retries := flag.Int("retries", 3, "maximum number of retries")
+	timeout := flag.Duration("timeout", 2*time.Second, "time allowed for all the requests")
+	flag.Parse()
+
+	policy, err := retryPolicy(*policyName, *retries)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	urls := []string{
 		"https://google.com",
@@ -35,18 +74,40 @@ func main() {
 		"https://facebook.com",
 		"https://linkedin.com",
 		"http://localhost:5000",
+		"http://localhost:5000/health",
 	}
 
-	c, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	c, cancel := context.WithTimeout(context.Background(), *timeout)
 	defer cancel()
 	ch := make(chan response, len(urls))
 
+	// one breaker per host, shared by its requests, so that a failing host does not keep being retried by every request.
+	// It opens before a single request runs out of retries.
+	threshold := *retries
+	if threshold < 1 {
+		threshold = 1
+	}
+	breakers := map[string]*behavioral.CircuitBreaker{}
 	for _, u := range urls {
-		go get(c, u, ch)
+		parsed, err := url.Parse(u)
+		if err != nil {
+			log.Fatal(err)
+		}
+		breaker, ok := breakers[parsed.Host]
+		if !ok {
+			breaker = behavioral.NewCircuitBreaker(threshold, 10*time.Second, nil)
+			breakers[parsed.Host] = breaker
+		}
+		retrier := behavioral.NewRetrier(behavioral.CircuitBreakerPolicy{Policy: policy, Breaker: breaker})
+		go get(c, u, retrier, ch)
 	}
 
 	for range urls {
 		resp := <-ch
+		if resp.err != nil {
+			log.Printf("GET %s: failed in %s: %v\n", resp.url, resp.latency, resp.err)
+			continue
+		}
 		log.Printf("GET %s: %s in %s\n", resp.url, resp.status, resp.latency)
 	}
 }
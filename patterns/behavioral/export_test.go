@@ -0,0 +1,109 @@
+package behavioral
+
+import (
+	"fmt"
+	"time"
+)
+
+var ExportSetChatRoomClock = func(r *ChatRoom, now func() time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.now = now
+}
+
+var ExportTamperMemento = func(m *Memento, balance float64) *Memento {
+	forged := *m
+	forged.balance = balance
+	return &forged
+}
+
+func ExportSetTournamentClock[M comparable](t *Tournament[M], now func() time.Time) {
+	t.now = now
+}
+
+// ExportCheckOrderedTree reports the first red-black, ordering or parent pointer invariant the tree breaks
+func ExportCheckOrderedTree[K, V any](t *OrderedTree[K, V]) error {
+	if t.root.red {
+		return fmt.Errorf("red root")
+	}
+	if t.root != t.leaf && t.root.parent != t.leaf {
+		return fmt.Errorf("root has a parent")
+	}
+	count := 0
+	var check func(n *rbNode[K, V]) (int, error)
+	check = func(n *rbNode[K, V]) (int, error) {
+		if n == t.leaf {
+			return 1, nil
+		}
+		count++
+		for _, child := range []*rbNode[K, V]{n.left, n.right} {
+			if child == t.leaf {
+				continue
+			}
+			if child.parent != n {
+				return 0, fmt.Errorf("wrong parent for %v", child.key)
+			}
+			if n.red && child.red {
+				return 0, fmt.Errorf("red node %v has a red child", n.key)
+			}
+		}
+		if n.left != t.leaf && !t.less(n.left.key, n.key) || n.right != t.leaf && !t.less(n.key, n.right.key) {
+			return 0, fmt.Errorf("keys out of order at %v", n.key)
+		}
+		left, err := check(n.left)
+		if err != nil {
+			return 0, err
+		}
+		right, err := check(n.right)
+		if err != nil {
+			return 0, err
+		}
+		if left != right {
+			return 0, fmt.Errorf("unequal black heights below %v", n.key)
+		}
+		if n.red {
+			return left, nil
+		}
+		return left + 1, nil
+	}
+	if _, err := check(t.root); err != nil {
+		return err
+	}
+	if count != t.size {
+		return fmt.Errorf("size %d, counted %d", t.size, count)
+	}
+	return nil
+}
+
+func ExportOrderedTreeHeight[K, V any](t *OrderedTree[K, V]) int {
+	var height func(n *rbNode[K, V]) int
+	height = func(n *rbNode[K, V]) int {
+		if n == t.leaf {
+			return 0
+		}
+		l, r := height(n.left), height(n.right)
+		if l > r {
+			return l + 1
+		}
+		return r + 1
+	}
+	return height(t.root)
+}
+
+// ExportBreakMessageStoreFile closes the file under the store, so that its next writes fail
+var ExportBreakMessageStoreFile = func(s *FileMessageStore) {
+	s.file.Close()
+}
+
+// ExportSetMementoOrigin passes m off as produced by the instance that produced other
+var ExportSetMementoOrigin = func(m, other *Memento) *Memento {
+	forged := *m
+	forged.instance = other.instance
+	return &forged
+}
+
+var ExportSystemSecretHash = func(s *SystemSecret) (salt, hash []byte) {
+	return s.salt, s.hash
+}
+
+var ExportPBKDF2 = pbkdf2
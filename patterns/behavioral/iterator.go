@@ -1,5 +1,7 @@
 package behavioral
 
+import "context"
+
 // Iterator is a behavioral design pattern that lets you traverse elements of a collection without exposing its underlying representation (list, stack, tree, etc.).
 
 // https://refactoring.guru/design-patterns/iterator
@@ -12,17 +14,14 @@ func (p *Person) Names() [3]string {
 	return [3]string{p.FirstName, p.MiddleName, p.LastName}
 }
 
-func (p *Person) NamesGenerator() <-chan string {
-	out := make(chan string)
-	go func() {
-		defer close(out)
-		out <- p.FirstName
-		if len(p.MiddleName) > 0 {
-			out <- p.MiddleName
-		}
-		out <- p.LastName
-	}()
-	return out
+// NamesGenerator sends the names until they run out, ctx is canceled or the consumer stops the generator
+func (p *Person) NamesGenerator(ctx context.Context) *Generator[string] {
+	names := []string{p.FirstName}
+	if len(p.MiddleName) > 0 {
+		names = append(names, p.MiddleName)
+	}
+	names = append(names, p.LastName)
+	return GenerateSlice(ctx, 0, names)
 }
 
 type PersonNameIterator struct {
@@ -51,6 +50,14 @@ func (p *PersonNameIterator) Value() string {
 	panic("We should not be here!")
 }
 
+// Next makes the iterator an Iterator[string]
+func (p *PersonNameIterator) Next() (string, bool) {
+	if !p.MoveNext() {
+		return "", false
+	}
+	return p.Value(), true
+}
+
 type Node struct {
 	Value               int
 	left, right, parent *Node
@@ -75,61 +82,169 @@ func NewNode(value int, left, right *Node) *Node {
 	return n
 }
 
-type InOrderIterator struct {
-	Current       *Node
-	root          *Node
-	returnedStart bool
+// TreeIterator walks a tree in any order, following the parent pointers rather than keeping a stack.
+// Only the level order needs to remember nodes, in a queue reused from one step to the next.
+type TreeIterator struct {
+	Current *Node
+	root    *Node
+	first   func(root *Node) *Node
+	next    func(current *Node) *Node
+	started bool
 }
 
-func NewInOrderIterator(root *Node) *InOrderIterator {
-	i := &InOrderIterator{
-		Current:       root,
-		root:          root,
-		returnedStart: false,
-	}
-	return i.traverseToLeftMost()
+// InOrderIterator is the name the iterator had when it only walked in order
+type InOrderIterator = TreeIterator
+
+func newTreeIterator(root *Node, first, next func(*Node) *Node) *TreeIterator {
+	i := &TreeIterator{root: root, first: first, next: next}
+	i.Reset()
+	return i
+}
+
+func NewInOrderIterator(root *Node) *TreeIterator {
+	return newTreeIterator(root, leftMost, inOrderSuccessor)
+}
+
+func NewReverseInOrderIterator(root *Node) *TreeIterator {
+	return newTreeIterator(root, rightMost, inOrderPredecessor)
+}
+
+func NewPreOrderIterator(root *Node) *TreeIterator {
+	return newTreeIterator(root, func(root *Node) *Node { return root }, preOrderSuccessor)
 }
 
-func (i *InOrderIterator) traverseToLeftMost() *InOrderIterator {
-	for i.Current.left != nil { // traverse to the left-most node
-		i.Current = i.Current.left
+func NewPostOrderIterator(root *Node) *TreeIterator {
+	return newTreeIterator(root, firstPostOrder, postOrderSuccessor)
+}
+
+// NewLevelOrderIterator walks the tree breadth first, from left to right
+func NewLevelOrderIterator(root *Node) *TreeIterator {
+	queue := []*Node{}
+	first := func(root *Node) *Node {
+		queue = queue[:0]
+		return root
 	}
-	return i
+	next := func(current *Node) *Node {
+		if current.left != nil {
+			queue = append(queue, current.left)
+		}
+		if current.right != nil {
+			queue = append(queue, current.right)
+		}
+		if len(queue) == 0 {
+			return nil
+		}
+		n := queue[0]
+		queue = queue[1:]
+		return n
+	}
+	return newTreeIterator(root, first, next)
 }
 
-func (i *InOrderIterator) Reset() {
-	i.Current = i.root
-	i.returnedStart = false
+// Reset moves back before the first node
+func (i *TreeIterator) Reset() {
+	i.Current = nil
+	if i.root != nil {
+		i.Current = i.first(i.root)
+	}
+	i.started = false
 }
 
-func (i *InOrderIterator) MoveNext() bool {
+func (i *TreeIterator) MoveNext() bool {
 	if i.Current == nil {
 		return false
 	}
-	if !i.returnedStart {
-		i.returnedStart = true
+	if !i.started {
+		i.started = true
 		return true
 	}
+	i.Current = i.next(i.Current)
+	return i.Current != nil
+}
+
+func (i *TreeIterator) Value() int {
+	return i.Current.Value
+}
+
+// Next makes the iterator an Iterator[int]
+func (i *TreeIterator) Next() (int, bool) {
+	if !i.MoveNext() {
+		return 0, false
+	}
+	return i.Value(), true
+}
+
+func leftMost(n *Node) *Node {
+	for n.left != nil {
+		n = n.left
+	}
+	return n
+}
+
+func rightMost(n *Node) *Node {
+	for n.right != nil {
+		n = n.right
+	}
+	return n
+}
+
+func inOrderSuccessor(n *Node) *Node {
+	if n.right != nil {
+		return leftMost(n.right)
+	}
+	for n.parent != nil && n == n.parent.right {
+		n = n.parent
+	}
+	return n.parent
+}
+
+func inOrderPredecessor(n *Node) *Node {
+	if n.left != nil {
+		return rightMost(n.left)
+	}
+	for n.parent != nil && n == n.parent.left {
+		n = n.parent
+	}
+	return n.parent
+}
 
-	if i.Current.right != nil {
-		i.Current = i.Current.right
-		for i.Current.left != nil {
-			i.Current = i.Current.left
+// preOrderSuccessor goes down to the first child, or else up to the closest right sibling of an ancestor
+func preOrderSuccessor(n *Node) *Node {
+	if n.left != nil {
+		return n.left
+	}
+	if n.right != nil {
+		return n.right
+	}
+	for n.parent != nil {
+		if n == n.parent.left && n.parent.right != nil {
+			return n.parent.right
 		}
-		return true
-	} else {
-		p := i.Current.parent
-		for p != nil && i.Current == p.right {
-			i.Current = p
-			p = p.parent
+		n = n.parent
+	}
+	return nil
+}
+
+// firstPostOrder is the leaf reached by going left whenever possible, and right otherwise
+func firstPostOrder(n *Node) *Node {
+	for {
+		switch {
+		case n.left != nil:
+			n = n.left
+		case n.right != nil:
+			n = n.right
+		default:
+			return n
 		}
-		i.Current = p
-		return i.Current != nil
 	}
 }
 
-func (i *InOrderIterator) Value() int {
-	return i.Current.Value
+func postOrderSuccessor(n *Node) *Node {
+	p := n.parent
+	if p != nil && n == p.left && p.right != nil {
+		return firstPostOrder(p.right)
+	}
+	return p
 }
 
 type BinaryTree struct {
@@ -140,6 +255,22 @@ func NewBinaryTree(root *Node) *BinaryTree {
 	return &BinaryTree{root}
 }
 
-func (b *BinaryTree) InOrder() *InOrderIterator {
+func (b *BinaryTree) InOrder() *TreeIterator {
 	return NewInOrderIterator(b.root)
 }
+
+func (b *BinaryTree) ReverseInOrder() *TreeIterator {
+	return NewReverseInOrderIterator(b.root)
+}
+
+func (b *BinaryTree) PreOrder() *TreeIterator {
+	return NewPreOrderIterator(b.root)
+}
+
+func (b *BinaryTree) PostOrder() *TreeIterator {
+	return NewPostOrderIterator(b.root)
+}
+
+func (b *BinaryTree) LevelOrder() *TreeIterator {
+	return NewLevelOrderIterator(b.root)
+}
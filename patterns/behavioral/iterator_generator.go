@@ -0,0 +1,95 @@
+package behavioral
+
+import (
+	"context"
+	"sync"
+)
+
+// Channel-based generators: a producer runs in its own goroutine and sends values over a channel.
+// A bare goroutine blocks forever on its send when the consumer stops reading early, so Generate ties the producer to
+// a context and stops it as soon as the context is canceled or the consumer calls Stop.
+
+// Yield sends a value to the consumer, and returns false when the producer must stop
+type Yield[T any] func(T) bool
+
+type Generator[T any] struct {
+	values <-chan T
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// Generate starts produce in a goroutine. Up to lookahead values are buffered, so the producer can run ahead of the
+// consumer. The error returned by produce, or the context error when it stopped early, is reported by Err.
+func Generate[T any](ctx context.Context, lookahead int, produce func(ctx context.Context, yield Yield[T]) error) *Generator[T] {
+	if lookahead < 0 {
+		lookahead = 0
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	out := make(chan T, lookahead)
+	g := &Generator[T]{values: out, cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		defer close(g.done)
+		defer close(out)
+		defer cancel()
+
+		stopped := false
+		err := produce(ctx, func(v T) bool {
+			if stopped {
+				return false
+			}
+			select {
+			case out <- v:
+				return true
+			case <-ctx.Done():
+				stopped = true
+				return false
+			}
+		})
+		if err == nil && stopped {
+			err = ctx.Err()
+		}
+		g.mu.Lock()
+		g.err = err
+		g.mu.Unlock()
+	}()
+	return g
+}
+
+// Values is closed once the producer has returned
+func (g *Generator[T]) Values() <-chan T {
+	return g.values
+}
+
+func (g *Generator[T]) Next() (T, bool) {
+	v, ok := <-g.values
+	return v, ok
+}
+
+// Err is the error the producer stopped with, once Values is closed
+func (g *Generator[T]) Err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+// Stop cancels the producer and waits for its goroutine to exit. Buffered values are still readable.
+func (g *Generator[T]) Stop() {
+	g.cancel()
+	<-g.done
+}
+
+// GenerateSlice yields the values of s in order
+func GenerateSlice[T any](ctx context.Context, lookahead int, s []T) *Generator[T] {
+	return Generate(ctx, lookahead, func(ctx context.Context, yield Yield[T]) error {
+		for _, v := range s {
+			if !yield(v) {
+				break
+			}
+		}
+		return nil
+	})
+}
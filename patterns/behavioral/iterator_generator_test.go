@@ -0,0 +1,145 @@
+package behavioral_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/fabricioandreis/design-patterns-go/patterns/behavioral"
+	"github.com/stretchr/testify/assert"
+)
+
+// assertNoLeak fails when the goroutines started by f are still running shortly after it returns
+func assertNoLeak(t *testing.T, f func()) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	f()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("leaked %d goroutines", runtime.NumGoroutine()-before)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	t.Run("Should not leak the names generator when the reader stops early", func(t *testing.T) {
+		p := behavioral.Person{"Alexander", "Graham", "Bell"}
+
+		assertNoLeak(t, func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			for i := 0; i < 10; i++ {
+				first, _ := p.NamesGenerator(ctx).Next()
+				assert.Equal(t, "Alexander", first)
+			}
+		})
+	})
+
+	t.Run("Should not leak the chat log generator when the reader stops early", func(t *testing.T) {
+		room := behavioral.NewChatRoom("general")
+		john, jane := behavioral.NewChatUser("John"), behavioral.NewChatUser("Jane")
+		room.Join(john)
+		room.Join(jane)
+		jane.Say("one")
+		jane.Say("two")
+
+		assertNoLeak(t, func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			for i := 0; i < 10; i++ {
+				john.ChatLog(ctx).Next()
+			}
+		})
+	})
+
+	t.Run("Should not leak the generators stopped early without a context to cancel", func(t *testing.T) {
+		p := behavioral.Person{"Alexander", "Graham", "Bell"}
+		john := behavioral.NewChatUser("John")
+		john.Receive("Jane", "one")
+		john.Receive("Jane", "two")
+
+		assertNoLeak(t, func() {
+			for i := 0; i < 10; i++ {
+				names := p.NamesGenerator(context.Background())
+				first, _ := names.Next()
+				assert.Equal(t, "Alexander", first)
+				names.Stop()
+				assert.ErrorIs(t, names.Err(), context.Canceled)
+
+				logs := john.ChatLog(context.Background())
+				logs.Next()
+				logs.Stop()
+			}
+		})
+	})
+
+	t.Run("Should stop the producer on Stop and report the cancellation", func(t *testing.T) {
+		var g *behavioral.Generator[int]
+		assertNoLeak(t, func() {
+			g = behavioral.Generate(context.Background(), 0, func(ctx context.Context, yield behavioral.Yield[int]) error {
+				for i := 0; yield(i); i++ {
+				}
+				return nil
+			})
+			assert.Equal(t, []int{0, 1, 2}, behavioral.Collect(behavioral.Take[int](g, 3)))
+			g.Stop()
+		})
+
+		assert.ErrorIs(t, g.Err(), context.Canceled)
+	})
+
+	t.Run("Should propagate the producer error after the values", func(t *testing.T) {
+		boom := errors.New("boom")
+		g := behavioral.Generate(context.Background(), 0, func(ctx context.Context, yield behavioral.Yield[string]) error {
+			yield("a")
+			return boom
+		})
+
+		var got []string
+		for v := range g.Values() {
+			got = append(got, v)
+		}
+
+		assert.Equal(t, []string{"a"}, got)
+		assert.ErrorIs(t, g.Err(), boom)
+	})
+
+	t.Run("Should let the producer run ahead by the lookahead", func(t *testing.T) {
+		produced := make(chan int, 10)
+		g := behavioral.Generate(context.Background(), 3, func(ctx context.Context, yield behavioral.Yield[int]) error {
+			for i := 0; i < 10; i++ {
+				if !yield(i) {
+					return nil
+				}
+				produced <- i
+			}
+			return nil
+		})
+		defer g.Stop()
+
+		for len(produced) < 3 {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, 3, len(produced), "the producer blocks once the lookahead is full")
+
+		v, ok := g.Next()
+		assert.True(t, ok)
+		assert.Equal(t, 0, v)
+	})
+
+	t.Run("Should stop when the parent context expires", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		g := behavioral.GenerateSlice(ctx, 0, []int{1, 2, 3})
+
+		<-ctx.Done()
+		g.Stop()
+
+		assert.ErrorIs(t, g.Err(), context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,146 @@
+package behavioral
+
+// Generic iterators: a single interface, converters from the usual collections, and lazy adapters to compose them.
+// Adapters pull values one at a time from the iterators they wrap, so nothing is computed until it is asked for,
+// and no goroutine is needed as with the channel-based generators.
+
+type Iterator[T any] interface {
+	// Next returns the next value, or false when there are no more
+	Next() (T, bool)
+}
+
+// IteratorFunc turns a function into an Iterator
+type IteratorFunc[T any] func() (T, bool)
+
+func (f IteratorFunc[T]) Next() (T, bool) {
+	return f()
+}
+
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+func FromSlice[T any](s []T) Iterator[T] {
+	i := 0
+	return IteratorFunc[T](func() (T, bool) {
+		if i >= len(s) {
+			var zero T
+			return zero, false
+		}
+		i++
+		return s[i-1], true
+	})
+}
+
+// FromMap iterates over the keys the map has when it is called, in no particular order.
+// Entries deleted since then are skipped, and their values are the current ones.
+func FromMap[K comparable, V any](m map[K]V) Iterator[Pair[K, V]] {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	i := 0
+	return IteratorFunc[Pair[K, V]](func() (Pair[K, V], bool) {
+		for i < len(keys) {
+			k := keys[i]
+			i++
+			if v, ok := m[k]; ok {
+				return Pair[K, V]{k, v}, true
+			}
+		}
+		return Pair[K, V]{}, false
+	})
+}
+
+// FromChannel iterates until the channel is closed
+func FromChannel[T any](ch <-chan T) Iterator[T] {
+	return IteratorFunc[T](func() (T, bool) {
+		v, ok := <-ch
+		return v, ok
+	})
+}
+
+func Map[T, U any](it Iterator[T], f func(T) U) Iterator[U] {
+	return IteratorFunc[U](func() (U, bool) {
+		v, ok := it.Next()
+		if !ok {
+			var zero U
+			return zero, false
+		}
+		return f(v), true
+	})
+}
+
+func Filter[T any](it Iterator[T], keep func(T) bool) Iterator[T] {
+	return IteratorFunc[T](func() (T, bool) {
+		for {
+			v, ok := it.Next()
+			if !ok || keep(v) {
+				return v, ok
+			}
+		}
+	})
+}
+
+// Take stops after n values, without pulling any more from it
+func Take[T any](it Iterator[T], n int) Iterator[T] {
+	return IteratorFunc[T](func() (T, bool) {
+		if n <= 0 {
+			var zero T
+			return zero, false
+		}
+		n--
+		return it.Next()
+	})
+}
+
+// Skip drops the first n values, when the first value is asked for
+func Skip[T any](it Iterator[T], n int) Iterator[T] {
+	return IteratorFunc[T](func() (T, bool) {
+		for ; n > 0; n-- {
+			if _, ok := it.Next(); !ok {
+				n = 0
+				break
+			}
+		}
+		return it.Next()
+	})
+}
+
+// Zip pairs the values of both iterators, stopping with the shorter one
+func Zip[A, B any](a Iterator[A], b Iterator[B]) Iterator[Pair[A, B]] {
+	return IteratorFunc[Pair[A, B]](func() (Pair[A, B], bool) {
+		first, ok := a.Next()
+		if !ok {
+			return Pair[A, B]{}, false
+		}
+		second, ok := b.Next()
+		if !ok {
+			return Pair[A, B]{}, false
+		}
+		return Pair[A, B]{first, second}, true
+	})
+}
+
+// Chain iterates over each iterator in turn
+func Chain[T any](its ...Iterator[T]) Iterator[T] {
+	return IteratorFunc[T](func() (T, bool) {
+		for len(its) > 0 {
+			if v, ok := its[0].Next(); ok {
+				return v, true
+			}
+			its = its[1:]
+		}
+		var zero T
+		return zero, false
+	})
+}
+
+func Collect[T any](it Iterator[T]) []T {
+	values := []T{}
+	for v, ok := it.Next(); ok; v, ok = it.Next() {
+		values = append(values, v)
+	}
+	return values
+}
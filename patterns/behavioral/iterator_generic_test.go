@@ -0,0 +1,144 @@
+package behavioral_test
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/fabricioandreis/design-patterns-go/patterns/behavioral"
+	"github.com/stretchr/testify/assert"
+)
+
+// counter iterates over the natural numbers forever, counting how many were pulled
+type counter struct {
+	next int
+}
+
+func (c *counter) Next() (int, bool) {
+	c.next++
+	return c.next - 1, true
+}
+
+func TestGenericIterator(t *testing.T) {
+	t.Run("Should convert slices, maps and channels", func(t *testing.T) {
+		assert.Equal(t, []int{1, 2, 3}, behavioral.Collect(behavioral.FromSlice([]int{1, 2, 3})))
+		assert.Equal(t, []int{}, behavioral.Collect(behavioral.FromSlice([]int(nil))))
+
+		pairs := behavioral.Collect(behavioral.FromMap(map[string]int{"a": 1, "b": 2}))
+		sort.Slice(pairs, func(i, j int) bool { return pairs[i].First < pairs[j].First })
+		assert.Equal(t, []behavioral.Pair[string, int]{{"a", 1}, {"b", 2}}, pairs)
+
+		ch := make(chan string, 2)
+		ch <- "x"
+		ch <- "y"
+		close(ch)
+		assert.Equal(t, []string{"x", "y"}, behavioral.Collect(behavioral.FromChannel(ch)))
+	})
+
+	t.Run("Should skip map entries deleted during the iteration", func(t *testing.T) {
+		m := map[int]bool{1: true, 2: true, 3: true}
+		it := behavioral.FromMap(m)
+		first, _ := it.Next()
+		for k := range m {
+			if k != first.First {
+				delete(m, k)
+			}
+		}
+
+		assert.Empty(t, behavioral.Collect(it))
+	})
+
+	t.Run("Should compose adapters lazily", func(t *testing.T) {
+		numbers := &counter{}
+		squaresOfOdds := behavioral.Map(
+			behavioral.Filter[int](numbers, func(n int) bool { return n%2 == 1 }),
+			func(n int) int { return n * n },
+		)
+
+		result := behavioral.Collect(behavioral.Take(behavioral.Skip(squaresOfOdds, 2), 3))
+
+		assert.Equal(t, []int{25, 49, 81}, result)
+		assert.Equal(t, 10, numbers.next, "only the numbers up to 9 were pulled")
+	})
+
+	t.Run("Should zip and chain", func(t *testing.T) {
+		letters := behavioral.FromSlice([]string{"a", "b", "c"})
+		zipped := behavioral.Collect(behavioral.Zip[int, string](&counter{}, letters))
+		assert.Equal(t, []behavioral.Pair[int, string]{{0, "a"}, {1, "b"}, {2, "c"}}, zipped)
+
+		chained := behavioral.Chain(behavioral.FromSlice([]int{1}), behavioral.FromSlice([]int{}), behavioral.FromSlice([]int{2, 3}))
+		assert.Equal(t, []int{1, 2, 3}, behavioral.Collect(chained))
+
+		assert.Empty(t, behavioral.Collect(behavioral.Skip(behavioral.FromSlice([]int{1, 2}), 5)))
+		assert.Empty(t, behavioral.Collect(behavioral.Chain[int]()))
+	})
+
+	t.Run("Should plug the person and tree iterators into the toolkit", func(t *testing.T) {
+		p := behavioral.Person{"Alexander", "", "Bell"}
+		names := behavioral.Filter[string](behavioral.NewPersonNameIterator(&p), func(name string) bool { return name != "" })
+		assert.Equal(t, "ALEXANDER BELL", strings.Join(behavioral.Collect(behavioral.Map(names, strings.ToUpper)), " "))
+
+		tree := behavioral.NewBinaryTree(behavioral.NewNode(1, behavioral.NewLeafNode(2), behavioral.NewLeafNode(3)))
+		doubled := behavioral.Map[int, int](tree.InOrder(), func(n int) int { return 2 * n })
+		assert.Equal(t, []int{4, 2, 6}, behavioral.Collect(doubled))
+	})
+}
+
+func buildBalancedTree(depth, value int) *behavioral.Node {
+	if depth == 0 {
+		return behavioral.NewLeafNode(value)
+	}
+	return behavioral.NewNode(value, buildBalancedTree(depth-1, 2*value), buildBalancedTree(depth-1, 2*value+1))
+}
+
+// inOrderGenerator walks a tree in a goroutine, the way NamesGenerator does
+func inOrderGenerator(tree *behavioral.BinaryTree) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for it := tree.InOrder(); it.MoveNext(); {
+			out <- it.Value()
+		}
+	}()
+	return out
+}
+
+func BenchmarkIterator(b *testing.B) {
+	p := behavioral.Person{"Alexander", "Graham", "Bell"}
+	tree := behavioral.NewBinaryTree(buildBalancedTree(10, 1))
+
+	b.Run("names/iterator", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			it := behavioral.NewPersonNameIterator(&p)
+			for _, ok := it.Next(); ok; _, ok = it.Next() {
+			}
+		}
+	})
+	b.Run("names/generator", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for range p.NamesGenerator(context.Background()).Values() {
+			}
+		}
+	})
+	b.Run("tree/iterator", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			it := tree.InOrder()
+			for _, ok := it.Next(); ok; _, ok = it.Next() {
+			}
+		}
+	})
+	b.Run("tree/adapters", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			it := behavioral.Map(behavioral.Filter[int](tree.InOrder(), func(n int) bool { return n%2 == 0 }), func(n int) int { return n / 2 })
+			for _, ok := it.Next(); ok; _, ok = it.Next() {
+			}
+		}
+	})
+	b.Run("tree/generator", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for range inOrderGenerator(tree) {
+			}
+		}
+	})
+}
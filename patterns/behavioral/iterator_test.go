@@ -1,6 +1,7 @@
 package behavioral_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/fabricioandreis/design-patterns-go/patterns/behavioral"
@@ -22,9 +23,9 @@ func TestIterator(t *testing.T) {
 	t.Run("Should be able to iterate with a generator", func(t *testing.T) {
 		p := behavioral.Person{"Alexander", "Graham", "Bell"}
 
-		generator := p.NamesGenerator()
+		generator := p.NamesGenerator(context.Background())
 
-		for name := range generator {
+		for name := range generator.Values() {
 			assert.NotEqual(t, "", name)
 		}
 	})
@@ -87,4 +88,99 @@ func TestIterator(t *testing.T) {
 			assert.Equal(t, expected[i], output[i])
 		}
 	})
+	collect := func(it *behavioral.TreeIterator) []int {
+		output := []int{}
+		for it.MoveNext() {
+			output = append(output, it.Value())
+		}
+		return output
+	}
+
+	//        1
+	//      /   \
+	//     2     3
+	//    / \     \
+	//   4   5     6
+	//      /     /
+	//     7     8
+	buildTree := func() *behavioral.BinaryTree {
+		return behavioral.NewBinaryTree(behavioral.NewNode(1,
+			behavioral.NewNode(2, behavioral.NewLeafNode(4), behavioral.NewNode(5, behavioral.NewLeafNode(7), nil)),
+			behavioral.NewNode(3, nil, behavioral.NewNode(6, behavioral.NewLeafNode(8), nil)),
+		))
+	}
+
+	t.Run("Should iterate over a binary tree in every order", func(t *testing.T) {
+		tree := buildTree()
+
+		assert.Equal(t, []int{4, 2, 7, 5, 1, 3, 8, 6}, collect(tree.InOrder()))
+		assert.Equal(t, []int{6, 8, 3, 1, 5, 7, 2, 4}, collect(tree.ReverseInOrder()))
+		assert.Equal(t, []int{1, 2, 4, 5, 7, 3, 6, 8}, collect(tree.PreOrder()))
+		assert.Equal(t, []int{4, 7, 5, 2, 8, 6, 3, 1}, collect(tree.PostOrder()))
+		assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8}, collect(tree.LevelOrder()))
+	})
+
+	t.Run("Should iterate again after a reset", func(t *testing.T) {
+		tree := buildTree()
+		iterators := map[string]*behavioral.TreeIterator{
+			"in-order":         tree.InOrder(),
+			"reverse in-order": tree.ReverseInOrder(),
+			"pre-order":        tree.PreOrder(),
+			"post-order":       tree.PostOrder(),
+			"level-order":      tree.LevelOrder(),
+		}
+
+		for name, it := range iterators {
+			first := collect(it)
+			it.Reset()
+			assert.Equal(t, first, collect(it), name)
+
+			it.Reset()
+			it.MoveNext()
+			it.MoveNext()
+			it.Reset()
+			assert.Equal(t, first, collect(it), name)
+		}
+	})
+
+	t.Run("Should iterate over degenerate, single-node and empty trees", func(t *testing.T) {
+		// 1 - 2 - 3 going left, and going right
+		left := behavioral.NewBinaryTree(behavioral.NewNode(1, behavioral.NewNode(2, behavioral.NewLeafNode(3), nil), nil))
+		right := behavioral.NewBinaryTree(behavioral.NewNode(1, nil, behavioral.NewNode(2, nil, behavioral.NewLeafNode(3))))
+		single := behavioral.NewBinaryTree(behavioral.NewLeafNode(1))
+		empty := behavioral.NewBinaryTree(nil)
+
+		assert.Equal(t, []int{3, 2, 1}, collect(left.InOrder()))
+		assert.Equal(t, []int{1, 2, 3}, collect(left.PreOrder()))
+		assert.Equal(t, []int{3, 2, 1}, collect(left.PostOrder()))
+		assert.Equal(t, []int{1, 2, 3}, collect(left.ReverseInOrder()))
+		assert.Equal(t, []int{1, 2, 3}, collect(left.LevelOrder()))
+
+		assert.Equal(t, []int{1, 2, 3}, collect(right.InOrder()))
+		assert.Equal(t, []int{1, 2, 3}, collect(right.PreOrder()))
+		assert.Equal(t, []int{3, 2, 1}, collect(right.PostOrder()))
+		assert.Equal(t, []int{3, 2, 1}, collect(right.ReverseInOrder()))
+		assert.Equal(t, []int{1, 2, 3}, collect(right.LevelOrder()))
+
+		for _, it := range []*behavioral.TreeIterator{single.InOrder(), single.PreOrder(), single.PostOrder(), single.ReverseInOrder(), single.LevelOrder()} {
+			assert.Equal(t, []int{1}, collect(it))
+		}
+		for _, it := range []*behavioral.TreeIterator{empty.InOrder(), empty.PreOrder(), empty.PostOrder(), empty.ReverseInOrder(), empty.LevelOrder(), behavioral.NewInOrderIterator(nil)} {
+			assert.Empty(t, collect(it))
+			it.Reset()
+			assert.False(t, it.MoveNext())
+		}
+	})
+
+	t.Run("Should not allocate while walking the tree", func(t *testing.T) {
+		tree := buildTree()
+		for _, it := range []*behavioral.TreeIterator{tree.InOrder(), tree.ReverseInOrder(), tree.PreOrder(), tree.PostOrder()} {
+			allocs := testing.AllocsPerRun(10, func() {
+				it.Reset()
+				for it.MoveNext() {
+				}
+			})
+			assert.Zero(t, allocs)
+		}
+	})
 }
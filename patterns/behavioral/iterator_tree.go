@@ -0,0 +1,381 @@
+package behavioral
+
+// OrderedTree is a red-black tree: a binary search tree kept balanced by coloring its nodes, so that inserting,
+// deleting and searching are O(log n) and iterating in order is O(1) per step.
+// Like the Node of the iterators, its nodes keep a pointer to their parent, which lets iterators walk the tree
+// without a stack. Iterators must not be used after the tree is modified.
+
+type Ordered interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 | ~string
+}
+
+type rbNode[K, V any] struct {
+	key                 K
+	value               V
+	left, right, parent *rbNode[K, V]
+	red                 bool
+}
+
+type OrderedTree[K, V any] struct {
+	root *rbNode[K, V]
+	leaf *rbNode[K, V] // black sentinel standing for every missing child, and for the parent of the root
+	less func(a, b K) bool
+	size int
+}
+
+func NewOrderedTree[K Ordered, V any]() *OrderedTree[K, V] {
+	return NewOrderedTreeFunc[K, V](func(a, b K) bool { return a < b })
+}
+
+// NewOrderedTreeFunc orders the keys with less, for keys that cannot be compared with <, such as time.Time
+func NewOrderedTreeFunc[K, V any](less func(a, b K) bool) *OrderedTree[K, V] {
+	leaf := &rbNode[K, V]{}
+	return &OrderedTree[K, V]{root: leaf, leaf: leaf, less: less}
+}
+
+func (t *OrderedTree[K, V]) Len() int {
+	return t.size
+}
+
+func (t *OrderedTree[K, V]) find(key K) *rbNode[K, V] {
+	n := t.root
+	for n != t.leaf {
+		switch {
+		case t.less(key, n.key):
+			n = n.left
+		case t.less(n.key, key):
+			n = n.right
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+func (t *OrderedTree[K, V]) Get(key K) (V, bool) {
+	n := t.find(key)
+	return n.value, n != t.leaf
+}
+
+// Put inserts the key, or replaces its value when it is already in the tree
+func (t *OrderedTree[K, V]) Put(key K, value V) {
+	parent, n := t.leaf, t.root
+	for n != t.leaf {
+		parent = n
+		switch {
+		case t.less(key, n.key):
+			n = n.left
+		case t.less(n.key, key):
+			n = n.right
+		default:
+			n.value = value
+			return
+		}
+	}
+
+	n = &rbNode[K, V]{key: key, value: value, left: t.leaf, right: t.leaf, parent: parent, red: true}
+	switch {
+	case parent == t.leaf:
+		t.root = n
+	case t.less(key, parent.key):
+		parent.left = n
+	default:
+		parent.right = n
+	}
+	t.size++
+	t.insertFixup(n)
+}
+
+// insertFixup restores the colors after adding the red node n, whose parent may be red too
+func (t *OrderedTree[K, V]) insertFixup(n *rbNode[K, V]) {
+	for n.parent.red {
+		grandparent := n.parent.parent
+		if n.parent == grandparent.left {
+			uncle := grandparent.right
+			if uncle.red {
+				n.parent.red, uncle.red, grandparent.red = false, false, true
+				n = grandparent
+				continue
+			}
+			if n == n.parent.right {
+				n = n.parent
+				t.rotateLeft(n)
+			}
+			n.parent.red, n.parent.parent.red = false, true
+			t.rotateRight(n.parent.parent)
+		} else {
+			uncle := grandparent.left
+			if uncle.red {
+				n.parent.red, uncle.red, grandparent.red = false, false, true
+				n = grandparent
+				continue
+			}
+			if n == n.parent.left {
+				n = n.parent
+				t.rotateRight(n)
+			}
+			n.parent.red, n.parent.parent.red = false, true
+			t.rotateLeft(n.parent.parent)
+		}
+	}
+	t.root.red = false
+}
+
+func (t *OrderedTree[K, V]) rotateLeft(n *rbNode[K, V]) {
+	r := n.right
+	n.right = r.left
+	if r.left != t.leaf {
+		r.left.parent = n
+	}
+	t.replace(n, r)
+	r.left = n
+	n.parent = r
+}
+
+func (t *OrderedTree[K, V]) rotateRight(n *rbNode[K, V]) {
+	l := n.left
+	n.left = l.right
+	if l.right != t.leaf {
+		l.right.parent = n
+	}
+	t.replace(n, l)
+	l.right = n
+	n.parent = l
+}
+
+// replace puts the subtree r where the subtree n was
+func (t *OrderedTree[K, V]) replace(n, r *rbNode[K, V]) {
+	switch {
+	case n.parent == t.leaf:
+		t.root = r
+	case n == n.parent.left:
+		n.parent.left = r
+	default:
+		n.parent.right = r
+	}
+	r.parent = n.parent
+}
+
+// Delete removes the key, and tells whether it was in the tree
+func (t *OrderedTree[K, V]) Delete(key K) bool {
+	n := t.find(key)
+	if n == t.leaf {
+		return false
+	}
+
+	// x takes the place of the node leaving its position, which is n, or its successor when n has two children
+	var x *rbNode[K, V]
+	removedRed := n.red
+	switch {
+	case n.left == t.leaf:
+		x = n.right
+		t.replace(n, n.right)
+	case n.right == t.leaf:
+		x = n.left
+		t.replace(n, n.left)
+	default:
+		successor := t.min(n.right)
+		removedRed = successor.red
+		x = successor.right
+		if successor.parent == n {
+			x.parent = successor // x may be the sentinel, whose parent the fixup needs
+		} else {
+			t.replace(successor, successor.right)
+			successor.right = n.right
+			successor.right.parent = successor
+		}
+		t.replace(n, successor)
+		successor.left = n.left
+		successor.left.parent = successor
+		successor.red = n.red
+	}
+	t.size--
+	if !removedRed {
+		t.deleteFixup(x)
+	}
+	t.leaf.parent = nil
+	return true
+}
+
+// deleteFixup restores the colors after a black node left from above x, which counts as black twice
+func (t *OrderedTree[K, V]) deleteFixup(x *rbNode[K, V]) {
+	for x != t.root && !x.red {
+		if x == x.parent.left {
+			sibling := x.parent.right
+			if sibling.red {
+				sibling.red, x.parent.red = false, true
+				t.rotateLeft(x.parent)
+				sibling = x.parent.right
+			}
+			if !sibling.left.red && !sibling.right.red {
+				sibling.red = true
+				x = x.parent
+				continue
+			}
+			if !sibling.right.red {
+				sibling.left.red, sibling.red = false, true
+				t.rotateRight(sibling)
+				sibling = x.parent.right
+			}
+			sibling.red, x.parent.red, sibling.right.red = x.parent.red, false, false
+			t.rotateLeft(x.parent)
+			x = t.root
+		} else {
+			sibling := x.parent.left
+			if sibling.red {
+				sibling.red, x.parent.red = false, true
+				t.rotateRight(x.parent)
+				sibling = x.parent.left
+			}
+			if !sibling.right.red && !sibling.left.red {
+				sibling.red = true
+				x = x.parent
+				continue
+			}
+			if !sibling.left.red {
+				sibling.right.red, sibling.red = false, true
+				t.rotateLeft(sibling)
+				sibling = x.parent.left
+			}
+			sibling.red, x.parent.red, sibling.left.red = x.parent.red, false, false
+			t.rotateRight(x.parent)
+			x = t.root
+		}
+	}
+	x.red = false
+}
+
+func (t *OrderedTree[K, V]) min(n *rbNode[K, V]) *rbNode[K, V] {
+	for n.left != t.leaf {
+		n = n.left
+	}
+	return n
+}
+
+func (t *OrderedTree[K, V]) max(n *rbNode[K, V]) *rbNode[K, V] {
+	for n.right != t.leaf {
+		n = n.right
+	}
+	return n
+}
+
+func (t *OrderedTree[K, V]) successor(n *rbNode[K, V]) *rbNode[K, V] {
+	if n.right != t.leaf {
+		return t.min(n.right)
+	}
+	for n.parent != t.leaf && n == n.parent.right {
+		n = n.parent
+	}
+	return n.parent
+}
+
+func (t *OrderedTree[K, V]) predecessor(n *rbNode[K, V]) *rbNode[K, V] {
+	if n.left != t.leaf {
+		return t.max(n.left)
+	}
+	for n.parent != t.leaf && n == n.parent.left {
+		n = n.parent
+	}
+	return n.parent
+}
+
+func (t *OrderedTree[K, V]) entry(n *rbNode[K, V]) (Pair[K, V], bool) {
+	if n == t.leaf {
+		return Pair[K, V]{}, false
+	}
+	return Pair[K, V]{n.key, n.value}, true
+}
+
+func (t *OrderedTree[K, V]) Min() (Pair[K, V], bool) {
+	if t.root == t.leaf {
+		return Pair[K, V]{}, false
+	}
+	return t.entry(t.min(t.root))
+}
+
+func (t *OrderedTree[K, V]) Max() (Pair[K, V], bool) {
+	if t.root == t.leaf {
+		return Pair[K, V]{}, false
+	}
+	return t.entry(t.max(t.root))
+}
+
+func (t *OrderedTree[K, V]) floor(key K) *rbNode[K, V] {
+	found, n := t.leaf, t.root
+	for n != t.leaf {
+		if t.less(key, n.key) {
+			n = n.left
+		} else {
+			found, n = n, n.right
+		}
+	}
+	return found
+}
+
+func (t *OrderedTree[K, V]) ceiling(key K) *rbNode[K, V] {
+	found, n := t.leaf, t.root
+	for n != t.leaf {
+		if t.less(n.key, key) {
+			n = n.right
+		} else {
+			found, n = n, n.left
+		}
+	}
+	return found
+}
+
+// Floor is the entry with the greatest key less than or equal to key
+func (t *OrderedTree[K, V]) Floor(key K) (Pair[K, V], bool) {
+	return t.entry(t.floor(key))
+}
+
+// Ceiling is the entry with the least key greater than or equal to key
+func (t *OrderedTree[K, V]) Ceiling(key K) (Pair[K, V], bool) {
+	return t.entry(t.ceiling(key))
+}
+
+// All iterates over every entry in key order
+func (t *OrderedTree[K, V]) All() Iterator[Pair[K, V]] {
+	if t.root == t.leaf {
+		return FromSlice([]Pair[K, V](nil))
+	}
+	return t.iterate(t.min(t.root), func(*rbNode[K, V]) bool { return true }, t.successor)
+}
+
+// Range iterates in key order over the entries with keys from lo to hi, both included
+func (t *OrderedTree[K, V]) Range(lo, hi K) Iterator[Pair[K, V]] {
+	return t.iterate(t.ceiling(lo), func(n *rbNode[K, V]) bool { return !t.less(hi, n.key) }, t.successor)
+}
+
+// RangeDescending iterates from hi down to lo, both included
+func (t *OrderedTree[K, V]) RangeDescending(hi, lo K) Iterator[Pair[K, V]] {
+	return t.iterate(t.floor(hi), func(n *rbNode[K, V]) bool { return !t.less(n.key, lo) }, t.predecessor)
+}
+
+func (t *OrderedTree[K, V]) iterate(n *rbNode[K, V], within func(*rbNode[K, V]) bool, next func(*rbNode[K, V]) *rbNode[K, V]) Iterator[Pair[K, V]] {
+	return IteratorFunc[Pair[K, V]](func() (Pair[K, V], bool) {
+		if n == t.leaf || !within(n) {
+			n = t.leaf
+			return Pair[K, V]{}, false
+		}
+		current := n
+		n = next(n)
+		return Pair[K, V]{current.key, current.value}, true
+	})
+}
+
+// NewBinaryTreeOf copies a tree of int keys into Nodes holding its keys, with the same shape, so that the iterators of
+// BinaryTree walk it in any of their orders. OrderedTree keeps nodes of its own because a Node only holds an int, with
+// neither the value nor the color of an entry. The copy does not follow later changes of the tree.
+func NewBinaryTreeOf[V any](t *OrderedTree[int, V]) *BinaryTree {
+	var copyNode func(n *rbNode[int, V]) *Node
+	copyNode = func(n *rbNode[int, V]) *Node {
+		if n == t.leaf {
+			return nil
+		}
+		return NewNode(n.key, copyNode(n.left), copyNode(n.right))
+	}
+	return NewBinaryTree(copyNode(t.root))
+}
@@ -0,0 +1,192 @@
+package behavioral_test
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/fabricioandreis/design-patterns-go/patterns/behavioral"
+	"github.com/stretchr/testify/assert"
+)
+
+func keys[K, V any](it behavioral.Iterator[behavioral.Pair[K, V]]) []K {
+	var ks []K
+	for p, ok := it.Next(); ok; p, ok = it.Next() {
+		ks = append(ks, p.First)
+	}
+	return ks
+}
+
+func TestOrderedTree(t *testing.T) {
+	t.Run("Should insert, replace, search and delete keys", func(t *testing.T) {
+		tree := behavioral.NewOrderedTree[int, string]()
+		for _, k := range []int{5, 3, 8, 1, 4} {
+			tree.Put(k, "v")
+		}
+		tree.Put(3, "three")
+
+		value, ok := tree.Get(3)
+		assert.True(t, ok)
+		assert.Equal(t, "three", value)
+		_, ok = tree.Get(7)
+		assert.False(t, ok)
+		assert.Equal(t, 5, tree.Len())
+
+		assert.True(t, tree.Delete(3))
+		assert.False(t, tree.Delete(3))
+		_, ok = tree.Get(3)
+		assert.False(t, ok)
+		assert.Equal(t, []int{1, 4, 5, 8}, keys(tree.All()))
+		assert.NoError(t, behavioral.ExportCheckOrderedTree(tree))
+	})
+
+	t.Run("Should find floor, ceiling, min and max", func(t *testing.T) {
+		tree := behavioral.NewOrderedTree[int, int]()
+		for _, k := range []int{10, 20, 30, 40} {
+			tree.Put(k, k*k)
+		}
+
+		floor, ok := tree.Floor(25)
+		assert.True(t, ok)
+		assert.Equal(t, behavioral.Pair[int, int]{20, 400}, floor)
+		ceiling, ok := tree.Ceiling(25)
+		assert.True(t, ok)
+		assert.Equal(t, 30, ceiling.First)
+		floor, _ = tree.Floor(30)
+		assert.Equal(t, 30, floor.First)
+		_, ok = tree.Floor(9)
+		assert.False(t, ok)
+		_, ok = tree.Ceiling(41)
+		assert.False(t, ok)
+
+		min, _ := tree.Min()
+		max, _ := tree.Max()
+		assert.Equal(t, 10, min.First)
+		assert.Equal(t, 40, max.First)
+	})
+
+	t.Run("Should iterate over a range of keys in both directions", func(t *testing.T) {
+		tree := behavioral.NewOrderedTree[int, struct{}]()
+		for k := 0; k < 20; k += 2 {
+			tree.Put(k, struct{}{})
+		}
+
+		assert.Equal(t, []int{4, 6, 8}, keys(tree.Range(3, 8)))
+		assert.Equal(t, []int{0, 2}, keys(tree.Range(-5, 2)))
+		assert.Equal(t, []int{16, 18}, keys(tree.Range(15, 100)))
+		assert.Empty(t, keys(tree.Range(5, 5)))
+		assert.Empty(t, keys(tree.Range(8, 4)))
+		assert.Equal(t, []int{8, 6, 4}, keys(tree.RangeDescending(9, 3)))
+	})
+
+	t.Run("Should compose with the iterator adapters", func(t *testing.T) {
+		tree := behavioral.NewOrderedTree[string, int]()
+		for i, name := range []string{"delta", "alpha", "charlie", "bravo", "echo"} {
+			tree.Put(name, i)
+		}
+
+		names := behavioral.Collect(behavioral.Take(behavioral.Map(tree.Range("b", "z"),
+			func(p behavioral.Pair[string, int]) string { return p.First }), 2))
+
+		assert.Equal(t, []string{"bravo", "charlie"}, names)
+	})
+
+	t.Run("Should handle an empty tree", func(t *testing.T) {
+		tree := behavioral.NewOrderedTree[int, int]()
+
+		_, ok := tree.Min()
+		assert.False(t, ok)
+		_, ok = tree.Floor(1)
+		assert.False(t, ok)
+		assert.False(t, tree.Delete(1))
+		assert.Empty(t, keys(tree.All()))
+		assert.Empty(t, keys(tree.Range(0, 10)))
+		assert.NoError(t, behavioral.ExportCheckOrderedTree(tree))
+	})
+
+	t.Run("Should order keys with a custom comparison, as in a time series", func(t *testing.T) {
+		series := behavioral.NewOrderedTreeFunc[time.Time, float64](func(a, b time.Time) bool { return a.Before(b) })
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		for _, minute := range []int{30, 0, 45, 15, 60} {
+			series.Put(start.Add(time.Duration(minute)*time.Minute), float64(minute))
+		}
+
+		var values []float64
+		it := series.Range(start.Add(10*time.Minute), start.Add(45*time.Minute))
+		for p, ok := it.Next(); ok; p, ok = it.Next() {
+			values = append(values, p.Second)
+		}
+
+		assert.Equal(t, []float64{15, 30, 45}, values)
+	})
+
+	t.Run("Should stay balanced and ordered under random inserts and deletes", func(t *testing.T) {
+		r := rand.New(rand.NewSource(1))
+		tree := behavioral.NewOrderedTree[int, int]()
+		model := map[int]int{}
+
+		for i := 0; i < 5000; i++ {
+			k := r.Intn(500)
+			if r.Intn(3) == 0 {
+				_, present := model[k]
+				assert.Equal(t, present, tree.Delete(k))
+				delete(model, k)
+			} else {
+				tree.Put(k, i)
+				model[k] = i
+			}
+			if i%100 == 0 {
+				if err := behavioral.ExportCheckOrderedTree(tree); err != nil {
+					t.Fatalf("after %d operations: %v", i, err)
+				}
+			}
+		}
+
+		want := make([]int, 0, len(model))
+		for k := range model {
+			want = append(want, k)
+		}
+		sort.Ints(want)
+		assert.Equal(t, want, keys(tree.All()))
+		assert.Equal(t, len(model), tree.Len())
+		for k, v := range model {
+			got, ok := tree.Get(k)
+			assert.True(t, ok)
+			assert.Equal(t, v, got)
+		}
+		assert.NoError(t, behavioral.ExportCheckOrderedTree(tree))
+	})
+
+	t.Run("Should keep sequential inserts logarithmic in height", func(t *testing.T) {
+		tree := behavioral.NewOrderedTree[int, int]()
+		for k := 0; k < 1<<12; k++ {
+			tree.Put(k, k)
+		}
+
+		assert.NoError(t, behavioral.ExportCheckOrderedTree(tree))
+		assert.LessOrEqual(t, behavioral.ExportOrderedTreeHeight(tree), 2*13)
+	})
+
+	t.Run("Should be walked by the iterators of the binary tree", func(t *testing.T) {
+		tree := behavioral.NewOrderedTree[int, string]()
+		for k := 1; k <= 7; k++ {
+			tree.Put(k, "v")
+		}
+		binary := behavioral.NewBinaryTreeOf(tree)
+
+		assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7}, behavioral.Collect[int](binary.InOrder()))
+		assert.Equal(t, []int{7, 6, 5, 4, 3, 2, 1}, behavioral.Collect[int](binary.ReverseInOrder()))
+		// the shape the red-black tree took after the sequential inserts
+		assert.Equal(t, []int{2, 1, 4, 3, 6, 5, 7}, behavioral.Collect[int](binary.LevelOrder()))
+
+		assert.Empty(t, behavioral.Collect[int](behavioral.NewBinaryTreeOf(behavioral.NewOrderedTree[int, string]()).InOrder()))
+	})
+}
+
+func BenchmarkOrderedTree(b *testing.B) {
+	tree := behavioral.NewOrderedTree[int, int]()
+	for i := 0; i < b.N; i++ {
+		tree.Put(i, i)
+	}
+}
@@ -1,51 +1,201 @@
 package behavioral
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 // Mediator (Also known as: Intermediary, Controller) is a behavioral design pattern that lets you reduce chaotic dependencies between objects. The pattern restricts direct communications between the objects and forces them to collaborate only via a mediator object.
 // Applicable when components may go in and out of a system at any time: chat room participants, players in an online game, and so on.
 // https://refactoring.guru/design-patterns/mediator
 
+const DefaultChatLogSize = 100
+
+var (
+	ErrUnknownRecipient = errors.New("unknown recipient")
+	ErrNotInRoom        = errors.New("user is not in the room")
+	ErrAlreadyInRoom    = errors.New("user is already in the room")
+	ErrBanned           = errors.New("user is banned from the room")
+	ErrMuted            = errors.New("user is muted")
+	ErrNotModerator     = errors.New("user is not a moderator")
+	ErrRateLimited      = errors.New("rate limit exceeded")
+	ErrNoMessageStore   = errors.New("room has no message store")
+	ErrReservedName     = errors.New("name is reserved for the room")
+)
+
 type ChatUser struct {
-	Name    string
-	Room    *ChatRoom
-	chatLog []string
+	Name string
+	Room *ChatRoom
+
+	mu         sync.Mutex
+	chatLog    []string
+	chatLogCap int
+	moving     sync.Mutex // held while the user joins a room, so that they are never moved to two rooms at once
 }
 
 func NewChatUser(name string) *ChatUser {
-	return &ChatUser{Name: name}
+	return &ChatUser{Name: name, chatLogCap: DefaultChatLogSize}
+}
+
+// SetChatLogSize bounds how many messages the user keeps, dropping the oldest ones first. A non-positive size keeps every message.
+func (u *ChatUser) SetChatLogSize(size int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.chatLogCap = size
+	u.trimChatLog()
 }
 
 func (u *ChatUser) Receive(sender, message string) {
 	s := fmt.Sprintf("%s: %s", sender, message)
 	fmt.Printf("[%s's chat session]: %s\n", u.Name, s)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
 	u.chatLog = append(u.chatLog, s)
+	u.trimChatLog()
 }
 
-func (u *ChatUser) Say(message string) {
-	u.Room.Broadcast(u.Name, message)
+func (u *ChatUser) trimChatLog() {
+	if u.chatLogCap > 0 && len(u.chatLog) > u.chatLogCap {
+		u.chatLog = u.chatLog[len(u.chatLog)-u.chatLogCap:]
+	}
 }
 
-func (u *ChatUser) PrivateMessage(who, message string) {
-	u.Room.Unicast(u.Name, who, message)
+func (u *ChatUser) Say(message string) error {
+	room := u.room()
+	if room == nil {
+		return ErrNotInRoom
+	}
+	return room.Broadcast(u.Name, message)
 }
 
-func (u *ChatUser) ChatLog() <-chan string {
-	out := make(chan string)
-	go func() {
-		defer close(out)
-		for _, log := range u.chatLog {
-			out <- log
-		}
-	}()
-	return out
+func (u *ChatUser) PrivateMessage(who, message string) error {
+	room := u.room()
+	if room == nil {
+		return ErrNotInRoom
+	}
+	return room.Unicast(u.Name, who, message)
+}
+
+func (u *ChatUser) room() *ChatRoom {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.Room
+}
+
+func (u *ChatUser) setRoom(r *ChatRoom) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.Room = r
+}
+
+// ChatLog sends a snapshot of the user's log until it runs out, ctx is canceled or the consumer stops the generator
+func (u *ChatUser) ChatLog(ctx context.Context) *Generator[string] {
+	u.mu.Lock()
+	logs := make([]string, len(u.chatLog))
+	copy(logs, u.chatLog)
+	u.mu.Unlock()
+
+	return GenerateSlice(ctx, 0, logs)
 }
 
+// The room name used as the sender of system messages
+const roomSender = "Room"
+
+// ChatRoom is the mediator: users never talk to each other directly.
+// The zero value is an empty room without moderators or rate limit, ready to use.
 type ChatRoom struct {
-	users []*ChatUser
+	id   uint64 // orders the locks of rooms taken together, first so that it is aligned for atomic access
+	Name string
+
+	mu         sync.RWMutex
+	users      []*ChatUser
+	moderators map[string]bool
+	muted      map[string]bool
+	banned     map[string]bool
+
+	rateLimit  int
+	ratePeriod time.Duration
+	sent       map[string][]time.Time
+	now        func() time.Time
+
+	store MessageStore
+}
+
+func NewChatRoom(name string) *ChatRoom {
+	return &ChatRoom{Name: name}
+}
+
+// SetRateLimit allows each user to send at most n messages in any given period. A non-positive n disables the limit.
+func (r *ChatRoom) SetRateLimit(n int, period time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rateLimit = n
+	r.ratePeriod = period
+	r.sent = nil
+}
+
+// SetMessageStore keeps every message delivered from now on in the store.
+func (r *ChatRoom) SetMessageStore(store MessageStore) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.store = store
+}
+
+// History iterates over the stored messages of this room.
+func (r *ChatRoom) History(q MessageQuery) *MessageIterator {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	q.Room = r.Name
+	if r.store == nil {
+		return &MessageIterator{err: ErrNoMessageStore}
+	}
+	return NewMessageIterator(r.store, q)
 }
 
-func (r *ChatRoom) Broadcast(source, message string) {
+func (r *ChatRoom) AddModerator(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.moderators == nil {
+		r.moderators = map[string]bool{}
+	}
+	r.moderators[name] = true
+}
+
+func (r *ChatRoom) Users() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.users))
+	for _, u := range r.users {
+		names = append(names, u.Name)
+	}
+	return names
+}
+
+func (r *ChatRoom) Broadcast(source, message string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.checkSender(source); err != nil {
+		return err
+	}
+	return r.broadcast(source, message)
+}
+
+func (r *ChatRoom) broadcast(source, message string) error {
+	if err := r.record(source, "", message); err != nil {
+		return err
+	}
+	r.deliver(source, message)
+	return nil
+}
+
+// deliver sends a message already recorded to every user but its sender
+func (r *ChatRoom) deliver(source, message string) {
 	for _, u := range r.users {
 		if u.Name != source {
 			u.Receive(source, message)
@@ -53,18 +203,262 @@ func (r *ChatRoom) Broadcast(source, message string) {
 	}
 }
 
-func (r *ChatRoom) Unicast(src, dst, msg string) {
+func (r *ChatRoom) Unicast(src, dst, msg string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.checkSender(src); err != nil {
+		return err
+	}
+	u := r.find(dst)
+	if u == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownRecipient, dst)
+	}
+	if err := r.record(src, dst, msg); err != nil {
+		return err
+	}
+	u.Receive(src, msg)
+	return nil
+}
+
+func (r *ChatRoom) record(sender, recipient, text string) error {
+	if r.store == nil {
+		return nil
+	}
+	_, err := r.store.Append(context.Background(), ChatMessage{
+		Room:      r.Name,
+		Sender:    sender,
+		Recipient: recipient,
+		Text:      text,
+		Time:      r.clock(),
+	})
+	return err
+}
+
+// checkSender must be called with the write lock held, since it records the message for rate limiting.
+// System messages never go through it: they are sent with broadcast, so no user can post as the room.
+func (r *ChatRoom) checkSender(name string) error {
+	if name == roomSender {
+		return fmt.Errorf("%w: %s", ErrReservedName, name)
+	}
+	if r.find(name) == nil {
+		return fmt.Errorf("%w: %s", ErrNotInRoom, name)
+	}
+	if r.muted[name] {
+		return fmt.Errorf("%w: %s", ErrMuted, name)
+	}
+	if r.rateLimit <= 0 {
+		return nil
+	}
+
+	now := r.clock()
+	if r.sent == nil {
+		r.sent = map[string][]time.Time{}
+	}
+	recent := r.sent[name][:0]
+	for _, t := range r.sent[name] {
+		if now.Sub(t) < r.ratePeriod {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= r.rateLimit {
+		r.sent[name] = recent
+		return fmt.Errorf("%w: %s", ErrRateLimited, name)
+	}
+	r.sent[name] = append(recent, now)
+	return nil
+}
+
+func (r *ChatRoom) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+func (r *ChatRoom) find(name string) *ChatUser {
 	for _, u := range r.users {
-		if u.Name == dst {
-			u.Receive(src, msg)
+		if u.Name == name {
+			return u
 		}
 	}
+	return nil
 }
 
-func (c *ChatRoom) Join(u *ChatUser) {
-	joinMsg := u.Name + " joins the chat"
-	c.Broadcast("Room", joinMsg)
+var roomIDs uint64
 
-	u.Room = c
+func (r *ChatRoom) lockOrder() uint64 {
+	if id := atomic.LoadUint64(&r.id); id != 0 {
+		return id
+	}
+	atomic.CompareAndSwapUint64(&r.id, 0, atomic.AddUint64(&roomIDs, 1))
+	return atomic.LoadUint64(&r.id)
+}
+
+// lockRooms locks both rooms in a fixed order, so that moves in opposite directions do not deadlock
+func lockRooms(a, b *ChatRoom) (unlock func()) {
+	if b == nil || a == b {
+		a.mu.Lock()
+		return a.mu.Unlock
+	}
+	if b.lockOrder() < a.lockOrder() {
+		a, b = b, a
+	}
+	a.mu.Lock()
+	b.mu.Lock()
+	return func() {
+		b.mu.Unlock()
+		a.mu.Unlock()
+	}
+}
+
+// Join adds the user to the room, leaving any other room the user was in.
+// The move is atomic: a user who cannot join stays in their current room. Both system messages are recorded
+// before anyone moves, so a failing message store stops the move; if only the second one fails,
+// the history of the old room tells that the user left although they stayed.
+func (c *ChatRoom) Join(u *ChatUser) error {
+	if u.Name == roomSender {
+		return fmt.Errorf("%w: %s", ErrReservedName, u.Name)
+	}
+	u.moving.Lock()
+	defer u.moving.Unlock()
+	current := u.room()
+	unlock := lockRooms(c, current)
+	defer unlock()
+
+	if c.banned[u.Name] {
+		return fmt.Errorf("%w: %s", ErrBanned, u.Name)
+	}
+	if c.find(u.Name) != nil {
+		return fmt.Errorf("%w: %s", ErrAlreadyInRoom, u.Name)
+	}
+
+	// a moderator may have kicked the user out of their room before it was locked
+	leaving := current != nil && current.find(u.Name) != nil
+	leaves, joins := u.Name+" leaves the chat", u.Name+" joins the chat"
+	if leaving {
+		if err := current.record(roomSender, "", leaves); err != nil {
+			return err
+		}
+	}
+	if err := c.record(roomSender, "", joins); err != nil {
+		return err
+	}
+
+	if leaving {
+		current.remove(u.Name)
+		current.deliver(roomSender, leaves)
+	}
+	c.deliver(roomSender, joins)
+	u.setRoom(c)
 	c.users = append(c.users, u)
+	return nil
+}
+
+func (c *ChatRoom) Leave(u *ChatUser) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.remove(u.Name) {
+		return fmt.Errorf("%w: %s", ErrNotInRoom, u.Name)
+	}
+	return c.broadcast(roomSender, u.Name+" leaves the chat")
+}
+
+func (c *ChatRoom) remove(name string) bool {
+	for i, u := range c.users {
+		if u.Name == name {
+			c.users = append(c.users[:i], c.users[i+1:]...)
+			u.setRoom(nil)
+			delete(c.sent, name)
+			return true
+		}
+	}
+	return false
+}
+
+// Moderation commands: only moderators may issue them
+
+func (c *ChatRoom) Mute(moderator, target string) error {
+	return c.moderate(moderator, target, func() error {
+		if c.muted == nil {
+			c.muted = map[string]bool{}
+		}
+		c.muted[target] = true
+		return c.broadcast(roomSender, target+" was muted by "+moderator)
+	})
+}
+
+func (c *ChatRoom) Unmute(moderator, target string) error {
+	return c.moderate(moderator, target, func() error {
+		delete(c.muted, target)
+		return c.broadcast(roomSender, target+" was unmuted by "+moderator)
+	})
+}
+
+func (c *ChatRoom) Kick(moderator, target string) error {
+	return c.moderate(moderator, target, func() error {
+		c.remove(target)
+		return c.broadcast(roomSender, target+" was kicked by "+moderator)
+	})
+}
+
+// Ban removes the user from the room and prevents them from joining again.
+func (c *ChatRoom) Ban(moderator, target string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.moderators[moderator] {
+		return fmt.Errorf("%w: %s", ErrNotModerator, moderator)
+	}
+	if c.banned == nil {
+		c.banned = map[string]bool{}
+	}
+	c.banned[target] = true
+	if c.remove(target) {
+		return c.broadcast(roomSender, target+" was banned by "+moderator)
+	}
+	return nil
+}
+
+func (c *ChatRoom) moderate(moderator, target string, command func() error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.moderators[moderator] {
+		return fmt.Errorf("%w: %s", ErrNotModerator, moderator)
+	}
+	if c.find(target) == nil {
+		return fmt.Errorf("%w: %s", ErrNotInRoom, target)
+	}
+	return command()
+}
+
+// ChatServer keeps track of many independent rooms by name
+type ChatServer struct {
+	mu    sync.Mutex
+	rooms map[string]*ChatRoom
+}
+
+func NewChatServer() *ChatServer {
+	return &ChatServer{rooms: map[string]*ChatRoom{}}
+}
+
+// Room returns the room with the given name, creating it on first use.
+func (s *ChatServer) Room(name string) *ChatRoom {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.rooms[name]
+	if !ok {
+		r = NewChatRoom(name)
+		s.rooms[name] = r
+	}
+	return r
+}
+
+func (s *ChatServer) Rooms() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.rooms))
+	for name := range s.rooms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
@@ -0,0 +1,319 @@
+package behavioral
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Chat history kept by the mediator, so that it survives the users that received it.
+
+const DefaultMessagePageSize = 50
+
+var ErrStoreClosed = errors.New("message store is closed")
+
+type ChatMessage struct {
+	ID        uint64    `json:"id"`
+	Room      string    `json:"room"`
+	Sender    string    `json:"sender"`
+	Recipient string    `json:"recipient,omitempty"` // empty for messages broadcast to the whole room
+	Text      string    `json:"text"`
+	Time      time.Time `json:"time"`
+}
+
+func (m ChatMessage) String() string {
+	return fmt.Sprintf("%s: %s", m.Sender, m.Text)
+}
+
+// VisibleTo tells whether the user sent or received the message.
+func (m ChatMessage) VisibleTo(user string) bool {
+	return m.Recipient == "" || m.Recipient == user || m.Sender == user
+}
+
+// MessageQuery selects a page of messages ordered by ID.
+// Pages are walked by passing the ID of the last message received as After.
+type MessageQuery struct {
+	Room        string // all rooms when empty
+	Participant string // only messages visible to this user when not empty
+	Text        string // only messages containing every word of Text when not empty
+	After       uint64
+	Limit       int // DefaultMessagePageSize when not positive
+}
+
+func (q MessageQuery) matches(m ChatMessage, terms []string) bool {
+	if m.ID <= q.After {
+		return false
+	}
+	if q.Room != "" && m.Room != q.Room {
+		return false
+	}
+	if q.Participant != "" && !m.VisibleTo(q.Participant) {
+		return false
+	}
+	if len(terms) == 0 {
+		return true
+	}
+	words := map[string]bool{}
+	for _, w := range tokenize(m.Text) {
+		words[w] = true
+	}
+	for _, term := range terms {
+		if !words[term] {
+			return false
+		}
+	}
+	return true
+}
+
+func (q MessageQuery) limit() int {
+	if q.Limit <= 0 {
+		return DefaultMessagePageSize
+	}
+	return q.Limit
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+type MessageStore interface {
+	// Append stores the message, assigning it the next ID
+	Append(ctx context.Context, m ChatMessage) (ChatMessage, error)
+	Find(ctx context.Context, q MessageQuery) ([]ChatMessage, error)
+}
+
+// 1. In-memory store
+type MemoryMessageStore struct {
+	mu       sync.RWMutex
+	messages []ChatMessage
+}
+
+func NewMemoryMessageStore() *MemoryMessageStore {
+	return &MemoryMessageStore{}
+}
+
+func (s *MemoryMessageStore) Append(ctx context.Context, m ChatMessage) (ChatMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return ChatMessage{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m.ID = uint64(len(s.messages)) + 1
+	s.messages = append(s.messages, m)
+	return m, nil
+}
+
+func (s *MemoryMessageStore) Find(ctx context.Context, q MessageQuery) ([]ChatMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	terms := tokenize(q.Text)
+	page := []ChatMessage{}
+	// IDs are the position in the slice plus one, so the page starts right after the cursor
+	start := q.After
+	if start > uint64(len(s.messages)) {
+		start = uint64(len(s.messages))
+	}
+	for _, m := range s.messages[start:] {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if q.matches(m, terms) {
+			page = append(page, m)
+			if len(page) == q.limit() {
+				break
+			}
+		}
+	}
+	return page, nil
+}
+
+func (s *MemoryMessageStore) len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.messages)
+}
+
+func (s *MemoryMessageStore) load(m ChatMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, m)
+}
+
+// 2. Append-only file store: one JSON message per line, reloaded when the file is opened again.
+// A message is only kept in memory once its line is synced to disk, and a line torn by a crash while it was written
+// is dropped when the file is opened again.
+type FileMessageStore struct {
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	torn   bool // a failed write may have left part of a line after size
+	memory *MemoryMessageStore
+}
+
+func NewFileMessageStore(path string) (*FileMessageStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	memory := NewMemoryMessageStore()
+	reader := bufio.NewReader(file)
+	var size int64
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(data) > 0 {
+				// The last write never completed: drop it, so that the next message takes its place
+				if err := file.Truncate(size); err != nil {
+					file.Close()
+					return nil, err
+				}
+			}
+			break
+		}
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		var m ChatMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if m.ID != uint64(line) {
+			file.Close()
+			return nil, fmt.Errorf("%s:%d: unexpected message ID %d", path, line, m.ID)
+		}
+		memory.load(m)
+		size += int64(len(data))
+	}
+
+	return &FileMessageStore{file: file, size: size, memory: memory}, nil
+}
+
+func (s *FileMessageStore) Append(ctx context.Context, m ChatMessage) (ChatMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return ChatMessage{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ChatMessage{}, ErrStoreClosed
+	}
+	if s.torn {
+		if err := s.file.Truncate(s.size); err != nil {
+			return ChatMessage{}, err
+		}
+		s.torn = false
+	}
+
+	m.ID = uint64(s.memory.len()) + 1
+	line, err := json.Marshal(m)
+	if err != nil {
+		return ChatMessage{}, err
+	}
+	line = append(line, '\n')
+	if err := s.write(line); err != nil {
+		// Do not leave part of the line behind, or the next message would be appended to it
+		if truncErr := s.file.Truncate(s.size); truncErr != nil {
+			s.torn = true
+			return ChatMessage{}, fmt.Errorf("%w; dropping the partial line: %v", err, truncErr)
+		}
+		return ChatMessage{}, err
+	}
+	s.size += int64(len(line))
+	s.memory.load(m)
+	return m, nil
+}
+
+func (s *FileMessageStore) write(line []byte) error {
+	if _, err := s.file.Write(line); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+func (s *FileMessageStore) Find(ctx context.Context, q MessageQuery) ([]ChatMessage, error) {
+	s.mu.Lock()
+	closed := s.file == nil
+	s.mu.Unlock()
+	if closed {
+		return nil, ErrStoreClosed
+	}
+	return s.memory.Find(ctx, q)
+}
+
+func (s *FileMessageStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// MessageIterator walks a query page by page. It does not spawn goroutines, so a consumer may stop at any time.
+type MessageIterator struct {
+	store   MessageStore
+	query   MessageQuery
+	page    []ChatMessage
+	current ChatMessage
+	done    bool
+	err     error
+}
+
+func NewMessageIterator(store MessageStore, q MessageQuery) *MessageIterator {
+	return &MessageIterator{store: store, query: q}
+}
+
+func (it *MessageIterator) MoveNext(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if err := ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	if len(it.page) == 0 {
+		if it.done {
+			return false
+		}
+		page, err := it.store.Find(ctx, it.query)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if len(page) < it.query.limit() {
+			it.done = true
+		}
+		if len(page) == 0 {
+			return false
+		}
+		it.page = page
+		it.query.After = page[len(page)-1].ID
+	}
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+func (it *MessageIterator) Value() ChatMessage {
+	return it.current
+}
+
+func (it *MessageIterator) Err() error {
+	return it.err
+}
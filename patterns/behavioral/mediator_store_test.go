@@ -0,0 +1,180 @@
+package behavioral_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fabricioandreis/design-patterns-go/patterns/behavioral"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageStore(t *testing.T) {
+	ctx := context.Background()
+
+	fill := func(store behavioral.MessageStore, n int) {
+		for i := 1; i <= n; i++ {
+			store.Append(ctx, behavioral.ChatMessage{Room: "general", Sender: "John", Text: fmt.Sprintf("message %d", i)})
+		}
+	}
+
+	texts := func(messages []behavioral.ChatMessage) []string {
+		out := []string{}
+		for _, m := range messages {
+			out = append(out, m.Text)
+		}
+		return out
+	}
+
+	t.Run("Should assign sequential IDs and paginate", func(t *testing.T) {
+		store := behavioral.NewMemoryMessageStore()
+		fill(store, 5)
+
+		first, err := store.Find(ctx, behavioral.MessageQuery{Limit: 2})
+		assert.NoError(t, err)
+		second, _ := store.Find(ctx, behavioral.MessageQuery{Limit: 2, After: first[1].ID})
+		last, _ := store.Find(ctx, behavioral.MessageQuery{Limit: 2, After: second[1].ID})
+
+		assert.Equal(t, []uint64{1, 2}, []uint64{first[0].ID, first[1].ID})
+		assert.Equal(t, []string{"message 3", "message 4"}, texts(second))
+		assert.Equal(t, []string{"message 5"}, texts(last))
+	})
+
+	t.Run("Should search the full text of messages", func(t *testing.T) {
+		store := behavioral.NewMemoryMessageStore()
+		store.Append(ctx, behavioral.ChatMessage{Room: "general", Sender: "John", Text: "Is the Deploy done?"})
+		store.Append(ctx, behavioral.ChatMessage{Room: "general", Sender: "Jane", Text: "deploy failed, again"})
+		store.Append(ctx, behavioral.ChatMessage{Room: "ops", Sender: "Jane", Text: "deploy is done"})
+		store.Append(ctx, behavioral.ChatMessage{Room: "general", Sender: "Jane", Recipient: "Simon", Text: "the deploy is done"})
+
+		all, _ := store.Find(ctx, behavioral.MessageQuery{Text: "DEPLOY done"})
+		general, _ := store.Find(ctx, behavioral.MessageQuery{Room: "general", Text: "deploy done"})
+		john, _ := store.Find(ctx, behavioral.MessageQuery{Room: "general", Participant: "John", Text: "deploy"})
+
+		assert.Equal(t, []string{"Is the Deploy done?", "deploy is done", "the deploy is done"}, texts(all))
+		assert.Equal(t, []string{"Is the Deploy done?", "the deploy is done"}, texts(general))
+		assert.Equal(t, []string{"Is the Deploy done?", "deploy failed, again"}, texts(john))
+	})
+
+	t.Run("Should persist messages in an append-only file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "chat.log")
+		store, err := behavioral.NewFileMessageStore(path)
+		assert.NoError(t, err)
+		fill(store, 3)
+		assert.NoError(t, store.Close())
+		_, err = store.Append(ctx, behavioral.ChatMessage{Text: "too late"})
+		assert.ErrorIs(t, err, behavioral.ErrStoreClosed)
+
+		reopened, err := behavioral.NewFileMessageStore(path)
+		assert.NoError(t, err)
+		defer reopened.Close()
+		m, err := reopened.Append(ctx, behavioral.ChatMessage{Text: "message 4"})
+		assert.NoError(t, err)
+		messages, _ := reopened.Find(ctx, behavioral.MessageQuery{})
+
+		assert.Equal(t, uint64(4), m.ID)
+		assert.Equal(t, []string{"message 1", "message 2", "message 3", "message 4"}, texts(messages))
+	})
+
+	t.Run("Should drop a line torn by a crash when the file is opened again", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "chat.log")
+		store, err := behavioral.NewFileMessageStore(path)
+		assert.NoError(t, err)
+		fill(store, 2)
+		assert.NoError(t, store.Close())
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		assert.NoError(t, err)
+		file.WriteString(`{"id":3,"text":"mess`)
+		file.Close()
+
+		reopened, err := behavioral.NewFileMessageStore(path)
+		assert.NoError(t, err)
+		m, err := reopened.Append(ctx, behavioral.ChatMessage{Text: "message 3"})
+		assert.NoError(t, err)
+		assert.NoError(t, reopened.Close())
+
+		again, err := behavioral.NewFileMessageStore(path)
+		assert.NoError(t, err)
+		defer again.Close()
+		messages, _ := again.Find(ctx, behavioral.MessageQuery{})
+		assert.Equal(t, uint64(3), m.ID)
+		assert.Equal(t, []string{"message 1", "message 2", "message 3"}, texts(messages))
+	})
+
+	t.Run("Should not keep a message it failed to write", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "chat.log")
+		store, err := behavioral.NewFileMessageStore(path)
+		assert.NoError(t, err)
+		fill(store, 2)
+		behavioral.ExportBreakMessageStoreFile(store)
+
+		_, err = store.Append(ctx, behavioral.ChatMessage{Text: "lost"})
+		assert.ErrorContains(t, err, "dropping the partial line")
+		_, err = store.Append(ctx, behavioral.ChatMessage{Text: "lost too"})
+		assert.Error(t, err, "the partial line must be dropped first")
+		messages, _ := store.Find(ctx, behavioral.MessageQuery{})
+		assert.Equal(t, []string{"message 1", "message 2"}, texts(messages))
+
+		reopened, err := behavioral.NewFileMessageStore(path)
+		assert.NoError(t, err)
+		defer reopened.Close()
+		m, err := reopened.Append(ctx, behavioral.ChatMessage{Text: "message 3"})
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(3), m.ID)
+	})
+
+	t.Run("Should iterate over every page and stop on cancellation", func(t *testing.T) {
+		store := behavioral.NewMemoryMessageStore()
+		fill(store, 7)
+
+		all := []string{}
+		it := behavioral.NewMessageIterator(store, behavioral.MessageQuery{Limit: 3})
+		for it.MoveNext(ctx) {
+			all = append(all, it.Value().Text)
+		}
+		assert.NoError(t, it.Err())
+		assert.Len(t, all, 7)
+
+		cctx, cancel := context.WithCancel(ctx)
+		it = behavioral.NewMessageIterator(store, behavioral.MessageQuery{Limit: 3})
+		assert.True(t, it.MoveNext(cctx))
+		cancel()
+		assert.False(t, it.MoveNext(cctx))
+		assert.ErrorIs(t, it.Err(), context.Canceled)
+	})
+
+	t.Run("Should keep the history of a chat room", func(t *testing.T) {
+		now := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+		room := behavioral.NewChatRoom("general")
+		behavioral.ExportSetChatRoomClock(room, func() time.Time { return now })
+		room.SetMessageStore(behavioral.NewMemoryMessageStore())
+		john := behavioral.NewChatUser("John")
+		jane := behavioral.NewChatUser("Jane")
+		room.Join(john)
+		room.Join(jane)
+		john.Say("hi room")
+		jane.PrivateMessage("John", "hi john")
+
+		history := []behavioral.ChatMessage{}
+		lines := []string{}
+		for it := room.History(behavioral.MessageQuery{Participant: "Jane"}); it.MoveNext(ctx); {
+			history = append(history, it.Value())
+			lines = append(lines, it.Value().String())
+		}
+
+		assert.Equal(t, []string{"Room: John joins the chat", "Room: Jane joins the chat", "John: hi room", "Jane: hi john"}, lines)
+		assert.Equal(t, uint64(4), history[3].ID)
+		assert.Equal(t, now, history[3].Time)
+		assert.Equal(t, "John", history[3].Recipient)
+	})
+
+	t.Run("Should fail to iterate a room without a store", func(t *testing.T) {
+		it := behavioral.NewChatRoom("general").History(behavioral.MessageQuery{})
+
+		assert.False(t, it.MoveNext(ctx))
+		assert.ErrorIs(t, it.Err(), behavioral.ErrNoMessageStore)
+	})
+}
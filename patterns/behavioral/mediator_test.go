@@ -1,7 +1,12 @@
 package behavioral_test
 
 import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/fabricioandreis/design-patterns-go/patterns/behavioral"
 	"github.com/stretchr/testify/assert"
@@ -28,10 +33,219 @@ func TestMediator(t *testing.T) {
 		}
 		for u, e := range expected {
 			i := 0
-			for log := range u.ChatLog() {
+			for log := range u.ChatLog(context.Background()).Values() {
 				assert.Equal(t, e[i], log)
 				i++
 			}
 		}
 	})
+
+	collect := func(u *behavioral.ChatUser) []string {
+		logs := []string{}
+		for log := range u.ChatLog(context.Background()).Values() {
+			logs = append(logs, log)
+		}
+		return logs
+	}
+
+	t.Run("Should broadcast when a user leaves the room", func(t *testing.T) {
+		room := behavioral.NewChatRoom("general")
+		john := behavioral.NewChatUser("John")
+		jane := behavioral.NewChatUser("Jane")
+		assert.NoError(t, room.Join(john))
+		assert.NoError(t, room.Join(jane))
+
+		assert.NoError(t, room.Leave(jane))
+
+		assert.Nil(t, jane.Room)
+		assert.Equal(t, []string{"John"}, room.Users())
+		assert.Equal(t, []string{"Room: Jane joins the chat", "Room: Jane leaves the chat"}, collect(john))
+		assert.ErrorIs(t, room.Leave(jane), behavioral.ErrNotInRoom)
+		assert.ErrorIs(t, jane.Say("anyone?"), behavioral.ErrNotInRoom)
+	})
+
+	t.Run("Should return an error for unknown recipients", func(t *testing.T) {
+		room := behavioral.ChatRoom{}
+		john := behavioral.NewChatUser("John")
+		room.Join(john)
+
+		err := john.PrivateMessage("Nobody", "hello?")
+
+		assert.ErrorIs(t, err, behavioral.ErrUnknownRecipient)
+	})
+
+	t.Run("Should keep a bounded chat history", func(t *testing.T) {
+		room := behavioral.ChatRoom{}
+		john := behavioral.NewChatUser("John")
+		jane := behavioral.NewChatUser("Jane")
+		john.SetChatLogSize(2)
+		room.Join(john)
+		room.Join(jane)
+
+		jane.Say("one")
+		jane.Say("two")
+		jane.Say("three")
+
+		assert.Equal(t, []string{"Jane: two", "Jane: three"}, collect(john))
+	})
+
+	t.Run("Should move users between rooms of a server", func(t *testing.T) {
+		server := behavioral.NewChatServer()
+		john := behavioral.NewChatUser("John")
+		jane := behavioral.NewChatUser("Jane")
+		server.Room("general").Join(john)
+		server.Room("general").Join(jane)
+
+		assert.NoError(t, server.Room("random").Join(jane))
+
+		assert.Equal(t, []string{"general", "random"}, server.Rooms())
+		assert.Equal(t, []string{"John"}, server.Room("general").Users())
+		assert.Equal(t, []string{"Jane"}, server.Room("random").Users())
+		assert.Same(t, server.Room("random"), jane.Room)
+	})
+
+	t.Run("Should keep users in their room when the message store of either room fails", func(t *testing.T) {
+		for _, broken := range []string{"general", "random"} {
+			general, random := behavioral.NewChatRoom("general"), behavioral.NewChatRoom("random")
+			store, err := behavioral.NewFileMessageStore(filepath.Join(t.TempDir(), "chat.log"))
+			assert.NoError(t, err)
+			map[string]*behavioral.ChatRoom{"general": general, "random": random}[broken].SetMessageStore(store)
+			john, jane := behavioral.NewChatUser("John"), behavioral.NewChatUser("Jane")
+			assert.NoError(t, general.Join(john))
+			assert.NoError(t, general.Join(jane))
+			behavioral.ExportBreakMessageStoreFile(store)
+
+			assert.Error(t, random.Join(john), broken)
+
+			assert.Equal(t, []string{"John", "Jane"}, general.Users(), broken)
+			assert.Empty(t, random.Users(), broken)
+			assert.Same(t, general, john.Room, broken)
+			assert.NotContains(t, collect(jane), "Room: John leaves the chat", broken)
+		}
+	})
+
+	t.Run("Should keep users who cannot join another room in their room", func(t *testing.T) {
+		general, random := behavioral.NewChatRoom("general"), behavioral.NewChatRoom("random")
+		random.AddModerator("Jane")
+		john := behavioral.NewChatUser("John")
+		assert.NoError(t, random.Join(john))
+		assert.NoError(t, random.Ban("Jane", "John"))
+		assert.NoError(t, general.Join(john))
+
+		assert.ErrorIs(t, random.Join(john), behavioral.ErrBanned)
+		assert.Equal(t, []string{"John"}, general.Users())
+		assert.Same(t, general, john.Room)
+	})
+
+	t.Run("Should allow only moderators to mute, kick and ban", func(t *testing.T) {
+		room := behavioral.NewChatRoom("general")
+		room.AddModerator("Jane")
+		john := behavioral.NewChatUser("John")
+		jane := behavioral.NewChatUser("Jane")
+		room.Join(john)
+		room.Join(jane)
+
+		assert.ErrorIs(t, room.Mute("John", "Jane"), behavioral.ErrNotModerator)
+
+		assert.NoError(t, room.Mute("Jane", "John"))
+		assert.ErrorIs(t, john.Say("let me talk"), behavioral.ErrMuted)
+		assert.NoError(t, room.Unmute("Jane", "John"))
+		assert.NoError(t, john.Say("thanks"))
+
+		assert.NoError(t, room.Kick("Jane", "John"))
+		assert.Nil(t, john.Room)
+		assert.NoError(t, room.Join(john))
+
+		assert.NoError(t, room.Ban("Jane", "John"))
+		assert.ErrorIs(t, room.Join(john), behavioral.ErrBanned)
+		assert.Equal(t, []string{"Jane"}, room.Users())
+	})
+
+	t.Run("Should not let anyone post as the room", func(t *testing.T) {
+		room := behavioral.NewChatRoom("general")
+		room.AddModerator("Jane")
+		john := behavioral.NewChatUser("John")
+		jane := behavioral.NewChatUser("Jane")
+		room.Join(john)
+		room.Join(jane)
+		assert.NoError(t, room.Ban("Jane", "John"))
+
+		assert.ErrorIs(t, room.Broadcast("Room", "John is a moderator now"), behavioral.ErrReservedName)
+		assert.ErrorIs(t, room.Unicast("Room", "Jane", "you were kicked"), behavioral.ErrReservedName)
+		assert.ErrorIs(t, room.Join(behavioral.NewChatUser("Room")), behavioral.ErrReservedName)
+		assert.Equal(t, []string{"Jane"}, room.Users())
+	})
+
+	t.Run("Should rate limit each user", func(t *testing.T) {
+		now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		room := behavioral.NewChatRoom("general")
+		behavioral.ExportSetChatRoomClock(room, func() time.Time { return now })
+		room.SetRateLimit(2, time.Minute)
+		john := behavioral.NewChatUser("John")
+		jane := behavioral.NewChatUser("Jane")
+		room.Join(john)
+		room.Join(jane)
+
+		assert.NoError(t, john.Say("one"))
+		assert.NoError(t, john.Say("two"))
+		assert.ErrorIs(t, john.Say("three"), behavioral.ErrRateLimited)
+		assert.NoError(t, jane.Say("I can still talk"))
+
+		now = now.Add(time.Minute)
+		assert.NoError(t, john.Say("three"))
+	})
+
+	t.Run("Should be safe for concurrent use", func(t *testing.T) {
+		room := behavioral.NewChatRoom("general")
+		listener := behavioral.NewChatUser("Listener")
+		listener.SetChatLogSize(0)
+		room.Join(listener)
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				u := behavioral.NewChatUser(fmt.Sprintf("User %d", i))
+				room.Join(u)
+				u.Say("hello")
+				u.PrivateMessage("Listener", "psst")
+				room.Leave(u)
+			}(i)
+		}
+		wg.Wait()
+
+		assert.Equal(t, []string{"Listener"}, room.Users())
+		assert.Len(t, collect(listener), 40)
+	})
+
+	t.Run("Should move a user joining rooms concurrently to only one of them", func(t *testing.T) {
+		rooms := []*behavioral.ChatRoom{behavioral.NewChatRoom("a"), behavioral.NewChatRoom("b"), behavioral.NewChatRoom("c")}
+		users := []*behavioral.ChatUser{behavioral.NewChatUser("John"), behavioral.NewChatUser("Jane")}
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 30; i++ {
+			for j, u := range users {
+				wg.Add(1)
+				go func(r *behavioral.ChatRoom, u *behavioral.ChatUser) {
+					defer wg.Done()
+					r.Join(u)
+				}(rooms[(i+j)%len(rooms)], u)
+			}
+		}
+		wg.Wait()
+
+		for _, u := range users {
+			in := 0
+			for _, r := range rooms {
+				for _, name := range r.Users() {
+					if name == u.Name {
+						in++
+						assert.Same(t, r, u.Room)
+					}
+				}
+			}
+			assert.Equal(t, 1, in, u.Name)
+		}
+	})
 }
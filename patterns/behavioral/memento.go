@@ -1,5 +1,16 @@
 package behavioral
 
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+)
+
 // Memento is a behavioral design pattern that lets you save and restore the previous state of an object without revealing the details of its implementation.
 // It is implemented as a token representing the system state. Lets us roll back to the state when the token was generated.
 // May or may not directly expose state information
@@ -12,86 +23,374 @@ package behavioral
 // - A memento is not required to expose directly the states(s) to which it reverts the system
 // - Can be used to implement undo/redo (but the Command design pattern saves memory)
 
+var (
+	ErrForgedMemento             = errors.New("memento was not produced by this system")
+	ErrForeignMemento            = errors.New("memento belongs to a different account")
+	ErrUnsupportedMementoVersion = errors.New("unsupported memento version")
+)
+
+// Memento is opaque: only its originator can create one, and it carries an HMAC over its content,
+// so that an originator only restores states that it actually produced.
+type Memento struct {
+	account  string
+	instance [8]byte
+	format   byte
+	version  uint64
+	balance  float64
+	mac      []byte
+}
+
+func (m *Memento) Balance() float64 {
+	return m.balance
+}
+
+// Version is the sequence number of the state within the originator instance that produced it.
+// Instances sharing an account each count from zero, so a state is only identified by its origin and version together.
+func (m *Memento) Version() uint64 {
+	return m.version
+}
+
+// Origin identifies the originator instance that produced the memento
+func (m *Memento) Origin() string {
+	return hex.EncodeToString(m.instance[:])
+}
+
+func (m *Memento) Account() string {
+	return m.account
+}
+
+const mementoFormat byte = 1
+
+// mementoSigner creates and verifies the mementos of one account
+type mementoSigner struct {
+	account  string
+	instance [8]byte // random, so that instances sharing an account never sign the same version twice
+	key      []byte
+	version  uint64
+}
+
+func newMementoSigner(account string, key []byte) *mementoSigner {
+	if key == nil {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+	}
+	if account == "" {
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			panic(err)
+		}
+		account = hex.EncodeToString(id)
+	}
+	s := &mementoSigner{account: account, key: key}
+	if _, err := rand.Read(s.instance[:]); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (s *mementoSigner) sign(balance float64) *Memento {
+	m := &Memento{account: s.account, instance: s.instance, format: mementoFormat, version: s.version, balance: balance}
+	s.version++
+	m.mac = s.mac(m)
+	return m
+}
+
+func (s *mementoSigner) mac(m *Memento) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte{m.format})
+	h.Write([]byte(m.account))
+	h.Write(m.instance[:])
+	fields := [16]byte{}
+	binary.BigEndian.PutUint64(fields[:8], m.version)
+	binary.BigEndian.PutUint64(fields[8:], math.Float64bits(m.balance))
+	h.Write(fields[:])
+	return h.Sum(nil)
+}
+
+func (s *mementoSigner) verify(m *Memento) error {
+	if m == nil || len(m.mac) == 0 {
+		return ErrForgedMemento
+	}
+	if m.format != mementoFormat {
+		return fmt.Errorf("%w: %d", ErrUnsupportedMementoVersion, m.format)
+	}
+	if m.account != s.account {
+		return fmt.Errorf("%w: %s", ErrForeignMemento, m.account)
+	}
+	if !hmac.Equal(m.mac, s.mac(m)) {
+		return ErrForgedMemento
+	}
+	return nil
+}
+
 type BankAccountMemento struct {
 	balance float64
+	signer  *mementoSigner
 }
 
+// NewBankAccountMemento creates an account with a random ID and signing key, which only lives as long as the process.
 func NewBankAccountMemento(balance float64) (*BankAccountMemento, *Memento) {
-	return &BankAccountMemento{balance}, &Memento{balance}
+	return NewBankAccountMementoWithKey("", nil, balance)
 }
 
-type Memento struct {
-	Balance float64
+// NewBankAccountMementoWithKey creates an account whose mementos stay valid across processes sharing the same ID and key.
+// Each process signs its mementos as a distinct origin, so their versions never collide.
+func NewBankAccountMementoWithKey(id string, key []byte, balance float64) (*BankAccountMemento, *Memento) {
+	b := &BankAccountMemento{balance: balance, signer: newMementoSigner(id, key)}
+	return b, b.signer.sign(balance)
+}
+
+func (b *BankAccountMemento) ID() string {
+	return b.signer.account
 }
 
 func (b *BankAccountMemento) Deposit(amount float64) *Memento {
 	b.balance += amount
-	return &Memento{float64(b.balance)}
+	return b.signer.sign(b.balance)
 }
 
-func (b *BankAccountMemento) Restore(m *Memento) {
-	b.balance = m.Balance
+// Restore rejects mementos that were forged, tampered with or produced by another account.
+func (b *BankAccountMemento) Restore(m *Memento) error {
+	if err := b.signer.verify(m); err != nil {
+		return err
+	}
+	b.balance = m.balance
+	return nil
 }
 
 func (b *BankAccountMemento) Balance() float64 {
 	return b.balance
 }
 
+// PaymentAccount keeps its mementos in a history tree, so that it can undo and redo changes.
+// Changes made after an undo either truncate the redo branch or fork a new branch next to it, see RedoMode.
 type PaymentAccount struct {
-	balance float64
-	changes []*Memento
-	current int // index of the changes slice
+	balance     float64
+	current     *historyNode
+	nodes       []*historyNode // in creation order, oldest first
+	nextID      int
+	checkpoints map[string]*historyNode
+	mode        RedoMode
+	maxHistory  int
+	signer      *mementoSigner
+}
+
+type RedoMode int
+
+const (
+	TruncateRedo RedoMode = iota // a change after an undo discards the changes that could be redone
+	ForkRedo                     // a change after an undo starts a new branch, keeping the old one reachable through JumpTo
+)
+
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+type historyNode struct {
+	id         int
+	memento    *Memento
+	parent     *historyNode
+	children   []*historyNode
+	redo       *historyNode // the child that Redo moves to
+	checkpoint string
+}
+
+// HistoryEntry describes one snapshot of the history
+type HistoryEntry struct {
+	ID         int
+	ParentID   int // -1 for the oldest snapshot of a branch
+	Balance    float64
+	Checkpoint string
+	Current    bool
 }
 
 func NewPaymentAccount(balance float64) *PaymentAccount {
-	mem := &Memento{balance}
-	return &PaymentAccount{
-		balance: balance,
-		changes: []*Memento{mem},
-		current: 0,
-	}
+	acc := &PaymentAccount{checkpoints: map[string]*historyNode{}, signer: newMementoSigner("", nil)}
+	acc.applyMemento(acc.signer.sign(balance))
+	return acc
 }
 
 func (acc *PaymentAccount) Balance() float64 {
 	return acc.balance
 }
 
+func (acc *PaymentAccount) SetRedoMode(mode RedoMode) {
+	acc.mode = mode
+}
+
+// SetMaxHistory bounds the number of snapshots kept, evicting the oldest ones first. A non-positive max keeps every snapshot.
+func (acc *PaymentAccount) SetMaxHistory(max int) {
+	acc.maxHistory = max
+	acc.evict()
+}
+
 func (acc *PaymentAccount) Deposit(amount float64) *Memento {
-	mem := &Memento{Balance: acc.balance + amount}
+	mem := acc.signer.sign(acc.balance + amount)
 	acc.applyMemento(mem)
 	return mem
 }
 
-func (acc *PaymentAccount) Restore(mem *Memento) {
-	if mem == nil {
-		return
+func (acc *PaymentAccount) Restore(mem *Memento) error {
+	if err := acc.signer.verify(mem); err != nil {
+		return err
 	}
 
 	acc.applyMemento(mem)
+	return nil
 }
 
 func (acc *PaymentAccount) applyMemento(mem *Memento) {
-	acc.balance = mem.Balance
-	acc.changes = append(acc.changes, mem)
-	acc.current++
+	node := &historyNode{id: acc.nextID, memento: mem, parent: acc.current}
+	acc.nextID++
+
+	if parent := acc.current; parent != nil {
+		if acc.mode == TruncateRedo {
+			for _, child := range parent.children {
+				acc.forget(child)
+			}
+			parent.children = nil
+		}
+		parent.children = append(parent.children, node)
+		parent.redo = node
+	}
+
+	acc.nodes = append(acc.nodes, node)
+	acc.moveTo(node)
+	acc.evict()
+}
+
+// forget drops a snapshot and all the snapshots that descend from it
+func (acc *PaymentAccount) forget(node *historyNode) {
+	for _, child := range node.children {
+		acc.forget(child)
+	}
+	for i, n := range acc.nodes {
+		if n == node {
+			acc.nodes = append(acc.nodes[:i], acc.nodes[i+1:]...)
+			break
+		}
+	}
+	if node.checkpoint != "" {
+		delete(acc.checkpoints, node.checkpoint)
+	}
+}
+
+// evict drops the oldest snapshots beyond the limit. The current snapshot is never dropped, so the next oldest
+// goes in its place, and a dropped snapshot hands its children to its parent, so that undo and redo always stay
+// connected to the rest of the history.
+func (acc *PaymentAccount) evict() {
+	for acc.maxHistory > 0 && len(acc.nodes) > acc.maxHistory {
+		victim := acc.nodes[0]
+		if victim == acc.current {
+			victim = acc.nodes[1]
+		}
+		acc.drop(victim)
+	}
+}
+
+// drop removes a single snapshot, handing its children over to its parent
+func (acc *PaymentAccount) drop(node *historyNode) {
+	for i, n := range acc.nodes {
+		if n == node {
+			acc.nodes = append(acc.nodes[:i], acc.nodes[i+1:]...)
+			break
+		}
+	}
+	for _, child := range node.children {
+		child.parent = node.parent
+	}
+	if parent := node.parent; parent != nil {
+		children := make([]*historyNode, 0, len(parent.children)+len(node.children))
+		for _, child := range parent.children {
+			if child == node {
+				children = append(children, node.children...)
+			} else {
+				children = append(children, child)
+			}
+		}
+		parent.children = children
+		if parent.redo == node {
+			parent.redo = node.redo
+		}
+	}
+	if node.checkpoint != "" {
+		delete(acc.checkpoints, node.checkpoint)
+	}
+}
+
+func (acc *PaymentAccount) moveTo(node *historyNode) *Memento {
+	acc.current = node
+	acc.balance = node.memento.balance
+	return node.memento
 }
 
 func (acc *PaymentAccount) Undo() *Memento {
-	if acc.current <= 0 {
+	if acc.current.parent == nil {
 		return nil
 	}
-	acc.current--
-	mem := acc.changes[acc.current]
-	acc.balance = mem.Balance
-	return mem
+	acc.current.parent.redo = acc.current
+	return acc.moveTo(acc.current.parent)
 }
 
 func (acc *PaymentAccount) Redo() *Memento {
-	if acc.current+1 >= len(acc.changes) {
+	if acc.current.redo == nil {
 		return nil
 	}
-	acc.current++
-	mem := acc.changes[acc.current]
-	acc.balance = mem.Balance
-	return mem
+	return acc.moveTo(acc.current.redo)
+}
+
+// Checkpoint names the current snapshot, replacing any snapshot previously known by that name.
+func (acc *PaymentAccount) Checkpoint(name string) {
+	if old, ok := acc.checkpoints[name]; ok {
+		old.checkpoint = ""
+	}
+	if acc.current.checkpoint != "" {
+		delete(acc.checkpoints, acc.current.checkpoint)
+	}
+	acc.current.checkpoint = name
+	acc.checkpoints[name] = acc.current
+}
+
+func (acc *PaymentAccount) JumpTo(id int) (*Memento, error) {
+	for _, node := range acc.nodes {
+		if node.id == id {
+			return acc.jump(node), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %d", ErrSnapshotNotFound, id)
+}
+
+func (acc *PaymentAccount) JumpToCheckpoint(name string) (*Memento, error) {
+	node, ok := acc.checkpoints[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSnapshotNotFound, name)
+	}
+	return acc.jump(node), nil
+}
+
+// jump moves to any snapshot, pointing the redo path of its ancestors to it so that undo and redo retrace the way back
+func (acc *PaymentAccount) jump(node *historyNode) *Memento {
+	for n := node; n.parent != nil; n = n.parent {
+		n.parent.redo = n
+	}
+	return acc.moveTo(node)
+}
+
+// History lists the snapshots that are still kept, oldest first
+func (acc *PaymentAccount) History() []HistoryEntry {
+	entries := make([]HistoryEntry, 0, len(acc.nodes))
+	for _, node := range acc.nodes {
+		parentID := -1
+		if node.parent != nil {
+			parentID = node.parent.id
+		}
+		entries = append(entries, HistoryEntry{
+			ID:         node.id,
+			ParentID:   parentID,
+			Balance:    node.memento.balance,
+			Checkpoint: node.checkpoint,
+			Current:    node == acc.current,
+		})
+	}
+	return entries
 }
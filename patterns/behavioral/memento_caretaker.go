@@ -0,0 +1,386 @@
+package behavioral
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Generic caretaker: keeps mementos of any originator without knowing what is inside them.
+// Snapshots are stored encoded, so that they can be compressed, delta-encoded against the previous one and persisted to disk.
+
+type Originator[S any] interface {
+	Snapshot() S
+	Restore(S)
+}
+
+type Codec interface {
+	Encode(v any) ([]byte, error)
+	Decode(data []byte, v any) error
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Encode(v any) ([]byte, error) {
+	buf := bytes.Buffer{}
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (gobCodec) Decode(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Decode(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+var (
+	GobCodec  Codec = gobCodec{}
+	JSONCodec Codec = jsonCodec{}
+)
+
+const DefaultKeyframeInterval = 16
+
+type CaretakerOptions struct {
+	Codec    Codec // GobCodec when nil
+	Compress bool
+	// Delta stores each snapshot as the difference to the previous one.
+	// Every KeyframeInterval snapshots a full one is stored, bounding the work to restore any of them.
+	Delta            bool
+	KeyframeInterval int    // DefaultKeyframeInterval when not positive
+	Dir              string // snapshots are persisted in this directory when not empty
+}
+
+const (
+	recordDelta byte = 1 << iota
+	recordCompressed
+)
+
+const snapshotExt = ".snapshot"
+
+type Caretaker[S any] struct {
+	mu         sync.Mutex
+	originator Originator[S]
+	opts       CaretakerOptions
+	records    [][]byte // flags byte followed by the payload
+	last       []byte   // encoding of the latest snapshot, the base of the next delta
+}
+
+// NewCaretaker loads the snapshots already persisted in opts.Dir, if any.
+func NewCaretaker[S any](originator Originator[S], opts CaretakerOptions) (*Caretaker[S], error) {
+	if opts.Codec == nil {
+		opts.Codec = GobCodec
+	}
+	if opts.KeyframeInterval <= 0 {
+		opts.KeyframeInterval = DefaultKeyframeInterval
+	}
+	c := &Caretaker[S]{originator: originator, opts: opts}
+	if opts.Dir == "" {
+		return c, nil
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), snapshotExt) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		if name != snapshotFileName(i) {
+			return nil, fmt.Errorf("unexpected snapshot file %s, want %s", name, snapshotFileName(i))
+		}
+		record, err := os.ReadFile(filepath.Join(opts.Dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if len(record) == 0 {
+			return nil, fmt.Errorf("empty snapshot file %s", name)
+		}
+		c.records = append(c.records, record)
+	}
+	if len(c.records) > 0 {
+		if c.last, err = c.encoded(len(c.records) - 1); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func snapshotFileName(index int) string {
+	return fmt.Sprintf("%08d%s", index, snapshotExt)
+}
+
+// Save takes a snapshot of the originator and returns its index
+func (c *Caretaker[S]) Save() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := c.opts.Codec.Encode(c.originator.Snapshot())
+	if err != nil {
+		return 0, err
+	}
+
+	var flags byte
+	payload := data
+	index := len(c.records)
+	if c.opts.Delta && index%c.opts.KeyframeInterval != 0 {
+		flags |= recordDelta
+		payload = encodeDelta(c.last, data)
+	}
+	if c.opts.Compress {
+		flags |= recordCompressed
+		if payload, err = deflate(payload); err != nil {
+			return 0, err
+		}
+	}
+	record := append([]byte{flags}, payload...)
+
+	if c.opts.Dir != "" {
+		if err := writeFileAtomically(filepath.Join(c.opts.Dir, snapshotFileName(index)), record); err != nil {
+			return 0, err
+		}
+	}
+	c.records = append(c.records, record)
+	c.last = data
+	return index, nil
+}
+
+func (c *Caretaker[S]) Restore(index int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if index < 0 || index >= len(c.records) {
+		return fmt.Errorf("%w: %d", ErrSnapshotNotFound, index)
+	}
+
+	data, err := c.encoded(index)
+	if err != nil {
+		return err
+	}
+	var s S
+	if err := c.opts.Codec.Decode(data, &s); err != nil {
+		return err
+	}
+	c.originator.Restore(s)
+	return nil
+}
+
+func (c *Caretaker[S]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.records)
+}
+
+// StoredBytes is the space taken by all the snapshots as stored
+func (c *Caretaker[S]) StoredBytes() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, r := range c.records {
+		n += len(r)
+	}
+	return n
+}
+
+// encoded rebuilds the encoding of a snapshot, starting from the closest full snapshot before it
+func (c *Caretaker[S]) encoded(index int) ([]byte, error) {
+	start := index
+	for start > 0 && c.records[start][0]&recordDelta != 0 {
+		start--
+	}
+
+	var data []byte
+	for i := start; i <= index; i++ {
+		flags, payload := c.records[i][0], c.records[i][1:]
+		if flags&recordCompressed != 0 {
+			var err error
+			if payload, err = inflate(payload); err != nil {
+				return nil, fmt.Errorf("snapshot %d: %w", i, err)
+			}
+		}
+		if flags&recordDelta == 0 {
+			data = payload
+			continue
+		}
+		patched, err := applyDelta(data, payload)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %d: %w", i, err)
+		}
+		data = patched
+	}
+	return data, nil
+}
+
+var errCorruptDelta = errors.New("corrupt delta")
+
+// Deltas are a sequence of operations that rebuild the new encoding from the previous one:
+// copy a range of the previous encoding, or insert literal bytes.
+// Edits to large documents are usually small, so most of the new encoding is copied.
+const (
+	deltaCopy byte = iota
+	deltaInsert
+)
+
+const deltaBlockSize = 16
+
+func encodeDelta(base, target []byte) []byte {
+	// index the blocks of the base, so that matching ranges of the target can be found
+	blocks := map[string]int{}
+	for off := 0; off+deltaBlockSize <= len(base); off += deltaBlockSize {
+		key := string(base[off : off+deltaBlockSize])
+		if _, ok := blocks[key]; !ok {
+			blocks[key] = off
+		}
+	}
+
+	out := []byte{}
+	literal := 0 // start of the bytes of the target not matched yet
+	for i := 0; i+deltaBlockSize <= len(target); {
+		off, ok := blocks[string(target[i:i+deltaBlockSize])]
+		if !ok {
+			i++
+			continue
+		}
+		// extend the match in both directions
+		start, end := i, i+deltaBlockSize
+		for start > literal && off > 0 && base[off-1] == target[start-1] {
+			start--
+			off--
+		}
+		for end < len(target) && off+end-start < len(base) && base[off+end-start] == target[end] {
+			end++
+		}
+
+		out = appendDeltaInsert(out, target[literal:start])
+		out = append(out, deltaCopy)
+		out = appendUvarint(out, uint64(off))
+		out = appendUvarint(out, uint64(end-start))
+		i, literal = end, end
+	}
+	return appendDeltaInsert(out, target[literal:])
+}
+
+func appendDeltaInsert(out, literal []byte) []byte {
+	if len(literal) == 0 {
+		return out
+	}
+	out = append(out, deltaInsert)
+	out = appendUvarint(out, uint64(len(literal)))
+	return append(out, literal...)
+}
+
+func appendUvarint(out []byte, v uint64) []byte {
+	buf := [binary.MaxVarintLen64]byte{}
+	n := binary.PutUvarint(buf[:], v)
+	return append(out, buf[:n]...)
+}
+
+func applyDelta(base, delta []byte) ([]byte, error) {
+	out := []byte{}
+	r := bytes.NewReader(delta)
+	for r.Len() > 0 {
+		op, _ := r.ReadByte()
+		switch op {
+		case deltaCopy:
+			off, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, errCorruptDelta
+			}
+			n, err := binary.ReadUvarint(r)
+			if err != nil || off+n > uint64(len(base)) {
+				return nil, errCorruptDelta
+			}
+			out = append(out, base[off:off+n]...)
+		case deltaInsert:
+			n, err := binary.ReadUvarint(r)
+			if err != nil || n > uint64(r.Len()) {
+				return nil, errCorruptDelta
+			}
+			literal := make([]byte, n)
+			r.Read(literal)
+			out = append(out, literal...)
+		default:
+			return nil, errCorruptDelta
+		}
+	}
+	return out, nil
+}
+
+func deflate(data []byte) ([]byte, error) {
+	buf := bytes.Buffer{}
+	w, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func inflate(data []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func writeFileAtomically(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	// The contents must be on disk before the rename is, or a power loss could leave an empty file behind the new name
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir makes the entries of the directory durable, such as a file just renamed into it
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
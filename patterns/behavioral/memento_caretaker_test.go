@@ -0,0 +1,124 @@
+package behavioral_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fabricioandreis/design-patterns-go/patterns/behavioral"
+	"github.com/stretchr/testify/assert"
+)
+
+type document struct {
+	Title string
+	Lines []string
+}
+
+type editor struct {
+	doc document
+}
+
+func (e *editor) Snapshot() document {
+	return document{Title: e.doc.Title, Lines: append([]string{}, e.doc.Lines...)}
+}
+
+func (e *editor) Restore(d document) {
+	e.doc = d
+}
+
+func (e *editor) Type(line int, text string) {
+	e.doc.Lines[line] += text
+}
+
+func newLargeEditor() *editor {
+	e := &editor{document{Title: "Report"}}
+	for i := 0; i < 500; i++ {
+		e.doc.Lines = append(e.doc.Lines, fmt.Sprintf("line %d of a rather long document", i))
+	}
+	return e
+}
+
+func TestCaretaker(t *testing.T) {
+	codecs := map[string]behavioral.Codec{"gob": behavioral.GobCodec, "json": behavioral.JSONCodec}
+
+	for name, codec := range codecs {
+		for _, opts := range []behavioral.CaretakerOptions{
+			{Codec: codec},
+			{Codec: codec, Compress: true},
+			{Codec: codec, Delta: true, KeyframeInterval: 3},
+			{Codec: codec, Delta: true, Compress: true},
+		} {
+			opts := opts
+			t.Run(fmt.Sprintf("Should restore any snapshot with %s, compress=%t, delta=%t", name, opts.Compress, opts.Delta), func(t *testing.T) {
+				e := newLargeEditor()
+				c, err := behavioral.NewCaretaker[document](e, opts)
+				assert.NoError(t, err)
+
+				expected := []document{}
+				for i := 0; i < 10; i++ {
+					e.Type(i*37%500, fmt.Sprintf(" edit %d", i))
+					_, err := c.Save()
+					assert.NoError(t, err)
+					expected = append(expected, e.Snapshot())
+				}
+
+				for _, i := range []int{4, 0, 9, 3, 7} {
+					assert.NoError(t, c.Restore(i))
+					assert.Equal(t, expected[i], e.doc)
+				}
+			})
+		}
+	}
+
+	t.Run("Should store successive snapshots in less space with delta and compression", func(t *testing.T) {
+		sizes := map[string]int{}
+		for name, opts := range map[string]behavioral.CaretakerOptions{
+			"plain":      {},
+			"compressed": {Compress: true},
+			"delta":      {Delta: true, Compress: true},
+		} {
+			e := newLargeEditor()
+			c, _ := behavioral.NewCaretaker[document](e, opts)
+			for i := 0; i < 20; i++ {
+				e.Type(250, "x")
+				c.Save()
+			}
+			sizes[name] = c.StoredBytes()
+		}
+
+		assert.Less(t, sizes["compressed"], sizes["plain"])
+		assert.Less(t, sizes["delta"], sizes["compressed"]/5)
+	})
+
+	t.Run("Should persist snapshots across restarts", func(t *testing.T) {
+		dir := t.TempDir()
+		opts := behavioral.CaretakerOptions{Codec: behavioral.JSONCodec, Delta: true, Compress: true, KeyframeInterval: 4, Dir: dir}
+		e := newLargeEditor()
+		c, _ := behavioral.NewCaretaker[document](e, opts)
+		for i := 0; i < 6; i++ {
+			e.Type(0, "!")
+			c.Save()
+		}
+
+		restarted := &editor{}
+		c, err := behavioral.NewCaretaker[document](restarted, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, 6, c.Len())
+		assert.NoError(t, c.Restore(5))
+		assert.True(t, strings.HasSuffix(restarted.doc.Lines[0], "!!!!!!"))
+
+		restarted.Type(0, "?")
+		index, err := c.Save()
+		assert.NoError(t, err)
+		assert.Equal(t, 6, index)
+		assert.NoError(t, c.Restore(2))
+		assert.NoError(t, c.Restore(6))
+		assert.True(t, strings.HasSuffix(restarted.doc.Lines[0], "!!!!!!?"))
+	})
+
+	t.Run("Should fail to restore unknown snapshots", func(t *testing.T) {
+		c, _ := behavioral.NewCaretaker[document](newLargeEditor(), behavioral.CaretakerOptions{})
+
+		assert.ErrorIs(t, c.Restore(0), behavioral.ErrSnapshotNotFound)
+	})
+}
@@ -1,6 +1,7 @@
 package behavioral_test
 
 import (
+	"math/rand"
 	"testing"
 
 	"github.com/fabricioandreis/design-patterns-go/patterns/behavioral"
@@ -18,6 +19,65 @@ func TestMemento(t *testing.T) {
 		assert.Equal(t, 50.0, ba.Balance())
 	})
 
+	t.Run("Should reject forged or tampered mementos", func(t *testing.T) {
+		ba, mem0 := behavioral.NewBankAccountMemento(50.0)
+		ba.Deposit(100.0)
+
+		assert.ErrorIs(t, ba.Restore(&behavioral.Memento{}), behavioral.ErrForgedMemento)
+		assert.ErrorIs(t, ba.Restore(nil), behavioral.ErrForgedMemento)
+		assert.ErrorIs(t, ba.Restore(behavioral.ExportTamperMemento(mem0, 1e6)), behavioral.ErrForgedMemento)
+		assert.Equal(t, 150.0, ba.Balance())
+		assert.NoError(t, ba.Restore(mem0))
+		assert.Equal(t, 50.0, ba.Balance())
+	})
+
+	t.Run("Should reject mementos from a different account", func(t *testing.T) {
+		ba, _ := behavioral.NewBankAccountMemento(50.0)
+		other, _ := behavioral.NewBankAccountMemento(50.0)
+		mem := other.Deposit(1000.0)
+
+		assert.ErrorIs(t, ba.Restore(mem), behavioral.ErrForeignMemento)
+		assert.Equal(t, 50.0, ba.Balance())
+	})
+
+	t.Run("Should accept mementos of the same account across restarts", func(t *testing.T) {
+		key := []byte("a secret shared by every instance")
+		ba, _ := behavioral.NewBankAccountMementoWithKey("acc-1", key, 50.0)
+		mem := ba.Deposit(25.0)
+
+		restarted, _ := behavioral.NewBankAccountMementoWithKey("acc-1", key, 0)
+		impostor, _ := behavioral.NewBankAccountMementoWithKey("acc-1", []byte("guessed key"), 0)
+
+		assert.NoError(t, restarted.Restore(mem))
+		assert.Equal(t, 75.0, restarted.Balance())
+		assert.Equal(t, uint64(1), mem.Version())
+		assert.Equal(t, "acc-1", mem.Account())
+		assert.ErrorIs(t, impostor.Restore(mem), behavioral.ErrForgedMemento)
+	})
+
+	t.Run("Should tell apart mementos with the same version from different processes", func(t *testing.T) {
+		key := []byte("a secret shared by every instance")
+		first, _ := behavioral.NewBankAccountMementoWithKey("acc-1", key, 0)
+		second, _ := behavioral.NewBankAccountMementoWithKey("acc-1", key, 0)
+		a, b := first.Deposit(10), second.Deposit(20)
+
+		assert.Equal(t, a.Version(), b.Version())
+		assert.NotEqual(t, a.Origin(), b.Origin())
+		assert.NoError(t, first.Restore(b))
+		assert.Equal(t, 20.0, first.Balance())
+		assert.ErrorIs(t, first.Restore(behavioral.ExportSetMementoOrigin(b, a)), behavioral.ErrForgedMemento)
+	})
+
+	t.Run("Should only restore payment account mementos it produced", func(t *testing.T) {
+		pa := behavioral.NewPaymentAccount(25.0)
+		mem := pa.Deposit(100.0)
+		pa.Deposit(200.0)
+
+		assert.ErrorIs(t, pa.Restore(behavioral.ExportTamperMemento(mem, 1e6)), behavioral.ErrForgedMemento)
+		assert.NoError(t, pa.Restore(mem))
+		assert.Equal(t, 125.0, pa.Balance())
+	})
+
 	t.Run("Should be able to do and redo actions in payment account", func(t *testing.T) {
 		pa := behavioral.NewPaymentAccount(25.0)
 		pa.Deposit(100.0)
@@ -30,4 +90,188 @@ func TestMemento(t *testing.T) {
 		pa.Redo()
 		assert.Equal(t, 125.0, pa.Balance())
 	})
+
+	t.Run("Should truncate the redo branch when depositing after an undo", func(t *testing.T) {
+		pa := behavioral.NewPaymentAccount(0)
+		pa.Deposit(10)
+		pa.Deposit(20)
+		pa.Undo()
+
+		pa.Deposit(5)
+
+		assert.Equal(t, 15.0, pa.Balance())
+		assert.Nil(t, pa.Redo())
+		assert.Equal(t, 10.0, pa.Undo().Balance())
+		assert.Equal(t, 15.0, pa.Redo().Balance())
+		assert.Len(t, pa.History(), 3)
+	})
+
+	t.Run("Should fork the redo branch when depositing after an undo", func(t *testing.T) {
+		pa := behavioral.NewPaymentAccount(0)
+		pa.SetRedoMode(behavioral.ForkRedo)
+		pa.Deposit(10)
+		abandoned := pa.History()[1].ID
+		pa.Undo()
+		pa.Deposit(5)
+
+		assert.Len(t, pa.History(), 3)
+		assert.Equal(t, 0.0, pa.Undo().Balance())
+		assert.Equal(t, 5.0, pa.Redo().Balance()) // redo follows the latest branch
+
+		mem, err := pa.JumpTo(abandoned)
+		assert.NoError(t, err)
+		assert.Equal(t, 10.0, mem.Balance())
+		assert.Equal(t, 0.0, pa.Undo().Balance())
+		assert.Equal(t, 10.0, pa.Redo().Balance()) // and back to the branch we jumped to
+	})
+
+	t.Run("Should evict the oldest snapshots beyond the maximum depth", func(t *testing.T) {
+		pa := behavioral.NewPaymentAccount(0)
+		pa.SetMaxHistory(3)
+		for i := 0; i < 5; i++ {
+			pa.Deposit(1)
+		}
+
+		assert.Equal(t, []float64{3, 4, 5}, balances(pa.History()))
+		assert.NotNil(t, pa.Undo())
+		assert.NotNil(t, pa.Undo())
+		assert.Nil(t, pa.Undo())
+		assert.Equal(t, 3.0, pa.Balance())
+		_, err := pa.JumpTo(0)
+		assert.ErrorIs(t, err, behavioral.ErrSnapshotNotFound)
+	})
+
+	t.Run("Should keep the current snapshot when shrinking the history after undos", func(t *testing.T) {
+		pa := behavioral.NewPaymentAccount(0)
+		for i := 0; i < 5; i++ {
+			pa.Deposit(1)
+		}
+		pa.Undo()
+		pa.Undo()
+		pa.Undo()
+
+		pa.SetMaxHistory(3)
+		assert.Equal(t, []float64{2, 4, 5}, balances(pa.History()), "the oldest snapshots go first, but never the current one")
+		assert.Nil(t, pa.Undo())
+		assert.Equal(t, 4.0, pa.Redo().Balance(), "4 now descends from 2")
+
+		pa.SetMaxHistory(1)
+		history := pa.History()
+		assert.Equal(t, []float64{4}, balances(history))
+		assert.True(t, history[0].Current)
+		assert.Equal(t, 4.0, pa.Balance())
+		assert.Nil(t, pa.Undo())
+
+		pa.SetMaxHistory(2)
+		pa.Deposit(5)
+		assert.Equal(t, 4.0, pa.Undo().Balance())
+		assert.Equal(t, 9.0, pa.Redo().Balance())
+	})
+
+	t.Run("Should evict the oldest snapshot even when it was forked", func(t *testing.T) {
+		pa := behavioral.NewPaymentAccount(0)
+		pa.SetRedoMode(behavioral.ForkRedo)
+		pa.Deposit(10)
+		pa.Deposit(1)
+		pa.Undo()
+		pa.Undo()
+		pa.Deposit(5) // forks from 0, next to 10
+
+		pa.SetMaxHistory(3)
+
+		assert.Equal(t, []float64{10, 11, 5}, balances(pa.History()))
+		assert.Nil(t, pa.Undo(), "5 lost its parent")
+		mem, err := pa.JumpTo(pa.History()[1].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, 11.0, mem.Balance())
+		assert.Equal(t, 10.0, pa.Undo().Balance())
+		assert.Nil(t, pa.Undo())
+	})
+
+	t.Run("Should jump to named checkpoints", func(t *testing.T) {
+		pa := behavioral.NewPaymentAccount(100)
+		pa.Checkpoint("opening")
+		pa.Deposit(50)
+		pa.Checkpoint("salary")
+		pa.Deposit(-120)
+
+		mem, err := pa.JumpToCheckpoint("opening")
+		assert.NoError(t, err)
+		assert.Equal(t, 100.0, mem.Balance())
+		mem, _ = pa.JumpToCheckpoint("salary")
+		assert.Equal(t, 150.0, mem.Balance())
+		_, err = pa.JumpToCheckpoint("bonus")
+		assert.ErrorIs(t, err, behavioral.ErrSnapshotNotFound)
+
+		history := pa.History()
+		assert.Equal(t, "opening", history[0].Checkpoint)
+		assert.Equal(t, "salary", history[1].Checkpoint)
+		assert.True(t, history[1].Current)
+		assert.Equal(t, history[0].ID, history[1].ParentID)
+	})
+
+	t.Run("Should forget checkpoints of truncated or evicted snapshots", func(t *testing.T) {
+		pa := behavioral.NewPaymentAccount(0)
+		pa.SetMaxHistory(2)
+		pa.Checkpoint("start")
+		pa.Deposit(10)
+		pa.Checkpoint("ten")
+		pa.Undo()
+		pa.Deposit(1)
+		pa.Deposit(1)
+
+		_, err := pa.JumpToCheckpoint("start")
+		assert.ErrorIs(t, err, behavioral.ErrSnapshotNotFound)
+		_, err = pa.JumpToCheckpoint("ten")
+		assert.ErrorIs(t, err, behavioral.ErrSnapshotNotFound)
+	})
+
+	t.Run("Should match a linear undo stack on long interleavings of undo, redo and deposit", func(t *testing.T) {
+		const maxHistory = 8
+		pa := behavioral.NewPaymentAccount(0)
+		pa.SetMaxHistory(maxHistory)
+		// reference model: the balances that can be undone/redone and the current position
+		stack, current := []float64{0}, 0
+
+		rnd := rand.New(rand.NewSource(42))
+		for i := 0; i < 2000; i++ {
+			switch rnd.Intn(3) {
+			case 0:
+				amount := float64(rnd.Intn(100))
+				pa.Deposit(amount)
+				stack = append(stack[:current+1], stack[current]+amount)
+				current++
+				if len(stack) > maxHistory {
+					stack = stack[1:]
+					current--
+				}
+			case 1:
+				mem := pa.Undo()
+				if current == 0 {
+					assert.Nil(t, mem)
+				} else {
+					current--
+					assert.Equal(t, stack[current], mem.Balance())
+				}
+			case 2:
+				mem := pa.Redo()
+				if current == len(stack)-1 {
+					assert.Nil(t, mem)
+				} else {
+					current++
+					assert.Equal(t, stack[current], mem.Balance())
+				}
+			}
+			assert.Equal(t, stack[current], pa.Balance())
+			assert.Equal(t, stack, balances(pa.History()))
+		}
+	})
+}
+
+func balances(history []behavioral.HistoryEntry) []float64 {
+	out := []float64{}
+	for _, e := range history {
+		out = append(out, e.Balance)
+	}
+	return out
 }
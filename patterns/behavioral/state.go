@@ -1,6 +1,14 @@
 package behavioral
 
-import "fmt"
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
 
 // State is a behavioral design pattern that lets an object alter its behavior when its internal state changes. It appears as if the object changed its class.
 // https://refactoring.guru/design-patterns/state
@@ -77,6 +85,7 @@ const (
 	Connected
 	OnHold
 	OnHook
+	Talking
 )
 
 func (s PhoneState) String() string {
@@ -91,6 +100,8 @@ func (s PhoneState) String() string {
 		return "OnHold"
 	case OnHook:
 		return "OnHook"
+	case Talking:
+		return "Talking"
 	}
 	return "Unknown"
 }
@@ -153,40 +164,243 @@ type SystemState int
 
 const (
 	Locked SystemState = iota
-	Failed
+	Failed             // locked out after too many failed attempts
 	Unlocked
 )
 
+func (s SystemState) String() string {
+	switch s {
+	case Locked:
+		return "Locked"
+	case Failed:
+		return "Failed"
+	case Unlocked:
+		return "Unlocked"
+	}
+	return "Unknown"
+}
+
+// LockoutPolicy locks the secret out after MaxAttempts failures within Window.
+// Each consecutive lockout lasts twice as long as the previous one, up to MaxLockout.
+// Fields that are not positive take their value from DefaultLockoutPolicy, so that a partial policy never disables lockouts.
+type LockoutPolicy struct {
+	MaxAttempts int
+	Window      time.Duration
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+	// HashIterations is the work factor of PBKDF2 when hashing the code.
+	// A short PIN has few enough values to be tried one by one, so each try must be expensive for whoever gets hold of the hash.
+	HashIterations int
+}
+
+// DefaultHashIterations of PBKDF2 with HMAC-SHA256, as recommended for passwords
+const DefaultHashIterations = 600_000
+
+// HashKeyLength is the length in bytes of the hash of the code
+const HashKeyLength = sha256.Size
+
+var DefaultLockoutPolicy = LockoutPolicy{
+	MaxAttempts:    3,
+	Window:         time.Minute,
+	BaseLockout:    30 * time.Second,
+	MaxLockout:     time.Hour,
+	HashIterations: DefaultHashIterations,
+}
+
+func (p LockoutPolicy) withDefaults() LockoutPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultLockoutPolicy.MaxAttempts
+	}
+	if p.Window <= 0 {
+		p.Window = DefaultLockoutPolicy.Window
+	}
+	if p.BaseLockout <= 0 {
+		p.BaseLockout = DefaultLockoutPolicy.BaseLockout
+	}
+	if p.MaxLockout <= 0 {
+		p.MaxLockout = DefaultLockoutPolicy.MaxLockout
+	}
+	if p.HashIterations <= 0 {
+		p.HashIterations = DefaultHashIterations
+	}
+	return p
+}
+
+// UnlockAttempt is the audit event of a call to Unlock
+type UnlockAttempt struct {
+	Time        time.Time
+	Success     bool
+	Rejected    bool // the code was not even checked, because the secret was locked out
+	State       SystemState
+	Failures    int // failures within the window, including this attempt
+	LockedUntil time.Time
+}
+
 type SystemSecret struct {
-	code  string
-	state SystemState
+	salt, hash  []byte // the code itself is never stored
+	state       SystemState
+	policy      LockoutPolicy
+	now         func() time.Time
+	failures    []time.Time
+	lockouts    int
+	lockedUntil time.Time
+	auditors    []func(UnlockAttempt)
 }
 
 func NewSystemSecret(code string) *SystemSecret {
-	return &SystemSecret{code: code, state: Locked}
+	return NewSystemSecretWithPolicy(code, DefaultLockoutPolicy, time.Now)
+}
+
+func NewSystemSecretWithPolicy(code string, policy LockoutPolicy, now func() time.Time) *SystemSecret {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err)
+	}
+	s := &SystemSecret{salt: salt, state: Locked, policy: policy.withDefaults(), now: now}
+	s.hash = s.hashCode(code)
+	return s
+}
+
+func (s *SystemSecret) hashCode(code string) []byte {
+	return pbkdf2([]byte(code), s.salt, s.policy.HashIterations, HashKeyLength)
+}
+
+// pbkdf2 is the key derivation of RFC 8018 with HMAC-SHA256 as its pseudorandom function
+func pbkdf2(password, salt []byte, iterations, keyLen int) []byte {
+	mac := hmac.New(sha256.New, password)
+	key := make([]byte, 0, keyLen+mac.Size())
+	block := make([]byte, 4)
+	for i := uint32(1); len(key) < keyLen; i++ {
+		mac.Reset()
+		mac.Write(salt)
+		binary.BigEndian.PutUint32(block, i)
+		mac.Write(block)
+		u := mac.Sum(nil)
+		t := append([]byte{}, u...)
+		for n := 1; n < iterations; n++ {
+			mac.Reset()
+			mac.Write(u)
+			u = mac.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+// OnAttempt registers an auditor notified of every unlock attempt
+func (s *SystemSecret) OnAttempt(auditor func(UnlockAttempt)) {
+	s.auditors = append(s.auditors, auditor)
 }
 
 func (s *SystemSecret) Unlock(code string) bool {
+	now := s.now()
 	switch s.state {
+	case Failed:
+		if now.Before(s.lockedUntil) {
+			s.audit(UnlockAttempt{Time: now, Rejected: true})
+			return false
+		}
+		s.state = Locked
+		fallthrough
 	case Locked:
-		if s.code == code {
+		if s.matches(code) {
 			s.state = Unlocked
+			s.failures = nil
+			s.lockouts = 0
+			s.audit(UnlockAttempt{Time: now, Success: true})
 			return true
 		}
 
-		s.state = Failed
-		return false
-	case Failed:
-		fmt.Println("A previous attempt to unlock was not successful, resetting the state do Locked")
-		s.state = Locked
+		s.audit(s.fail(now))
 		return false
 	case Unlocked:
-		fmt.Println("Unlocked")
-		return true
+		// the secret stays unlocked either way, but a wrong code is not audited as a success
+		success := s.matches(code)
+		s.audit(UnlockAttempt{Time: now, Success: success})
+		return success
 	}
 	return false
 }
 
+// matches compares hashes in constant time, so that timing does not tell how much of the code is right
+func (s *SystemSecret) matches(code string) bool {
+	return subtle.ConstantTimeCompare(s.hash, s.hashCode(code)) == 1
+}
+
+func (s *SystemSecret) fail(now time.Time) UnlockAttempt {
+	recent := s.failures[:0]
+	for _, t := range s.failures {
+		if now.Sub(t) < s.policy.Window {
+			recent = append(recent, t)
+		}
+	}
+	s.failures = append(recent, now)
+
+	attempt := UnlockAttempt{Time: now, Failures: len(s.failures)}
+	if len(s.failures) >= s.policy.MaxAttempts {
+		lockout := s.policy.BaseLockout
+		for i := 0; i < s.lockouts && lockout < s.policy.MaxLockout; i++ {
+			lockout *= 2
+		}
+		if lockout > s.policy.MaxLockout {
+			lockout = s.policy.MaxLockout
+		}
+		s.state = Failed
+		s.lockouts++
+		s.lockedUntil = now.Add(lockout)
+		s.failures = nil
+	}
+	return attempt
+}
+
+func (s *SystemSecret) audit(attempt UnlockAttempt) {
+	attempt.State = s.state
+	if s.state == Failed {
+		attempt.LockedUntil = s.lockedUntil
+	}
+	for _, auditor := range s.auditors {
+		auditor(attempt)
+	}
+}
+
+// Lock locks the secret again after it was unlocked
+func (s *SystemSecret) Lock() {
+	if s.state == Unlocked {
+		s.state = Locked
+	}
+}
+
+// Triggers of the transitions of a SystemSecret, as persisted by PersistentSystemSecret
+const (
+	SecretUnlocked = "unlock"
+	SecretFailed   = "fail"
+	SecretLocked   = "lock"
+)
+
+// replay applies a persisted transition at the time it happened, without checking any code or notifying the auditors,
+// so that failures and lockouts survive a restart as well as the state
+func (s *SystemSecret) replay(trigger string, at time.Time) error {
+	switch trigger {
+	case SecretUnlocked:
+		s.state = Unlocked
+		s.failures = nil
+		s.lockouts = 0
+	case SecretFailed:
+		if s.state == Failed && !at.Before(s.lockedUntil) {
+			s.state = Locked
+		}
+		s.fail(at)
+	case SecretLocked:
+		s.Lock()
+	default:
+		return fmt.Errorf("unknown trigger %q", trigger)
+	}
+	return nil
+}
+
 func (s *SystemSecret) State() SystemState {
 	return s.state
 }
@@ -0,0 +1,520 @@
+package behavioral
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 4: A generic state machine driven by a configuration of permitted transitions, like the Rules table above
+//
+//	m := NewStateMachine[PhoneState, PhoneTrigger](OffHook)
+//	m.Configure(OffHook).Permit(CallDialed, Connecting)
+//	m.Configure(Connecting).OnEntry(startRinging).Permit(CallConnected, Connected)
+//	err := m.Fire(CallDialed)
+
+var ErrInvalidTransition = errors.New("invalid transition")
+
+// Transition describes a change of state. Args are the parameters the trigger was fired with.
+type Transition[S, T comparable] struct {
+	Source      S
+	Destination S
+	Trigger     T
+	Args        []any
+}
+
+func (t Transition[S, T]) IsReentry() bool {
+	return t.Source == t.Destination
+}
+
+type TransitionAction[S, T comparable] func(t Transition[S, T])
+
+// Guard decides whether a transition is permitted, given the arguments of the trigger
+type Guard func(args ...any) bool
+
+// InvalidTransitionError is returned when a trigger is not permitted from the current state.
+// It matches ErrInvalidTransition with errors.Is.
+type InvalidTransitionError[S, T comparable] struct {
+	State       S
+	Trigger     T
+	UnmetGuards []string // descriptions of the guards that blocked the trigger, if any
+}
+
+func (e *InvalidTransitionError[S, T]) Error() string {
+	if len(e.UnmetGuards) > 0 {
+		return fmt.Sprintf("trigger %v is not permitted from state %v: unmet guards: %s", e.Trigger, e.State, strings.Join(e.UnmetGuards, ", "))
+	}
+	return fmt.Sprintf("trigger %v is not valid from state %v", e.Trigger, e.State)
+}
+
+func (e *InvalidTransitionError[S, T]) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+type triggerBehaviour[S comparable] struct {
+	destination      S
+	ignore           bool
+	guard            Guard
+	guardDescription string
+}
+
+func (b *triggerBehaviour[S]) permitted(args []any) bool {
+	return b.guard == nil || b.guard(args...)
+}
+
+type StateMachine[S, T comparable] struct {
+	initial      S
+	state        S
+	states       map[S]*StateConfig[S, T]
+	order        []S // states in configuration order
+	onTransition []TransitionAction[S, T]
+
+	firing bool
+	queue  []queuedTrigger[T]
+}
+
+type queuedTrigger[T comparable] struct {
+	trigger T
+	args    []any
+}
+
+func NewStateMachine[S, T comparable](initial S) *StateMachine[S, T] {
+	return &StateMachine[S, T]{initial: initial, state: initial, states: map[S]*StateConfig[S, T]{}}
+}
+
+// State is the innermost active state
+func (m *StateMachine[S, T]) State() S {
+	return m.state
+}
+
+// IsInState tells whether the state is active, either as the current state or as one of its superstates
+func (m *StateMachine[S, T]) IsInState(state S) bool {
+	for _, s := range m.ancestors(m.state) {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// ancestors returns the state followed by its superstates, innermost first
+func (m *StateMachine[S, T]) ancestors(state S) []S {
+	path := []S{state}
+	for c := m.states[state]; c != nil && c.parent != nil; c = c.parent {
+		path = append(path, c.parent.state)
+	}
+	return path
+}
+
+// Configure returns the configuration of a state, creating it on first use
+func (m *StateMachine[S, T]) Configure(state S) *StateConfig[S, T] {
+	c, ok := m.states[state]
+	if !ok {
+		c = &StateConfig[S, T]{
+			machine:   m,
+			state:     state,
+			behaviour: map[T][]*triggerBehaviour[S]{},
+		}
+		m.states[state] = c
+		m.order = append(m.order, state)
+	}
+	return c
+}
+
+// OnTransition registers an action run after every transition, once the destination state has been entered
+func (m *StateMachine[S, T]) OnTransition(action TransitionAction[S, T]) {
+	m.onTransition = append(m.onTransition, action)
+}
+
+// Fire moves the machine along the first permitted transition for the trigger.
+// Triggers fired from within actions are queued and run after the current transition completes.
+func (m *StateMachine[S, T]) Fire(trigger T, args ...any) error {
+	if m.firing {
+		m.queue = append(m.queue, queuedTrigger[T]{trigger, args})
+		return nil
+	}
+
+	m.firing = true
+	defer func() { m.firing = false }()
+	if err := m.fire(trigger, args); err != nil {
+		m.queue = nil
+		return err
+	}
+	for len(m.queue) > 0 {
+		next := m.queue[0]
+		m.queue = m.queue[1:]
+		if err := m.fire(next.trigger, next.args); err != nil {
+			m.queue = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *StateMachine[S, T]) fire(trigger T, args []any) error {
+	b, err := m.behaviourFor(trigger, args)
+	if err != nil {
+		return err
+	}
+	if b.ignore {
+		return nil
+	}
+
+	t := Transition[S, T]{Source: m.state, Destination: b.destination, Trigger: trigger, Args: args}
+	exits, entries := m.transitionPath(t.Source, b.destination)
+	m.recordHistory(t.Source, exits)
+	entries = append(entries, m.descend(b.destination)...)
+	t.Destination = entries[len(entries)-1]
+
+	for _, state := range exits {
+		if c, ok := m.states[state]; ok {
+			c.exit(t)
+		}
+	}
+	m.state = t.Destination
+	for _, state := range entries {
+		if c, ok := m.states[state]; ok {
+			c.enter(t)
+		}
+	}
+	for _, action := range m.onTransition {
+		action(t)
+	}
+	return nil
+}
+
+// transitionPath lists the states exited, innermost first, and the states entered, outermost first, to go from source to destination.
+// Only the states below their closest common superstate are exited and entered, and the destination is always entered again.
+func (m *StateMachine[S, T]) transitionPath(source, destination S) (exits, entries []S) {
+	active := map[S]bool{}
+	for _, s := range m.ancestors(source) {
+		active[s] = true
+	}
+
+	targets := m.ancestors(destination)
+	common := len(targets)
+	for i := 1; i < len(targets); i++ {
+		if active[targets[i]] {
+			common = i
+			break
+		}
+	}
+	for i := common - 1; i >= 0; i-- {
+		entries = append(entries, targets[i])
+	}
+	for _, s := range m.ancestors(source) {
+		if common < len(targets) && s == targets[common] {
+			break
+		}
+		exits = append(exits, s)
+	}
+	return exits, entries
+}
+
+// recordHistory remembers the active substates of the states about to be exited
+func (m *StateMachine[S, T]) recordHistory(leaf S, exits []S) {
+	for _, s := range exits {
+		if c, ok := m.states[s]; ok && c.parent != nil {
+			c.parent.lastChild, c.parent.lastLeaf, c.parent.visited = s, leaf, true
+		}
+	}
+}
+
+// descend follows initial transitions and history into the substates of a state, returning the states entered on the way
+func (m *StateMachine[S, T]) descend(state S) []S {
+	path := []S{}
+	for {
+		c, ok := m.states[state]
+		if !ok {
+			return path
+		}
+		switch {
+		case c.history == DeepHistory && c.visited:
+			leafPath := m.ancestors(c.lastLeaf)
+			for k, s := range leafPath {
+				if s == state {
+					for i := k - 1; i >= 0; i-- {
+						path = append(path, leafPath[i])
+					}
+					break
+				}
+			}
+			return path
+		case c.history == ShallowHistory && c.visited:
+			state = c.lastChild
+		case c.hasInitial:
+			state = c.initial
+		default:
+			return path
+		}
+		path = append(path, state)
+	}
+}
+
+// behaviourFor looks for the trigger in the current state and then in its superstates
+func (m *StateMachine[S, T]) behaviourFor(trigger T, args []any) (*triggerBehaviour[S], error) {
+	unmet := []string{}
+	for _, s := range m.ancestors(m.state) {
+		c, ok := m.states[s]
+		if !ok {
+			continue
+		}
+		for _, b := range c.behaviour[trigger] {
+			if b.permitted(args) {
+				return b, nil
+			}
+			unmet = append(unmet, b.guardDescription)
+		}
+	}
+	return nil, &InvalidTransitionError[S, T]{State: m.state, Trigger: trigger, UnmetGuards: unmet}
+}
+
+func (m *StateMachine[S, T]) CanFire(trigger T, args ...any) bool {
+	_, err := m.behaviourFor(trigger, args)
+	return err == nil
+}
+
+// PermittedTriggers lists the triggers that can be fired from the current state with the given arguments, in configuration order.
+// Triggers of the current state come first, followed by the ones inherited from its superstates.
+func (m *StateMachine[S, T]) PermittedTriggers(args ...any) []T {
+	triggers := []T{}
+	seen := map[T]bool{}
+	for _, s := range m.ancestors(m.state) {
+		c, ok := m.states[s]
+		if !ok {
+			continue
+		}
+		for _, trigger := range c.triggers {
+			if !seen[trigger] && m.CanFire(trigger, args...) {
+				triggers = append(triggers, trigger)
+			}
+			seen[trigger] = true
+		}
+	}
+	return triggers
+}
+
+// StateConfig is the fluent configuration of a single state
+type StateConfig[S, T comparable] struct {
+	machine   *StateMachine[S, T]
+	state     S
+	behaviour map[T][]*triggerBehaviour[S]
+	triggers  []T // in configuration order
+	entry     []entryAction[S, T]
+	exits     []TransitionAction[S, T]
+
+	parent     *StateConfig[S, T]
+	initial    S
+	hasInitial bool
+	history    HistoryKind
+	visited    bool // whether lastChild and lastLeaf were recorded
+	lastChild  S
+	lastLeaf   S
+}
+
+type HistoryKind int
+
+const (
+	NoHistory      HistoryKind = iota
+	ShallowHistory             // entering the state resumes the substate that was active when it was last exited
+	DeepHistory                // entering the state resumes the innermost state that was active when it was last exited
+)
+
+type entryAction[S, T comparable] struct {
+	action  TransitionAction[S, T]
+	from    T
+	anyFrom bool
+}
+
+func (c *StateConfig[S, T]) State() S {
+	return c.state
+}
+
+// Configure continues the configuration with another state
+func (c *StateConfig[S, T]) Configure(state S) *StateConfig[S, T] {
+	return c.machine.Configure(state)
+}
+
+// SubstateOf nests the state in a superstate: triggers not handled by the state are handled by its superstates.
+// It panics if the state would become its own superstate, directly or not.
+func (c *StateConfig[S, T]) SubstateOf(parent S) *StateConfig[S, T] {
+	p := c.machine.Configure(parent)
+	for s := p; s != nil; s = s.parent {
+		if s == c {
+			panic(fmt.Sprintf("behavioral: SubstateOf(%v) makes state %v a superstate of itself", parent, c.state))
+		}
+	}
+	c.parent = p
+	return c
+}
+
+// InitialTransition is the substate entered when a transition targets this state
+func (c *StateConfig[S, T]) InitialTransition(substate S) *StateConfig[S, T] {
+	c.initial, c.hasInitial = substate, true
+	return c
+}
+
+// History makes the state resume its last active substate when entered again, instead of the initial one
+func (c *StateConfig[S, T]) History(kind HistoryKind) *StateConfig[S, T] {
+	c.history = kind
+	return c
+}
+
+func (c *StateConfig[S, T]) add(trigger T, b *triggerBehaviour[S]) *StateConfig[S, T] {
+	if _, ok := c.behaviour[trigger]; !ok {
+		c.triggers = append(c.triggers, trigger)
+	}
+	c.behaviour[trigger] = append(c.behaviour[trigger], b)
+	return c
+}
+
+func (c *StateConfig[S, T]) Permit(trigger T, destination S) *StateConfig[S, T] {
+	return c.add(trigger, &triggerBehaviour[S]{destination: destination})
+}
+
+// PermitIf permits the transition only when the guard is met. The description is reported when it is not.
+func (c *StateConfig[S, T]) PermitIf(trigger T, destination S, guard Guard, description string) *StateConfig[S, T] {
+	return c.add(trigger, &triggerBehaviour[S]{destination: destination, guard: guard, guardDescription: description})
+}
+
+// PermitReentry exits and enters the state again, running its exit and entry actions
+func (c *StateConfig[S, T]) PermitReentry(trigger T) *StateConfig[S, T] {
+	return c.Permit(trigger, c.state)
+}
+
+// Ignore accepts the trigger without changing state nor running any action
+func (c *StateConfig[S, T]) Ignore(trigger T) *StateConfig[S, T] {
+	return c.add(trigger, &triggerBehaviour[S]{destination: c.state, ignore: true})
+}
+
+func (c *StateConfig[S, T]) IgnoreIf(trigger T, guard Guard, description string) *StateConfig[S, T] {
+	return c.add(trigger, &triggerBehaviour[S]{destination: c.state, ignore: true, guard: guard, guardDescription: description})
+}
+
+func (c *StateConfig[S, T]) OnEntry(action TransitionAction[S, T]) *StateConfig[S, T] {
+	c.entry = append(c.entry, entryAction[S, T]{action: action, anyFrom: true})
+	return c
+}
+
+// OnEntryFrom runs the action only when the state is entered by the given trigger
+func (c *StateConfig[S, T]) OnEntryFrom(trigger T, action TransitionAction[S, T]) *StateConfig[S, T] {
+	c.entry = append(c.entry, entryAction[S, T]{action: action, from: trigger})
+	return c
+}
+
+func (c *StateConfig[S, T]) OnExit(action TransitionAction[S, T]) *StateConfig[S, T] {
+	c.exits = append(c.exits, action)
+	return c
+}
+
+func (c *StateConfig[S, T]) enter(t Transition[S, T]) {
+	for _, e := range c.entry {
+		if e.anyFrom || e.from == t.Trigger {
+			e.action(t)
+		}
+	}
+}
+
+func (c *StateConfig[S, T]) exit(t Transition[S, T]) {
+	for _, action := range c.exits {
+		action(t)
+	}
+}
+
+// The phone example of the Rules table, ported to the generic state machine
+
+func NewPhoneStateMachine() *StateMachine[PhoneState, PhoneTrigger] {
+	return newPhoneStateMachine(nil)
+}
+
+// newPhoneStateMachine guards every transition of the Rules table with the guard of its trigger, if any
+func newPhoneStateMachine(guards map[PhoneTrigger]phoneGuard) *StateMachine[PhoneState, PhoneTrigger] {
+	m := NewStateMachine[PhoneState, PhoneTrigger](OffHook)
+	states := make([]PhoneState, 0, len(Rules))
+	for state := range Rules {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i] < states[j] })
+	for _, state := range states {
+		c := m.Configure(state)
+		for _, r := range Rules[state] {
+			if g, ok := guards[r.PhoneTrigger]; ok {
+				c.PermitIf(r.PhoneTrigger, r.PhoneState, g.guard, g.description)
+			} else {
+				c.Permit(r.PhoneTrigger, r.PhoneState)
+			}
+		}
+	}
+	return m
+}
+
+// NewHierarchicalPhoneStateMachine nests Talking and OnHold in Connected, so that hanging up and leaving a message are handled once by Connected.
+func NewHierarchicalPhoneStateMachine() *StateMachine[PhoneState, PhoneTrigger] {
+	m := NewStateMachine[PhoneState, PhoneTrigger](OffHook)
+	m.Configure(OffHook).
+		Permit(CallDialed, Connecting)
+	m.Configure(Connecting).
+		Permit(HungUp, OnHook).
+		Permit(CallConnected, Connected)
+	m.Configure(Connected).
+		InitialTransition(Talking).
+		Permit(LeftMessage, OnHook).
+		Permit(HungUp, OnHook)
+	m.Configure(Talking).
+		SubstateOf(Connected).
+		Permit(PlacedOnHold, OnHold)
+	m.Configure(OnHold).
+		SubstateOf(Connected).
+		Permit(TakenOffHold, Talking)
+	return m
+}
+
+// PhoneCall adds behaviour to the states of the phone: dialing takes the number as a trigger parameter
+type PhoneCall struct {
+	*StateMachine[PhoneState, PhoneTrigger]
+	Number string
+	Log    []string
+}
+
+type phoneGuard struct {
+	guard       Guard
+	description string
+}
+
+// hasNumber only lets a call be dialed with the number to call
+func hasNumber(args ...any) bool {
+	if len(args) != 1 {
+		return false
+	}
+	number, ok := args[0].(string)
+	return ok && number != ""
+}
+
+func NewPhoneCall() *PhoneCall {
+	c := &PhoneCall{StateMachine: newPhoneStateMachine(map[PhoneTrigger]phoneGuard{
+		CallDialed: {hasNumber, "a number to dial"},
+	})}
+	c.Configure(Connecting).
+		OnEntryFrom(CallDialed, func(t Transition[PhoneState, PhoneTrigger]) {
+			c.Number = t.Args[0].(string)
+			c.log("Calling %s", c.Number)
+		})
+	c.Configure(Connected).
+		OnEntry(func(t Transition[PhoneState, PhoneTrigger]) { c.log("Connected to %s", c.Number) }).
+		OnExit(func(t Transition[PhoneState, PhoneTrigger]) { c.log("%v while talking to %s", t.Trigger, c.Number) })
+	c.Configure(OnHold).
+		Ignore(PlacedOnHold).
+		OnEntry(func(t Transition[PhoneState, PhoneTrigger]) { c.log("On hold") })
+	c.Configure(OnHook).
+		OnEntry(func(t Transition[PhoneState, PhoneTrigger]) { c.log("Hung up") })
+	return c
+}
+
+func (c *PhoneCall) Dial(number string) error {
+	return c.Fire(CallDialed, number)
+}
+
+func (c *PhoneCall) log(format string, args ...any) {
+	c.Log = append(c.Log, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,260 @@
+package behavioral
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// StateGraph is the static structure of a state machine, used to draw it and to check it for mistakes.
+// States are kept in the order they were configured, so the output is stable and can be reviewed in diffs.
+type StateGraph[S, T comparable] struct {
+	Initial  S
+	States   []S
+	Parents  map[S]S // superstate of each substate
+	Initials map[S]S // initial substate of each superstate
+	Edges    []StateGraphEdge[S, T]
+}
+
+type StateGraphEdge[S, T comparable] struct {
+	Source      S
+	Destination S
+	Trigger     T
+	Guard       string // description of the guard, empty when the transition is unconditional
+	Ignored     bool
+}
+
+func (e StateGraphEdge[S, T]) Label() string {
+	label := fmt.Sprint(e.Trigger)
+	if e.Guard != "" {
+		label += " [" + e.Guard + "]"
+	}
+	if e.Ignored {
+		label += " (ignored)"
+	}
+	return label
+}
+
+// StateGraphReport lists the states that can never be entered and the states that can never be left
+type StateGraphReport[S comparable] struct {
+	Unreachable []S
+	DeadEnds    []S
+}
+
+func (m *StateMachine[S, T]) Graph() *StateGraph[S, T] {
+	g := &StateGraph[S, T]{
+		Initial:  m.initial,
+		Parents:  map[S]S{},
+		Initials: map[S]S{},
+	}
+	seen := map[S]bool{}
+	addState := func(s S) {
+		if !seen[s] {
+			seen[s] = true
+			g.States = append(g.States, s)
+		}
+	}
+
+	addState(m.initial)
+	for _, s := range m.order {
+		addState(s)
+	}
+	for _, s := range m.order {
+		c := m.states[s]
+		if c.parent != nil {
+			g.Parents[s] = c.parent.state
+		}
+		if c.hasInitial {
+			g.Initials[s] = c.initial
+			addState(c.initial)
+		}
+		for _, trigger := range c.triggers {
+			for _, b := range c.behaviour[trigger] {
+				g.Edges = append(g.Edges, StateGraphEdge[S, T]{
+					Source:      s,
+					Destination: b.destination,
+					Trigger:     trigger,
+					Guard:       b.guardDescription,
+					Ignored:     b.ignore,
+				})
+				addState(b.destination)
+			}
+		}
+	}
+	return g
+}
+
+func (g *StateGraph[S, T]) children(parent S) []S {
+	children := []S{}
+	for _, s := range g.States {
+		if p, ok := g.Parents[s]; ok && p == parent {
+			children = append(children, s)
+		}
+	}
+	return children
+}
+
+// Analyze walks the graph from the initial state. A state inherits the transitions of its superstates,
+// and entering a superstate also enters its initial substate.
+func (g *StateGraph[S, T]) Analyze() StateGraphReport[S] {
+	outgoing := map[S][]StateGraphEdge[S, T]{}
+	for _, e := range g.Edges {
+		if !e.Ignored {
+			outgoing[e.Source] = append(outgoing[e.Source], e)
+		}
+	}
+
+	reached := map[S]bool{}
+	var reach func(s S)
+	reach = func(s S) {
+		if reached[s] {
+			return
+		}
+		reached[s] = true
+		if p, ok := g.Parents[s]; ok {
+			reach(p)
+		}
+		if initial, ok := g.Initials[s]; ok {
+			reach(initial)
+		}
+		for active := s; ; {
+			for _, e := range outgoing[active] {
+				reach(e.Destination)
+			}
+			p, ok := g.Parents[active]
+			if !ok {
+				break
+			}
+			active = p
+		}
+	}
+	reach(g.Initial)
+
+	report := StateGraphReport[S]{Unreachable: []S{}, DeadEnds: []S{}}
+	for _, s := range g.States {
+		if !reached[s] {
+			report.Unreachable = append(report.Unreachable, s)
+		}
+		if len(g.children(s)) > 0 {
+			continue // only the innermost states can be stuck in
+		}
+		deadEnd := true
+		for active, ok := s, true; ok; active, ok = g.Parents[active] {
+			if len(outgoing[active]) > 0 {
+				deadEnd = false
+				break
+			}
+		}
+		if deadEnd {
+			report.DeadEnds = append(report.DeadEnds, s)
+		}
+	}
+	return report
+}
+
+// DOT renders the graph for Graphviz: superstates are drawn as clusters around their substates
+func (g *StateGraph[S, T]) DOT() string {
+	sb := strings.Builder{}
+	sb.WriteString("digraph {\n")
+	sb.WriteString("\tcompound=true;\n")
+	sb.WriteString("\tnode [shape=box, style=rounded];\n")
+	sb.WriteString("\t\"__start\" [shape=point];\n")
+	sb.WriteString(fmt.Sprintf("\t\"__start\" -> %q;\n", g.dotNode(g.Initial)))
+
+	var writeState func(s S, indent string)
+	writeState = func(s S, indent string) {
+		children := g.children(s)
+		if len(children) == 0 {
+			sb.WriteString(fmt.Sprintf("%s%q;\n", indent, fmt.Sprint(s)))
+			return
+		}
+		sb.WriteString(fmt.Sprintf("%ssubgraph %q {\n", indent, "cluster_"+fmt.Sprint(s)))
+		sb.WriteString(fmt.Sprintf("%s\tlabel=%q;\n", indent, fmt.Sprint(s)))
+		for _, c := range children {
+			writeState(c, indent+"\t")
+		}
+		sb.WriteString(indent + "}\n")
+	}
+	for _, s := range g.States {
+		if _, ok := g.Parents[s]; !ok {
+			writeState(s, "\t")
+		}
+	}
+
+	for _, e := range g.Edges {
+		attrs := []string{fmt.Sprintf("label=%q", e.Label())}
+		if len(g.children(e.Source)) > 0 {
+			attrs = append(attrs, fmt.Sprintf("ltail=%q", "cluster_"+fmt.Sprint(e.Source)))
+		}
+		if len(g.children(e.Destination)) > 0 {
+			attrs = append(attrs, fmt.Sprintf("lhead=%q", "cluster_"+fmt.Sprint(e.Destination)))
+		}
+		if e.Ignored {
+			attrs = append(attrs, "style=dashed")
+		}
+		sb.WriteString(fmt.Sprintf("\t%q -> %q [%s];\n", g.dotNode(e.Source), g.dotNode(e.Destination), strings.Join(attrs, ", ")))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// dotNode is the node that stands for a state: superstates are clusters, which are reached through their initial (or first) substate
+func (g *StateGraph[S, T]) dotNode(s S) string {
+	for {
+		children := g.children(s)
+		if len(children) == 0 {
+			return fmt.Sprint(s)
+		}
+		if initial, ok := g.Initials[s]; ok {
+			s = initial
+		} else {
+			s = children[0]
+		}
+	}
+}
+
+// Mermaid renders the graph as a Mermaid state diagram
+func (g *StateGraph[S, T]) Mermaid() string {
+	sb := strings.Builder{}
+	sb.WriteString("stateDiagram-v2\n")
+	sb.WriteString(fmt.Sprintf("    [*] --> %s\n", mermaidID(g.Initial)))
+
+	var writeState func(s S, indent string)
+	writeState = func(s S, indent string) {
+		children := g.children(s)
+		if len(children) == 0 {
+			return
+		}
+		sb.WriteString(fmt.Sprintf("%sstate %s {\n", indent, mermaidID(s)))
+		if initial, ok := g.Initials[s]; ok {
+			sb.WriteString(fmt.Sprintf("%s    [*] --> %s\n", indent, mermaidID(initial)))
+		}
+		for _, c := range children {
+			if len(g.children(c)) > 0 {
+				writeState(c, indent+"    ")
+			} else {
+				sb.WriteString(fmt.Sprintf("%s    %s\n", indent, mermaidID(c)))
+			}
+		}
+		sb.WriteString(indent + "}\n")
+	}
+	for _, s := range g.States {
+		if _, ok := g.Parents[s]; !ok {
+			writeState(s, "    ")
+		}
+	}
+
+	for _, e := range g.Edges {
+		sb.WriteString(fmt.Sprintf("    %s --> %s : %s\n", mermaidID(e.Source), mermaidID(e.Destination), e.Label()))
+	}
+	return sb.String()
+}
+
+func mermaidID(s any) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, fmt.Sprint(s))
+}
@@ -0,0 +1,134 @@
+package behavioral_test
+
+import (
+	"testing"
+
+	"github.com/fabricioandreis/design-patterns-go/patterns/behavioral"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateMachineGraph(t *testing.T) {
+	t.Run("Should export the phone rules as a DOT diagram", func(t *testing.T) {
+		g := behavioral.NewPhoneStateMachine().Graph()
+
+		assert.Equal(t, `digraph {
+	compound=true;
+	node [shape=box, style=rounded];
+	"__start" [shape=point];
+	"__start" -> "OffHook";
+	"OffHook";
+	"Connecting";
+	"Connected";
+	"OnHold";
+	"OnHook";
+	"OffHook" -> "Connecting" [label="CallDialed"];
+	"Connecting" -> "OnHook" [label="HungUp"];
+	"Connecting" -> "Connected" [label="CallConnected"];
+	"Connected" -> "OnHook" [label="LeftMessage"];
+	"Connected" -> "OnHook" [label="HungUp"];
+	"Connected" -> "OnHold" [label="PlacedOnHold"];
+	"OnHold" -> "Connected" [label="TakenOffHold"];
+	"OnHold" -> "OnHook" [label="HungUp"];
+}
+`, g.DOT())
+	})
+
+	t.Run("Should export the phone rules as a Mermaid diagram", func(t *testing.T) {
+		g := behavioral.NewPhoneStateMachine().Graph()
+
+		assert.Equal(t, `stateDiagram-v2
+    [*] --> OffHook
+    OffHook --> Connecting : CallDialed
+    Connecting --> OnHook : HungUp
+    Connecting --> Connected : CallConnected
+    Connected --> OnHook : LeftMessage
+    Connected --> OnHook : HungUp
+    Connected --> OnHold : PlacedOnHold
+    OnHold --> Connected : TakenOffHold
+    OnHold --> OnHook : HungUp
+`, g.Mermaid())
+	})
+
+	t.Run("Should draw superstates around their substates", func(t *testing.T) {
+		g := behavioral.NewHierarchicalPhoneStateMachine().Graph()
+
+		assert.Equal(t, `stateDiagram-v2
+    [*] --> OffHook
+    state Connected {
+        [*] --> Talking
+        Talking
+        OnHold
+    }
+    OffHook --> Connecting : CallDialed
+    Connecting --> OnHook : HungUp
+    Connecting --> Connected : CallConnected
+    Connected --> OnHook : LeftMessage
+    Connected --> OnHook : HungUp
+    Talking --> OnHold : PlacedOnHold
+    OnHold --> Talking : TakenOffHold
+`, g.Mermaid())
+		assert.Contains(t, g.DOT(), "subgraph \"cluster_Connected\" {\n\t\tlabel=\"Connected\";\n\t\t\"Talking\";\n\t\t\"OnHold\";\n\t}")
+		assert.Contains(t, g.DOT(), `"Connecting" -> "Talking" [label="CallConnected", lhead="cluster_Connected"];`)
+		assert.Contains(t, g.DOT(), `"Talking" -> "OnHook" [label="HungUp", ltail="cluster_Connected"];`)
+	})
+
+	t.Run("Should report that nothing leaves OnHook", func(t *testing.T) {
+		for _, m := range []*behavioral.StateMachine[behavioral.PhoneState, behavioral.PhoneTrigger]{
+			behavioral.NewPhoneStateMachine(),
+			behavioral.NewHierarchicalPhoneStateMachine(),
+		} {
+			report := m.Graph().Analyze()
+
+			assert.Empty(t, report.Unreachable)
+			assert.Equal(t, []behavioral.PhoneState{behavioral.OnHook}, report.DeadEnds)
+		}
+	})
+
+	t.Run("Should start the graph from the initial state after the machine has moved on", func(t *testing.T) {
+		m := behavioral.NewPhoneStateMachine()
+		assert.NoError(t, m.Fire(behavioral.CallDialed))
+		assert.NoError(t, m.Fire(behavioral.CallConnected))
+
+		g := m.Graph()
+
+		assert.Equal(t, behavioral.OffHook, g.Initial)
+		assert.Equal(t, behavioral.OffHook, g.States[0])
+		assert.Empty(t, g.Analyze().Unreachable)
+		assert.Contains(t, g.Mermaid(), "[*] --> OffHook\n")
+	})
+
+	t.Run("Should label guards and ignored triggers and find unreachable states", func(t *testing.T) {
+		m := behavioral.NewStateMachine[string, string]("Draft")
+		m.Configure("Draft").
+			PermitIf("submit", "Review", func(args ...any) bool { return true }, "has reviewers").
+			Ignore("save")
+		m.Configure("Review").
+			Permit("approve", "Published").
+			Permit("reject", "Draft")
+		m.Configure("Archived").
+			Permit("restore", "Draft")
+
+		g := m.Graph()
+		report := g.Analyze()
+
+		assert.Contains(t, g.Mermaid(), "Draft --> Review : submit [has reviewers]\n")
+		assert.Contains(t, g.Mermaid(), "Draft --> Draft : save (ignored)\n")
+		assert.Contains(t, g.DOT(), `"Draft" -> "Draft" [label="save (ignored)", style=dashed];`)
+		assert.Equal(t, []string{"Archived"}, report.Unreachable)
+		assert.Equal(t, []string{"Published"}, report.DeadEnds)
+	})
+
+	t.Run("Should reach substates through initial transitions and inherited triggers", func(t *testing.T) {
+		m := behavioral.NewStateMachine[string, string]("Idle")
+		m.Configure("Idle").Permit("start", "Running")
+		m.Configure("Running").InitialTransition("Warmup").Permit("stop", "Idle")
+		m.Configure("Warmup").SubstateOf("Running").Permit("ready", "Steady")
+		m.Configure("Steady").SubstateOf("Running")
+		m.Configure("Cooldown").SubstateOf("Running")
+
+		report := m.Graph().Analyze()
+
+		assert.Equal(t, []string{"Cooldown"}, report.Unreachable)
+		assert.Empty(t, report.DeadEnds) // Steady and Cooldown inherit stop from Running
+	})
+}
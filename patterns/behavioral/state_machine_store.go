@@ -0,0 +1,441 @@
+package behavioral
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Persistent state machines: every transition is appended to a log kept by a store,
+// so that a machine can be resumed after a restart, either by jumping to its last state or by replaying the log.
+
+var (
+	ErrSequenceConflict = errors.New("transition log was modified concurrently")
+	ErrReplayDiverged   = errors.New("replayed transition does not match the log")
+)
+
+// TransitionRecord is an entry of the transition log. Args must survive a round trip through the store:
+// the file store encodes them as JSON, so numbers come back as float64.
+type TransitionRecord[S, T comparable] struct {
+	Sequence    uint64    `json:"sequence"` // starts at 1
+	Source      S         `json:"source"`
+	Destination S         `json:"destination"`
+	Trigger     T         `json:"trigger"`
+	Args        []any     `json:"args,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+type StateMachineStore[S, T comparable] interface {
+	// Load returns the log of a machine, empty if the machine is unknown
+	Load(id string) ([]TransitionRecord[S, T], error)
+	// Append adds a record to the log, failing with ErrSequenceConflict unless it directly follows the last one
+	Append(id string, r TransitionRecord[S, T]) error
+}
+
+// 1. In-memory store
+type MemoryStateMachineStore[S, T comparable] struct {
+	mu   sync.Mutex
+	logs map[string][]TransitionRecord[S, T]
+}
+
+func NewMemoryStateMachineStore[S, T comparable]() *MemoryStateMachineStore[S, T] {
+	return &MemoryStateMachineStore[S, T]{logs: map[string][]TransitionRecord[S, T]{}}
+}
+
+func (s *MemoryStateMachineStore[S, T]) Load(id string) ([]TransitionRecord[S, T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]TransitionRecord[S, T]{}, s.logs[id]...), nil
+}
+
+func (s *MemoryStateMachineStore[S, T]) Append(id string, r TransitionRecord[S, T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Sequence != uint64(len(s.logs[id]))+1 {
+		return fmt.Errorf("%w: %s at %d", ErrSequenceConflict, id, r.Sequence)
+	}
+	s.logs[id] = append(s.logs[id], r)
+	return nil
+}
+
+// 2. JSON file store: one append-only file per machine, with one JSON record per line.
+// The store expects to be the only writer of its directory: it remembers the length of each log after reading it once.
+// A record is only acknowledged once its line is synced to disk, and a line torn by a crash while it was written
+// is dropped when the log is read again.
+type FileStateMachineStore[S, T comparable] struct {
+	mu        sync.Mutex
+	dir       string
+	sequences map[string]uint64 // last sequence of the logs read so far
+}
+
+func NewFileStateMachineStore[S, T comparable](dir string) (*FileStateMachineStore[S, T], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStateMachineStore[S, T]{dir: dir, sequences: map[string]uint64{}}, nil
+}
+
+// path escapes the ID, so that every ID has a file of its own inside the directory
+func (s *FileStateMachineStore[S, T]) path(id string) string {
+	return filepath.Join(s.dir, url.PathEscape(id)+".jsonl")
+}
+
+func (s *FileStateMachineStore[S, T]) Load(id string) ([]TransitionRecord[S, T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(id)
+}
+
+func (s *FileStateMachineStore[S, T]) load(id string) ([]TransitionRecord[S, T], error) {
+	log := []TransitionRecord[S, T]{}
+	file, err := os.OpenFile(s.path(id), os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return log, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var size int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				// The last write never completed: drop it, so that the next record takes its place
+				if err := file.Truncate(size); err != nil {
+					return nil, err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var r TransitionRecord[S, T]
+		if err := json.Unmarshal(line, &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", s.path(id), len(log)+1, err)
+		}
+		log = append(log, r)
+		size += int64(len(line))
+	}
+	s.sequences[id] = uint64(len(log))
+	return log, nil
+}
+
+func (s *FileStateMachineStore[S, T]) Append(id string, r TransitionRecord[S, T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last, ok := s.sequences[id]
+	if !ok {
+		log, err := s.load(id)
+		if err != nil {
+			return err
+		}
+		last = uint64(len(log))
+	}
+	if r.Sequence != last+1 {
+		return fmt.Errorf("%w: %s at %d", ErrSequenceConflict, id, r.Sequence)
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := s.write(id, append(line, '\n')); err != nil {
+		// The line may be partly written: read the log again next time
+		delete(s.sequences, id)
+		return err
+	}
+	s.sequences[id] = r.Sequence
+	return nil
+}
+
+func (s *FileStateMachineStore[S, T]) write(id string, line []byte) error {
+	file, err := os.OpenFile(s.path(id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// PersistentStateMachine appends every transition of the wrapped machine to the store
+type PersistentStateMachine[S, T comparable] struct {
+	*StateMachine[S, T]
+	id       string
+	store    StateMachineStore[S, T]
+	sequence uint64
+	now      func() time.Time
+
+	recording bool
+	err       error
+}
+
+// ResumeStateMachine moves the machine along the states of its log, without running any action,
+// so that superstates remember their history as they did before the restart.
+// The machine must be configured and still in its initial state.
+func ResumeStateMachine[S, T comparable](id string, m *StateMachine[S, T], store StateMachineStore[S, T]) (*PersistentStateMachine[S, T], error) {
+	log, err := store.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	p := newPersistentStateMachine(id, m, store)
+	for _, r := range log {
+		if r.Source != m.state {
+			return nil, fmt.Errorf("%w: %s at %d", ErrReplayDiverged, id, r.Sequence)
+		}
+		exits, _ := m.transitionPath(r.Source, r.Destination)
+		m.recordHistory(r.Source, exits)
+		m.state = r.Destination
+		p.sequence = r.Sequence
+	}
+	p.recording = true
+	return p, nil
+}
+
+// ReplayStateMachine fires every trigger of the log again, from the initial state, so that actions rebuild whatever they keep besides the state.
+// Triggers fired by actions are part of the log too: they are checked rather than fired twice.
+func ReplayStateMachine[S, T comparable](id string, m *StateMachine[S, T], store StateMachineStore[S, T]) (*PersistentStateMachine[S, T], error) {
+	log, err := store.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	p := newPersistentStateMachine(id, m, store)
+
+	replayed := []Transition[S, T]{}
+	m.OnTransition(func(t Transition[S, T]) {
+		if !p.recording {
+			replayed = append(replayed, t)
+		}
+	})
+	for i := 0; i < len(log); {
+		replayed = replayed[:0]
+		if err := m.Fire(log[i].Trigger, log[i].Args...); err != nil {
+			return nil, fmt.Errorf("replaying %s at %d: %w", id, log[i].Sequence, err)
+		}
+		for _, t := range replayed {
+			if i >= len(log) || t.Trigger != log[i].Trigger || t.Destination != log[i].Destination {
+				return nil, fmt.Errorf("%w: %s at %d", ErrReplayDiverged, id, i+1)
+			}
+			p.sequence = log[i].Sequence
+			i++
+		}
+		if len(replayed) == 0 {
+			return nil, fmt.Errorf("%w: %s at %d", ErrReplayDiverged, id, log[i].Sequence)
+		}
+	}
+	p.recording = true
+	return p, nil
+}
+
+func newPersistentStateMachine[S, T comparable](id string, m *StateMachine[S, T], store StateMachineStore[S, T]) *PersistentStateMachine[S, T] {
+	p := &PersistentStateMachine[S, T]{StateMachine: m, id: id, store: store, now: time.Now}
+	m.OnTransition(p.record)
+	return p
+}
+
+func (p *PersistentStateMachine[S, T]) record(t Transition[S, T]) {
+	if !p.recording || p.err != nil {
+		return
+	}
+	r := TransitionRecord[S, T]{
+		Sequence:    p.sequence + 1,
+		Source:      t.Source,
+		Destination: t.Destination,
+		Trigger:     t.Trigger,
+		Args:        t.Args,
+		Time:        p.now(),
+	}
+	if err := p.store.Append(p.id, r); err != nil {
+		p.err = err
+		return
+	}
+	p.sequence = r.Sequence
+}
+
+// Fire fires the trigger and persists the resulting transitions.
+// When the store fails the machine has already moved: the error tells that the log is behind the machine.
+func (p *PersistentStateMachine[S, T]) Fire(trigger T, args ...any) error {
+	if p.err != nil {
+		return p.err
+	}
+	if err := p.StateMachine.Fire(trigger, args...); err != nil {
+		return err
+	}
+	return p.err
+}
+
+func (p *PersistentStateMachine[S, T]) ID() string {
+	return p.id
+}
+
+// History is the persisted transition log
+func (p *PersistentStateMachine[S, T]) History() ([]TransitionRecord[S, T], error) {
+	return p.store.Load(p.id)
+}
+
+// 3. Persisting the machines of state.go, which are not built on StateMachine
+
+// PersistentSwitch appends every time the light is turned on or off to the store
+type PersistentSwitch struct {
+	*Switch
+	id       string
+	store    StateMachineStore[string, string]
+	sequence uint64
+	now      func() time.Time
+}
+
+// ResumeSwitch turns the light on or off as it was at the end of its log
+func ResumeSwitch(id string, store StateMachineStore[string, string]) (*PersistentSwitch, error) {
+	log, err := store.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	p := &PersistentSwitch{Switch: &Switch{&OffState{}}, id: id, store: store, now: time.Now}
+	for _, r := range log {
+		if r.Source != p.state() {
+			return nil, fmt.Errorf("%w: %s at %d", ErrReplayDiverged, id, r.Sequence)
+		}
+		switch r.Destination {
+		case "On":
+			p.State = &OnState{}
+		case "Off":
+			p.State = &OffState{}
+		default:
+			return nil, fmt.Errorf("%w: %s at %d", ErrReplayDiverged, id, r.Sequence)
+		}
+		p.sequence = r.Sequence
+	}
+	return p, nil
+}
+
+func (p *PersistentSwitch) state() string {
+	if _, on := p.State.(*OnState); on {
+		return "On"
+	}
+	return "Off"
+}
+
+// On turns the light on. When the store fails the light is already on: the error tells that the log is behind it.
+func (p *PersistentSwitch) On() error {
+	return p.fire("On", p.Switch.On)
+}
+
+func (p *PersistentSwitch) Off() error {
+	return p.fire("Off", p.Switch.Off)
+}
+
+func (p *PersistentSwitch) fire(trigger string, action func()) error {
+	source := p.state()
+	action()
+	if p.state() == source {
+		return nil
+	}
+	r := TransitionRecord[string, string]{Sequence: p.sequence + 1, Source: source, Destination: p.state(), Trigger: trigger, Time: p.now()}
+	if err := p.store.Append(p.id, r); err != nil {
+		return err
+	}
+	p.sequence = r.Sequence
+	return nil
+}
+
+// PersistentSystemSecret appends every unlock, failed attempt and lock of the secret to the store,
+// so that a restart neither locks an unlocked secret nor lifts a lockout
+type PersistentSystemSecret struct {
+	*SystemSecret
+	id       string
+	store    StateMachineStore[SystemState, string]
+	sequence uint64
+	recorded SystemState // state as of the last attempt recorded
+	err      error
+}
+
+// ResumeSystemSecret replays the log of the secret, which must have just been created with the same code
+func ResumeSystemSecret(id string, s *SystemSecret, store StateMachineStore[SystemState, string]) (*PersistentSystemSecret, error) {
+	log, err := store.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range log {
+		if r.Source != s.state {
+			return nil, fmt.Errorf("%w: %s at %d", ErrReplayDiverged, id, r.Sequence)
+		}
+		if err := s.replay(r.Trigger, r.Time); err != nil {
+			return nil, fmt.Errorf("replaying %s at %d: %w", id, r.Sequence, err)
+		}
+		if s.state != r.Destination {
+			return nil, fmt.Errorf("%w: %s at %d", ErrReplayDiverged, id, r.Sequence)
+		}
+	}
+	p := &PersistentSystemSecret{SystemSecret: s, id: id, store: store, recorded: s.state}
+	if len(log) > 0 {
+		p.sequence = log[len(log)-1].Sequence
+	}
+	s.OnAttempt(p.record)
+	return p, nil
+}
+
+// record persists the attempts that changed anything: rejected attempts and attempts while unlocked do not
+func (p *PersistentSystemSecret) record(attempt UnlockAttempt) {
+	source := p.recorded
+	p.recorded = attempt.State
+	if attempt.Rejected || source == Unlocked {
+		return
+	}
+	trigger := SecretFailed
+	if attempt.Success {
+		trigger = SecretUnlocked
+	}
+	p.append(source, trigger, attempt.Time)
+}
+
+func (p *PersistentSystemSecret) append(source SystemState, trigger string, at time.Time) {
+	if p.err != nil {
+		return
+	}
+	r := TransitionRecord[SystemState, string]{Sequence: p.sequence + 1, Source: source, Destination: p.recorded, Trigger: trigger, Time: at}
+	if err := p.store.Append(p.id, r); err != nil {
+		p.err = err
+		return
+	}
+	p.sequence = r.Sequence
+}
+
+// Unlock checks the code and persists the attempt.
+// When the store fails the secret has already changed: the error tells that the log is behind it.
+func (p *PersistentSystemSecret) Unlock(code string) (bool, error) {
+	if p.err != nil {
+		return false, p.err
+	}
+	unlocked := p.SystemSecret.Unlock(code)
+	return unlocked, p.err
+}
+
+func (p *PersistentSystemSecret) Lock() error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.SystemSecret.State() != Unlocked {
+		return nil
+	}
+	p.SystemSecret.Lock()
+	p.recorded = Locked
+	p.append(Unlocked, SecretLocked, p.now())
+	return p.err
+}
@@ -0,0 +1,292 @@
+package behavioral_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fabricioandreis/design-patterns-go/patterns/behavioral"
+	"github.com/stretchr/testify/assert"
+)
+
+// An order workflow whose total is kept by the actions of the machine, not by the machine itself
+type order struct {
+	*behavioral.StateMachine[string, string]
+	Total float64
+}
+
+func newOrder() *order {
+	o := &order{StateMachine: behavioral.NewStateMachine[string, string]("Cart")}
+	o.Configure("Cart").
+		PermitReentry("add").
+		Permit("checkout", "Placed").
+		OnEntryFrom("add", func(t behavioral.Transition[string, string]) {
+			o.Total += t.Args[0].(float64)
+		})
+	o.Configure("Placed").
+		Permit("pay", "Paid").
+		Permit("cancel", "Cancelled")
+	o.Configure("Paid").
+		Permit("ship", "Shipped").
+		OnEntry(func(t behavioral.Transition[string, string]) {
+			if o.Total == 0 {
+				o.Fire("ship") // nothing to deliver
+			}
+		})
+	o.Configure("Shipped").
+		Permit("deliver", "Delivered")
+	return o
+}
+
+func TestPersistentStateMachine(t *testing.T) {
+	fileStore := func(t *testing.T) behavioral.StateMachineStore[string, string] {
+		store, err := behavioral.NewFileStateMachineStore[string, string](t.TempDir())
+		assert.NoError(t, err)
+		return store
+	}
+	memoryStore := func(t *testing.T) behavioral.StateMachineStore[string, string] {
+		return behavioral.NewMemoryStateMachineStore[string, string]()
+	}
+
+	for name, newStore := range map[string]func(t *testing.T) behavioral.StateMachineStore[string, string]{
+		"memory": memoryStore,
+		"file":   fileStore,
+	} {
+		newStore := newStore
+
+		t.Run("Should resume the last state after a restart with the "+name+" store", func(t *testing.T) {
+			store := newStore(t)
+			o := newOrder()
+			m, err := behavioral.ResumeStateMachine("order-1", o.StateMachine, store)
+			assert.NoError(t, err)
+			assert.NoError(t, m.Fire("add", 10.0))
+			assert.NoError(t, m.Fire("checkout"))
+			assert.NoError(t, m.Fire("pay"))
+
+			restarted := newOrder()
+			m, err = behavioral.ResumeStateMachine("order-1", restarted.StateMachine, store)
+			assert.NoError(t, err)
+
+			assert.Equal(t, "Paid", m.State())
+			assert.Equal(t, 0.0, restarted.Total) // actions are not run when resuming
+			assert.NoError(t, m.Fire("ship"))
+			history, _ := m.History()
+			assert.Len(t, history, 4)
+			assert.Equal(t, uint64(4), history[3].Sequence)
+			assert.Equal(t, "Paid", history[3].Source)
+			assert.Equal(t, "Shipped", history[3].Destination)
+		})
+
+		t.Run("Should rebuild the workflow by replaying the log with the "+name+" store", func(t *testing.T) {
+			store := newStore(t)
+			o := newOrder()
+			m, _ := behavioral.ResumeStateMachine("order-2", o.StateMachine, store)
+			m.Fire("add", 10.0)
+			m.Fire("add", 5.5)
+			m.Fire("checkout")
+
+			restarted := newOrder()
+			m, err := behavioral.ReplayStateMachine("order-2", restarted.StateMachine, store)
+			assert.NoError(t, err)
+
+			assert.Equal(t, "Placed", m.State())
+			assert.Equal(t, 15.5, restarted.Total)
+			assert.NoError(t, m.Fire("pay"))
+			history, _ := m.History()
+			assert.Len(t, history, 4)
+		})
+
+		t.Run("Should resume the history of superstates with the "+name+" store", func(t *testing.T) {
+			newDocument := func() *behavioral.StateMachine[string, string] {
+				m := behavioral.NewStateMachine[string, string]("Idle")
+				m.Configure("Idle").Permit("edit", "Editing")
+				m.Configure("Editing").
+					InitialTransition("Draft").
+					History(behavioral.ShallowHistory).
+					Permit("pause", "Paused")
+				m.Configure("Draft").SubstateOf("Editing").Permit("submit", "Review")
+				m.Configure("Review").SubstateOf("Editing")
+				m.Configure("Paused").Permit("resume", "Editing")
+				return m
+			}
+			store := newStore(t)
+			m, _ := behavioral.ResumeStateMachine("document", newDocument(), store)
+			m.Fire("edit")
+			m.Fire("submit")
+			m.Fire("pause")
+
+			m, err := behavioral.ResumeStateMachine("document", newDocument(), store)
+			assert.NoError(t, err)
+			assert.NoError(t, m.Fire("resume"))
+
+			assert.Equal(t, "Review", m.State())
+		})
+
+		t.Run("Should not fire triggers of actions twice when replaying with the "+name+" store", func(t *testing.T) {
+			store := newStore(t)
+			m, _ := behavioral.ResumeStateMachine("order-3", newOrder().StateMachine, store)
+			m.Fire("checkout")
+			m.Fire("pay") // fires ship from the entry action of Paid
+			assert.Equal(t, "Shipped", m.State())
+
+			m, err := behavioral.ReplayStateMachine("order-3", newOrder().StateMachine, store)
+
+			assert.NoError(t, err)
+			assert.Equal(t, "Shipped", m.State())
+			history, _ := m.History()
+			assert.Len(t, history, 3)
+		})
+	}
+
+	t.Run("Should keep machines apart by ID", func(t *testing.T) {
+		store := fileStore(t)
+		a, _ := behavioral.ResumeStateMachine("a", newOrder().StateMachine, store)
+		b, _ := behavioral.ResumeStateMachine("b", newOrder().StateMachine, store)
+		a.Fire("checkout")
+
+		a, _ = behavioral.ResumeStateMachine("a", newOrder().StateMachine, store)
+		b, _ = behavioral.ResumeStateMachine("b", newOrder().StateMachine, store)
+
+		assert.Equal(t, "Placed", a.State())
+		assert.Equal(t, "Cart", b.State())
+	})
+
+	t.Run("Should give IDs that only differ in their directory a log of their own", func(t *testing.T) {
+		dir := t.TempDir()
+		var store behavioral.StateMachineStore[string, string]
+		store, _ = behavioral.NewFileStateMachineStore[string, string](dir)
+		a, _ := behavioral.ResumeStateMachine("a/order", newOrder().StateMachine, store)
+		b, _ := behavioral.ResumeStateMachine("b/order", newOrder().StateMachine, store)
+		assert.NoError(t, a.Fire("checkout"))
+		assert.NoError(t, b.Fire("checkout"))
+		assert.NoError(t, b.Fire("cancel"))
+
+		var reopened behavioral.StateMachineStore[string, string]
+		reopened, _ = behavioral.NewFileStateMachineStore[string, string](dir)
+		for id, state := range map[string]string{"a/order": "Placed", "b/order": "Cancelled", "order": "Cart", "../order": "Cart"} {
+			m, err := behavioral.ResumeStateMachine(id, newOrder().StateMachine, reopened)
+			assert.NoError(t, err)
+			assert.Equal(t, state, m.State(), id)
+		}
+		files, _ := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+		assert.Len(t, files, 2)
+	})
+
+	t.Run("Should detect concurrent writers of the same log with the file store", func(t *testing.T) {
+		store := fileStore(t)
+		first, _ := behavioral.ResumeStateMachine("order", newOrder().StateMachine, store)
+		second, _ := behavioral.ResumeStateMachine("order", newOrder().StateMachine, store)
+
+		assert.NoError(t, first.Fire("checkout"))
+		assert.ErrorIs(t, second.Fire("checkout"), behavioral.ErrSequenceConflict)
+		assert.NoError(t, first.Fire("pay"))
+	})
+
+	t.Run("Should detect concurrent writers of the same log", func(t *testing.T) {
+		store := memoryStore(t)
+		first, _ := behavioral.ResumeStateMachine("order", newOrder().StateMachine, store)
+		second, _ := behavioral.ResumeStateMachine("order", newOrder().StateMachine, store)
+
+		assert.NoError(t, first.Fire("checkout"))
+		err := second.Fire("checkout")
+
+		assert.ErrorIs(t, err, behavioral.ErrSequenceConflict)
+		assert.ErrorIs(t, second.Fire("pay"), behavioral.ErrSequenceConflict)
+	})
+
+	t.Run("Should fail to replay a log the machine cannot follow", func(t *testing.T) {
+		store := memoryStore(t)
+		m, _ := behavioral.ResumeStateMachine("order", newOrder().StateMachine, store)
+		m.Fire("checkout")
+		m.Fire("cancel")
+
+		strict := behavioral.NewStateMachine[string, string]("Cart")
+		strict.Configure("Cart").Permit("checkout", "Placed")
+		strict.Configure("Placed").Permit("pay", "Paid")
+		_, err := behavioral.ReplayStateMachine("order", strict, store)
+
+		assert.True(t, errors.Is(err, behavioral.ErrInvalidTransition))
+	})
+
+	t.Run("Should drop a record torn by a crash with the file store", func(t *testing.T) {
+		dir := t.TempDir()
+		var store behavioral.StateMachineStore[string, string]
+		store, _ = behavioral.NewFileStateMachineStore[string, string](dir)
+		m, _ := behavioral.ResumeStateMachine("order", newOrder().StateMachine, store)
+		m.Fire("checkout")
+		file, _ := os.OpenFile(filepath.Join(dir, "order.jsonl"), os.O_WRONLY|os.O_APPEND, 0)
+		file.WriteString(`{"sequence":2,"source":"Pla`)
+		file.Close()
+
+		store, _ = behavioral.NewFileStateMachineStore[string, string](dir)
+		m, err := behavioral.ResumeStateMachine("order", newOrder().StateMachine, store)
+		assert.NoError(t, err)
+		assert.Equal(t, "Placed", m.State())
+		assert.NoError(t, m.Fire("pay"))
+
+		store, _ = behavioral.NewFileStateMachineStore[string, string](dir)
+		history, err := store.Load("order")
+		assert.NoError(t, err)
+		assert.Len(t, history, 3) // paying for an empty order ships it
+		assert.Equal(t, "Paid", history[1].Destination)
+	})
+
+	t.Run("Should persist the switch", func(t *testing.T) {
+		store := behavioral.NewMemoryStateMachineStore[string, string]()
+		sw, err := behavioral.ResumeSwitch("hall", store)
+		assert.NoError(t, err)
+		assert.NoError(t, sw.On())
+		assert.NoError(t, sw.On()) // already on: nothing to persist
+
+		sw, err = behavioral.ResumeSwitch("hall", store)
+		assert.NoError(t, err)
+		assert.IsType(t, &behavioral.OnState{}, sw.State)
+		assert.NoError(t, sw.Off())
+		history, _ := store.Load("hall")
+		assert.Len(t, history, 2)
+		assert.Equal(t, "Off", history[1].Destination)
+	})
+
+	t.Run("Should keep the secret locked out across a restart", func(t *testing.T) {
+		store := behavioral.NewMemoryStateMachineStore[behavioral.SystemState, string]()
+		now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		policy := behavioral.LockoutPolicy{MaxAttempts: 2, Window: time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour, HashIterations: 1}
+		newSecret := func() *behavioral.SystemSecret {
+			return behavioral.NewSystemSecretWithPolicy("1234", policy, func() time.Time { return now })
+		}
+		s, _ := behavioral.ResumeSystemSecret("vault", newSecret(), store)
+		s.Unlock("0000")
+		s.Unlock("0000")
+		assert.Equal(t, behavioral.Failed, s.State())
+
+		s, err := behavioral.ResumeSystemSecret("vault", newSecret(), store)
+		assert.NoError(t, err)
+		assert.Equal(t, behavioral.Failed, s.State())
+		unlocked, err := s.Unlock("1234")
+		assert.NoError(t, err)
+		assert.False(t, unlocked, "still locked out")
+
+		now = now.Add(time.Minute)
+		unlocked, err = s.Unlock("1234")
+		assert.NoError(t, err)
+		assert.True(t, unlocked)
+		s, _ = behavioral.ResumeSystemSecret("vault", newSecret(), store)
+		assert.Equal(t, behavioral.Unlocked, s.State())
+
+		assert.NoError(t, s.Lock())
+		s, _ = behavioral.ResumeSystemSecret("vault", newSecret(), store)
+		assert.Equal(t, behavioral.Locked, s.State())
+		history, _ := store.Load("vault")
+		assert.Equal(t, []string{"fail", "fail", "unlock", "lock"}, triggersOf(history))
+	})
+}
+
+func triggersOf[S comparable](history []behavioral.TransitionRecord[S, string]) []string {
+	triggers := []string{}
+	for _, r := range history {
+		triggers = append(triggers, r.Trigger)
+	}
+	return triggers
+}
@@ -0,0 +1,290 @@
+package behavioral_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fabricioandreis/design-patterns-go/patterns/behavioral"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateMachine(t *testing.T) {
+	type phoneTransition = behavioral.Transition[behavioral.PhoneState, behavioral.PhoneTrigger]
+
+	t.Run("Should transition states of a landline phone configured from the rules", func(t *testing.T) {
+		m := behavioral.NewPhoneStateMachine()
+
+		assert.NoError(t, m.Fire(behavioral.CallDialed))
+		assert.NoError(t, m.Fire(behavioral.CallConnected))
+		assert.NoError(t, m.Fire(behavioral.LeftMessage))
+
+		assert.Equal(t, behavioral.OnHook, m.State())
+	})
+
+	t.Run("Should return a typed error for invalid transitions", func(t *testing.T) {
+		m := behavioral.NewPhoneStateMachine()
+
+		err := m.Fire(behavioral.HungUp)
+
+		assert.ErrorIs(t, err, behavioral.ErrInvalidTransition)
+		var invalid *behavioral.InvalidTransitionError[behavioral.PhoneState, behavioral.PhoneTrigger]
+		assert.True(t, errors.As(err, &invalid))
+		assert.Equal(t, behavioral.OffHook, invalid.State)
+		assert.Equal(t, behavioral.HungUp, invalid.Trigger)
+		assert.Equal(t, behavioral.OffHook, m.State())
+	})
+
+	t.Run("Should list the permitted triggers of the current state", func(t *testing.T) {
+		m := behavioral.NewPhoneStateMachine()
+		m.Fire(behavioral.CallDialed)
+		m.Fire(behavioral.CallConnected)
+
+		assert.Equal(t, []behavioral.PhoneTrigger{behavioral.LeftMessage, behavioral.HungUp, behavioral.PlacedOnHold}, m.PermittedTriggers())
+		assert.True(t, m.CanFire(behavioral.PlacedOnHold))
+		assert.False(t, m.CanFire(behavioral.CallDialed))
+	})
+
+	t.Run("Should only permit transitions whose guard is met", func(t *testing.T) {
+		balance := 0
+		m := behavioral.NewStateMachine[behavioral.PhoneState, behavioral.PhoneTrigger](behavioral.OffHook)
+		m.Configure(behavioral.OffHook).
+			PermitIf(behavioral.CallDialed, behavioral.Connecting, func(args ...any) bool { return balance > 0 }, "has credit")
+
+		err := m.Fire(behavioral.CallDialed)
+		assert.ErrorIs(t, err, behavioral.ErrInvalidTransition)
+		assert.Contains(t, err.Error(), "has credit")
+		assert.Empty(t, m.PermittedTriggers())
+
+		balance = 10
+		assert.NoError(t, m.Fire(behavioral.CallDialed))
+		assert.Equal(t, behavioral.Connecting, m.State())
+	})
+
+	t.Run("Should pass trigger parameters to guards and actions", func(t *testing.T) {
+		dialed := ""
+		m := behavioral.NewStateMachine[behavioral.PhoneState, behavioral.PhoneTrigger](behavioral.OffHook)
+		m.Configure(behavioral.OffHook).
+			PermitIf(behavioral.CallDialed, behavioral.Connecting, func(args ...any) bool {
+				number, ok := args[0].(string)
+				return ok && len(number) > 0
+			}, "number is not empty")
+		m.Configure(behavioral.Connecting).
+			OnEntryFrom(behavioral.CallDialed, func(t phoneTransition) { dialed = t.Args[0].(string) })
+
+		assert.False(t, m.CanFire(behavioral.CallDialed, ""))
+		assert.ErrorIs(t, m.Fire(behavioral.CallDialed, ""), behavioral.ErrInvalidTransition)
+		assert.NoError(t, m.Fire(behavioral.CallDialed, "555-0100"))
+		assert.Equal(t, "555-0100", dialed)
+	})
+
+	t.Run("Should run exit and entry actions in order", func(t *testing.T) {
+		calls := []string{}
+		m := behavioral.NewPhoneStateMachine()
+		m.Configure(behavioral.Connecting).
+			OnExit(func(t phoneTransition) { calls = append(calls, "exit "+t.Source.String()) })
+		m.Configure(behavioral.Connected).
+			OnEntry(func(t phoneTransition) { calls = append(calls, "enter "+t.Destination.String()) }).
+			PermitReentry(behavioral.CallConnected).
+			OnExit(func(t phoneTransition) { calls = append(calls, "exit "+t.Source.String()) })
+		m.OnTransition(func(t phoneTransition) { calls = append(calls, "transition "+t.Trigger.String()) })
+
+		m.Fire(behavioral.CallDialed)
+		m.Fire(behavioral.CallConnected)
+		m.Fire(behavioral.CallConnected)
+
+		assert.Equal(t, []string{
+			"transition CallDialed",
+			"exit Connecting", "enter Connected", "transition CallConnected",
+			"exit Connected", "enter Connected", "transition CallConnected",
+		}, calls)
+	})
+
+	t.Run("Should ignore triggers without running actions", func(t *testing.T) {
+		entered := 0
+		m := behavioral.NewStateMachine[behavioral.PhoneState, behavioral.PhoneTrigger](behavioral.OnHold)
+		m.Configure(behavioral.OnHold).
+			Ignore(behavioral.PlacedOnHold).
+			OnEntry(func(t phoneTransition) { entered++ })
+
+		assert.NoError(t, m.Fire(behavioral.PlacedOnHold))
+		assert.Equal(t, 0, entered)
+		assert.Equal(t, behavioral.OnHold, m.State())
+	})
+
+	t.Run("Should queue triggers fired from actions", func(t *testing.T) {
+		m := behavioral.NewPhoneStateMachine()
+		m.Configure(behavioral.Connecting).
+			OnEntry(func(t phoneTransition) { m.Fire(behavioral.CallConnected) })
+
+		assert.NoError(t, m.Fire(behavioral.CallDialed))
+		assert.Equal(t, behavioral.Connected, m.State())
+	})
+
+	t.Run("Should port the phone call onto the state machine", func(t *testing.T) {
+		call := behavioral.NewPhoneCall()
+
+		assert.NoError(t, call.Dial("Daiana"))
+		assert.NoError(t, call.Fire(behavioral.CallConnected))
+		assert.NoError(t, call.Fire(behavioral.PlacedOnHold))
+		assert.NoError(t, call.Fire(behavioral.PlacedOnHold))
+		assert.NoError(t, call.Fire(behavioral.HungUp))
+
+		assert.Equal(t, behavioral.OnHook, call.State())
+		assert.Equal(t, []string{"Calling Daiana", "Connected to Daiana", "PlacedOnHold while talking to Daiana", "On hold", "Hung up"}, call.Log)
+	})
+
+	t.Run("Should refuse to dial without a number", func(t *testing.T) {
+		call := behavioral.NewPhoneCall()
+
+		for _, args := range [][]any{nil, {42}, {""}, {"Daiana", "Simon"}} {
+			err := call.Fire(behavioral.CallDialed, args...)
+			assert.ErrorIs(t, err, behavioral.ErrInvalidTransition, args)
+			assert.ErrorContains(t, err, "a number to dial")
+		}
+		assert.Equal(t, behavioral.OffHook, call.State())
+		assert.Empty(t, call.Log)
+		assert.NoError(t, call.Dial("Daiana"))
+	})
+}
+
+func TestHierarchicalStateMachine(t *testing.T) {
+	type phoneTransition = behavioral.Transition[behavioral.PhoneState, behavioral.PhoneTrigger]
+
+	traced := func() (*behavioral.StateMachine[behavioral.PhoneState, behavioral.PhoneTrigger], *[]string) {
+		calls := []string{}
+		m := behavioral.NewHierarchicalPhoneStateMachine()
+		for _, s := range []behavioral.PhoneState{behavioral.OffHook, behavioral.Connecting, behavioral.Connected, behavioral.Talking, behavioral.OnHold, behavioral.OnHook} {
+			s := s
+			m.Configure(s).
+				OnEntry(func(t phoneTransition) { calls = append(calls, "enter "+s.String()) }).
+				OnExit(func(t phoneTransition) { calls = append(calls, "exit "+s.String()) })
+		}
+		return m, &calls
+	}
+
+	t.Run("Should handle hanging up once in the superstate", func(t *testing.T) {
+		m := behavioral.NewHierarchicalPhoneStateMachine()
+		m.Fire(behavioral.CallDialed)
+		m.Fire(behavioral.CallConnected)
+
+		assert.Equal(t, behavioral.Talking, m.State())
+		assert.True(t, m.IsInState(behavioral.Connected))
+		assert.Equal(t, []behavioral.PhoneTrigger{behavioral.PlacedOnHold, behavioral.LeftMessage, behavioral.HungUp}, m.PermittedTriggers())
+
+		assert.NoError(t, m.Fire(behavioral.PlacedOnHold))
+		assert.Equal(t, behavioral.OnHold, m.State())
+		assert.True(t, m.IsInState(behavioral.Connected))
+
+		assert.NoError(t, m.Fire(behavioral.HungUp))
+		assert.Equal(t, behavioral.OnHook, m.State())
+		assert.False(t, m.IsInState(behavioral.Connected))
+	})
+
+	t.Run("Should leave a message from any substate of a connected call", func(t *testing.T) {
+		for _, trigger := range [][]behavioral.PhoneTrigger{
+			{behavioral.LeftMessage},
+			{behavioral.PlacedOnHold, behavioral.LeftMessage},
+			{behavioral.PlacedOnHold, behavioral.TakenOffHold, behavioral.LeftMessage},
+		} {
+			m := behavioral.NewHierarchicalPhoneStateMachine()
+			m.Fire(behavioral.CallDialed)
+			m.Fire(behavioral.CallConnected)
+			for _, tr := range trigger {
+				assert.NoError(t, m.Fire(tr))
+			}
+			assert.Equal(t, behavioral.OnHook, m.State())
+		}
+	})
+
+	t.Run("Should enter superstates before substates and exit them after", func(t *testing.T) {
+		m, calls := traced()
+
+		m.Fire(behavioral.CallDialed)
+		m.Fire(behavioral.CallConnected)
+		assert.Equal(t, []string{"exit OffHook", "enter Connecting", "exit Connecting", "enter Connected", "enter Talking"}, *calls)
+
+		*calls = nil
+		m.Fire(behavioral.PlacedOnHold)
+		assert.Equal(t, []string{"exit Talking", "enter OnHold"}, *calls)
+
+		*calls = nil
+		m.Fire(behavioral.HungUp)
+		assert.Equal(t, []string{"exit OnHold", "exit Connected", "enter OnHook"}, *calls)
+	})
+
+	t.Run("Should report the innermost states of a transition", func(t *testing.T) {
+		m := behavioral.NewHierarchicalPhoneStateMachine()
+		transitions := []phoneTransition{}
+		m.OnTransition(func(t phoneTransition) { transitions = append(transitions, t) })
+		m.Fire(behavioral.CallDialed)
+		m.Fire(behavioral.CallConnected)
+		m.Fire(behavioral.PlacedOnHold)
+		m.Fire(behavioral.HungUp)
+
+		assert.Equal(t, behavioral.Talking, transitions[1].Destination)
+		assert.Equal(t, behavioral.OnHold, transitions[3].Source)
+		assert.Equal(t, behavioral.OnHook, transitions[3].Destination)
+	})
+
+	// A radio with nested stations:
+	// Off -> On { Radio { FM1, FM2 }, CD }
+	newRadio := func(history behavioral.HistoryKind) *behavioral.StateMachine[string, string] {
+		m := behavioral.NewStateMachine[string, string]("Off")
+		m.Configure("Off").Permit("power", "On")
+		m.Configure("On").InitialTransition("Radio").History(history).Permit("power", "Off")
+		m.Configure("Radio").SubstateOf("On").InitialTransition("FM1").Permit("source", "CD")
+		m.Configure("FM1").SubstateOf("Radio").Permit("next", "FM2")
+		m.Configure("FM2").SubstateOf("Radio").Permit("next", "FM1")
+		m.Configure("CD").SubstateOf("On").Permit("source", "Radio")
+		return m
+	}
+
+	t.Run("Should enter initial substates without history", func(t *testing.T) {
+		m := newRadio(behavioral.NoHistory)
+		m.Fire("power")
+		m.Fire("next")
+		m.Fire("power")
+
+		m.Fire("power")
+
+		assert.Equal(t, "FM1", m.State())
+	})
+
+	t.Run("Should resume the last substate with shallow history", func(t *testing.T) {
+		m := newRadio(behavioral.ShallowHistory)
+		m.Fire("power")
+		m.Fire("next")
+		m.Fire("power")
+
+		m.Fire("power")
+		assert.Equal(t, "FM1", m.State()) // Radio is resumed, but enters its own initial state
+
+		m.Fire("source")
+		m.Fire("power")
+		m.Fire("power")
+		assert.Equal(t, "CD", m.State())
+	})
+
+	t.Run("Should resume the innermost state with deep history", func(t *testing.T) {
+		m := newRadio(behavioral.DeepHistory)
+		m.Fire("power")
+		m.Fire("next")
+		m.Fire("power")
+
+		assert.NoError(t, m.Fire("power"))
+
+		assert.Equal(t, "FM2", m.State())
+		assert.True(t, m.IsInState("Radio"))
+	})
+	t.Run("Should refuse a state nested in itself", func(t *testing.T) {
+		m := behavioral.NewStateMachine[string, string]("A")
+		m.Configure("B").SubstateOf("A")
+		m.Configure("C").SubstateOf("B")
+
+		assert.Panics(t, func() { m.Configure("A").SubstateOf("A") })
+		assert.Panics(t, func() { m.Configure("A").SubstateOf("B") })
+		assert.Panics(t, func() { m.Configure("A").SubstateOf("C") })
+		assert.NotPanics(t, func() { m.Configure("C").SubstateOf("A") })
+		assert.True(t, m.IsInState("A"))
+	})
+}
@@ -1,7 +1,11 @@
 package behavioral_test
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/fabricioandreis/design-patterns-go/patterns/behavioral"
 	"github.com/stretchr/testify/assert"
@@ -30,21 +34,160 @@ func TestState(t *testing.T) {
 
 	t.Run("Should be able to transition states with switch statement", func(t *testing.T) {
 		type testResult struct {
+			code   string
 			output bool
 			state  behavioral.SystemState
 		}
-		sys := behavioral.NewSystemSecret("Rafael")
-		tests := map[string]testResult{
-			"Daiana":   {false, behavioral.Failed},
-			"Fabrício": {false, behavioral.Locked},
-			"Rafael":   {true, behavioral.Unlocked},
+		sys := behavioral.NewSystemSecretWithPolicy("Rafael", behavioral.LockoutPolicy{MaxAttempts: 3, HashIterations: 1}, time.Now)
+		tests := []testResult{
+			{"Daiana", false, behavioral.Locked},
+			{"Fabrício", false, behavioral.Locked},
+			{"Rafael", true, behavioral.Unlocked},
+			{"Daiana", false, behavioral.Unlocked},
+			{"Rafael", true, behavioral.Unlocked},
 		}
 
-		for code, result := range tests {
-			output := sys.Unlock(code)
+		for _, test := range tests {
+			output := sys.Unlock(test.code)
 
-			assert.Equal(t, result.output, output)
-			assert.Equal(t, result.state, sys.State())
+			assert.Equal(t, test.output, output)
+			assert.Equal(t, test.state, sys.State())
 		}
 	})
+
+	policy := behavioral.LockoutPolicy{
+		MaxAttempts: 3,
+		Window:      time.Minute,
+		BaseLockout: 30 * time.Second,
+		MaxLockout:  2 * time.Minute,
+		// cheap hashing, which production code must not use
+		HashIterations: 1,
+	}
+
+	t.Run("Should lock out after too many failed attempts within the window", func(t *testing.T) {
+		now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		sys := behavioral.NewSystemSecretWithPolicy("1234", policy, func() time.Time { return now })
+
+		assert.False(t, sys.Unlock("0000"))
+		assert.False(t, sys.Unlock("1111"))
+		now = now.Add(time.Minute) // the first two failures are out of the window
+		assert.False(t, sys.Unlock("2222"))
+		assert.Equal(t, behavioral.Locked, sys.State())
+		assert.False(t, sys.Unlock("3333"))
+		assert.False(t, sys.Unlock("4444"))
+		assert.Equal(t, behavioral.Failed, sys.State())
+
+		assert.False(t, sys.Unlock("1234")) // the right code is not even checked while locked out
+		assert.Equal(t, behavioral.Failed, sys.State())
+
+		now = now.Add(30 * time.Second)
+		assert.True(t, sys.Unlock("1234"))
+		assert.Equal(t, behavioral.Unlocked, sys.State())
+	})
+
+	t.Run("Should double the lockout on each consecutive lockout up to the maximum", func(t *testing.T) {
+		now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		sys := behavioral.NewSystemSecretWithPolicy("1234", policy, func() time.Time { return now })
+		lockouts := []time.Duration{}
+		sys.OnAttempt(func(a behavioral.UnlockAttempt) {
+			if a.State == behavioral.Failed && !a.Rejected {
+				lockouts = append(lockouts, a.LockedUntil.Sub(a.Time))
+			}
+		})
+
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 3; j++ {
+				sys.Unlock("0000")
+			}
+			now = now.Add(time.Hour)
+		}
+		assert.Equal(t, []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 2 * time.Minute}, lockouts)
+
+		assert.True(t, sys.Unlock("1234")) // a success resets the lockout
+		sys.Lock()
+		for j := 0; j < 3; j++ {
+			sys.Unlock("0000")
+		}
+		assert.Equal(t, 30*time.Second, lockouts[len(lockouts)-1])
+	})
+
+	t.Run("Should audit every unlock attempt", func(t *testing.T) {
+		now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		sys := behavioral.NewSystemSecretWithPolicy("1234", policy, func() time.Time { return now })
+		attempts := []behavioral.UnlockAttempt{}
+		sys.OnAttempt(func(a behavioral.UnlockAttempt) { attempts = append(attempts, a) })
+
+		sys.Unlock("0000")
+		sys.Unlock("0000")
+		sys.Unlock("0000")
+		sys.Unlock("1234")
+
+		assert.Equal(t, []behavioral.UnlockAttempt{
+			{Time: now, State: behavioral.Locked, Failures: 1},
+			{Time: now, State: behavioral.Locked, Failures: 2},
+			{Time: now, State: behavioral.Failed, Failures: 3, LockedUntil: now.Add(30 * time.Second)},
+			{Time: now, Rejected: true, State: behavioral.Failed, LockedUntil: now.Add(30 * time.Second)},
+		}, attempts)
+	})
+
+	t.Run("Should not keep the code in the secret", func(t *testing.T) {
+		sys := behavioral.NewSystemSecret("a very recognizable code")
+
+		assert.NotContains(t, fmt.Sprintf("%#v", *sys), "a very recognizable code")
+	})
+
+	t.Run("Should store the code hashed with a slow key derivation", func(t *testing.T) {
+		sys := behavioral.NewSystemSecret("1234")
+		salt, hash := behavioral.ExportSystemSecretHash(sys)
+
+		fast := sha256.Sum256(append(append([]byte{}, salt...), "1234"...))
+		slow := behavioral.ExportPBKDF2([]byte("1234"), salt, behavioral.DefaultLockoutPolicy.HashIterations, behavioral.HashKeyLength)
+		assert.NotEqual(t, fast[:], hash)
+		assert.Equal(t, slow, hash)
+	})
+
+	t.Run("Should derive keys as PBKDF2 with HMAC-SHA256", func(t *testing.T) {
+		vectors := map[int]string{
+			1:    "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b",
+			2:    "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43",
+			4096: "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a",
+		}
+		for iterations, expected := range vectors {
+			key := behavioral.ExportPBKDF2([]byte("password"), []byte("salt"), iterations, 32)
+			assert.Equal(t, expected, hex.EncodeToString(key), iterations)
+		}
+		assert.Len(t, behavioral.ExportPBKDF2([]byte("password"), []byte("salt"), 1, 40), 40, "longer than a block")
+	})
+
+	t.Run("Should fill a partial policy with the default one", func(t *testing.T) {
+		now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		uncapped := behavioral.LockoutPolicy{MaxAttempts: 3, Window: time.Minute, BaseLockout: 30 * time.Second, HashIterations: 1}
+		sys := behavioral.NewSystemSecretWithPolicy("1234", uncapped, func() time.Time { return now })
+		for i := 0; i < 3; i++ {
+			sys.Unlock("0000")
+		}
+		assert.Equal(t, behavioral.Failed, sys.State(), "a missing maximum lockout does not disable lockouts")
+		now = now.Add(29 * time.Second)
+		assert.False(t, sys.Unlock("1234"))
+
+		sys = behavioral.NewSystemSecretWithPolicy("1234", behavioral.LockoutPolicy{HashIterations: 1}, func() time.Time { return now })
+		sys.Unlock("0000")
+		assert.Equal(t, behavioral.Locked, sys.State(), "a missing maximum of attempts does not lock out on the first failure")
+		sys.Unlock("0000")
+		sys.Unlock("0000")
+		assert.Equal(t, behavioral.Failed, sys.State())
+	})
+
+	t.Run("Should audit wrong codes while unlocked as failures", func(t *testing.T) {
+		sys := behavioral.NewSystemSecretWithPolicy("1234", policy, time.Now)
+		var attempts []behavioral.UnlockAttempt
+		sys.OnAttempt(func(a behavioral.UnlockAttempt) { attempts = append(attempts, a) })
+		sys.Unlock("1234")
+		sys.Unlock("4321")
+
+		assert.Len(t, attempts, 2)
+		assert.True(t, attempts[0].Success)
+		assert.False(t, attempts[1].Success)
+		assert.Equal(t, behavioral.Unlocked, attempts[1].State)
+	})
 }
@@ -1,45 +1,352 @@
 package behavioral
 
-import "strings"
+import (
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
 
 // Print a list of textual items using different formats
-type OutputFormat int
+type OutputFormat string
 
 const (
-	Markdown OutputFormat = iota
-	Html
+	Markdown         OutputFormat = "markdown"
+	Html             OutputFormat = "html"
+	AsciiDoc         OutputFormat = "asciidoc"
+	ReStructuredText OutputFormat = "rst"
+	LaTeX            OutputFormat = "latex"
+	PlainText        OutputFormat = "text"
 )
 
+var ErrUnknownOutputFormat = errors.New("unknown output format")
+
+// List is a list of items, each of which may hold a nested list
+type List struct {
+	Ordered bool
+	Items   []ListItem
+}
+
+type ListItem struct {
+	Text    string
+	Sublist *List
+}
+
+// ListStrategy writes a list. Nested lists are started after the item that holds them and ended before the item ends,
+// so strategies keep track of the lists they are in.
+// Items are given unescaped: strategies escape whatever is special in their format.
 type ListStrategy interface {
-	Start(builder *strings.Builder)
+	Start(builder *strings.Builder, ordered bool)
 	End(builder *strings.Builder)
-	AddListItem(builder *strings.Builder, item string)
+	StartItem(builder *strings.Builder, item ListItem, number int) // number counts the items of the list from 1
+	EndItem(builder *strings.Builder, item ListItem)
+}
+
+var (
+	outputFormatsMu sync.RWMutex
+	outputFormats   = map[OutputFormat]func() ListStrategy{
+		Markdown:         func() ListStrategy { return &MarkdownListStrategy{} },
+		Html:             func() ListStrategy { return &HtmlListStrategy{} },
+		AsciiDoc:         func() ListStrategy { return &AsciiDocListStrategy{} },
+		ReStructuredText: func() ListStrategy { return &ReStructuredTextListStrategy{} },
+		LaTeX:            func() ListStrategy { return &LaTeXListStrategy{} },
+		PlainText:        func() ListStrategy { return &PlainTextListStrategy{} },
+	}
+)
+
+// RegisterOutputFormat makes a format available by name. It panics if the name is already taken, like database/sql drivers.
+func RegisterOutputFormat(f OutputFormat, newStrategy func() ListStrategy) {
+	outputFormatsMu.Lock()
+	defer outputFormatsMu.Unlock()
+	if newStrategy == nil {
+		panic("behavioral: RegisterOutputFormat strategy is nil")
+	}
+	if _, ok := outputFormats[f]; ok {
+		panic("behavioral: RegisterOutputFormat called twice for " + string(f))
+	}
+	outputFormats[f] = newStrategy
+}
+
+// OutputFormats lists the registered formats, sorted by name
+func OutputFormats() []OutputFormat {
+	outputFormatsMu.RLock()
+	defer outputFormatsMu.RUnlock()
+	formats := make([]OutputFormat, 0, len(outputFormats))
+	for f := range outputFormats {
+		formats = append(formats, f)
+	}
+	sort.Slice(formats, func(i, j int) bool { return formats[i] < formats[j] })
+	return formats
+}
+
+// listLevels is the stack of the lists a strategy is in, innermost last
+type listLevels []listLevel
+
+type listLevel struct {
+	ordered bool
+	indent  string // indentation of the list
+	content string // indentation of the lists nested in the current item
+}
+
+func (l *listLevels) push(ordered bool) *listLevel {
+	indent := ""
+	if n := len(*l); n > 0 {
+		indent = (*l)[n-1].content
+	}
+	*l = append(*l, listLevel{ordered: ordered, indent: indent, content: indent})
+	return l.top()
+}
+
+func (l *listLevels) pop() {
+	*l = (*l)[:len(*l)-1]
 }
 
-type MarkdownListStrategy struct{}
+func (l listLevels) top() *listLevel {
+	return &l[len(l)-1]
+}
 
-func (m *MarkdownListStrategy) Start(builder *strings.Builder) {
+func (l listLevels) depth() int {
+	return len(l) - 1
+}
+
+// writeItem writes an item behind its marker, so that the lists nested in the item and the other lines of its text
+// line up with its first line
+func (l listLevels) writeItem(builder *strings.Builder, marker, text string) {
+	top := l.top()
+	top.content = top.indent + strings.Repeat(" ", len(marker))
+	builder.WriteString(top.indent + marker + strings.ReplaceAll(text, "\n", "\n"+top.content) + "\n")
+}
+
+// Escaping for the formats where a backslash takes the special meaning away from the next character
+
+var (
+	markdownEscaper = strings.NewReplacer(
+		`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+		`<`, `\<`, `>`, `\>`, `#`, `\#`, `|`, `\|`, `~`, `\~`,
+	)
+	rstEscaper = strings.NewReplacer(
+		`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `|`, `\|`, `[`, `\[`, `]`, `\]`,
+	)
+	// enumerator matches the start of a line that would begin an ordered list, such as "1.", "a)", "(iv)" or "#."
+	enumerator = regexp.MustCompile(`^\(?(?:\d+|[A-Za-z]|[IVXLCDMivxlcdm]+|#)([.)])(?:\s|$)`)
+)
+
+// escapeLines escapes every line of text, and then whatever would make a line start a new list, heading or rule
+// instead of continuing the item
+func escapeLines(text string, escaper *strings.Replacer) string {
+	lines := strings.Split(escaper.Replace(text), "\n")
+	for i, line := range lines {
+		line = strings.TrimLeft(line, " \t")
+		if m := enumerator.FindStringSubmatchIndex(line); m != nil {
+			line = line[:m[2]] + `\` + line[m[2]:]
+		} else if line != "" && strings.ContainsRune("-+=", rune(line[0])) {
+			line = `\` + line
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 1. Markdown
+type MarkdownListStrategy struct {
+	levels listLevels
+}
+
+func (m *MarkdownListStrategy) Start(builder *strings.Builder, ordered bool) {
+	m.levels.push(ordered)
 }
 
 func (m *MarkdownListStrategy) End(builder *strings.Builder) {
+	m.levels.pop()
 }
 
-func (m *MarkdownListStrategy) AddListItem(builder *strings.Builder, item string) {
-	builder.WriteString(" * " + item + "\n")
+func (m *MarkdownListStrategy) StartItem(builder *strings.Builder, item ListItem, number int) {
+	marker := " * "
+	if m.levels.top().ordered {
+		marker = fmt.Sprintf(" %d. ", number)
+	}
+	m.levels.writeItem(builder, marker, escapeLines(item.Text, markdownEscaper))
 }
 
-type HtmlListStrategy struct{}
+func (m *MarkdownListStrategy) EndItem(builder *strings.Builder, item ListItem) {
+}
 
-func (h *HtmlListStrategy) Start(builder *strings.Builder) {
-	builder.WriteString("<ul>\n")
+// 2. HTML
+type HtmlListStrategy struct {
+	levels listLevels
+}
+
+func (h *HtmlListStrategy) tag() string {
+	if h.levels.top().ordered {
+		return "ol"
+	}
+	return "ul"
+}
+
+func (h *HtmlListStrategy) Start(builder *strings.Builder, ordered bool) {
+	level := h.levels.push(ordered)
+	builder.WriteString(level.indent + "<" + h.tag() + ">\n")
 }
 
 func (h *HtmlListStrategy) End(builder *strings.Builder) {
-	builder.WriteString("</ul>\n")
+	builder.WriteString(h.levels.top().indent + "</" + h.tag() + ">\n")
+	h.levels.pop()
+}
+
+func (h *HtmlListStrategy) StartItem(builder *strings.Builder, item ListItem, number int) {
+	level := h.levels.top()
+	builder.WriteString(level.indent + "  <li>" + html.EscapeString(item.Text))
+	if item.Sublist == nil {
+		builder.WriteString("</li>\n")
+		return
+	}
+	builder.WriteString("\n")
+	level.content = level.indent + "    "
+}
+
+func (h *HtmlListStrategy) EndItem(builder *strings.Builder, item ListItem) {
+	if item.Sublist != nil {
+		builder.WriteString(h.levels.top().indent + "  </li>\n")
+	}
+}
+
+// 3. AsciiDoc: the depth of an item is told by the length of its marker
+type AsciiDocListStrategy struct {
+	levels listLevels
+}
+
+// asciiDocEscape keeps an item on a single line, and passes text with markup characters through without formatting it,
+// only escaping the characters that are special in HTML
+func asciiDocEscape(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if !strings.ContainsAny(text, "*_`#^~+{}[]<>&'\":\\") {
+		return text
+	}
+	return "pass:c[" + strings.ReplaceAll(text, "]", `\]`) + "]"
 }
 
-func (h *HtmlListStrategy) AddListItem(builder *strings.Builder, item string) {
-	builder.WriteString("  <li>" + item + "</li>\n")
+func (a *AsciiDocListStrategy) Start(builder *strings.Builder, ordered bool) {
+	a.levels.push(ordered)
+}
+
+func (a *AsciiDocListStrategy) End(builder *strings.Builder) {
+	a.levels.pop()
+}
+
+func (a *AsciiDocListStrategy) StartItem(builder *strings.Builder, item ListItem, number int) {
+	marker := "*"
+	if a.levels.top().ordered {
+		marker = "."
+	}
+	builder.WriteString(strings.Repeat(marker, a.levels.depth()+1) + " " + asciiDocEscape(item.Text) + "\n")
+}
+
+func (a *AsciiDocListStrategy) EndItem(builder *strings.Builder, item ListItem) {
+}
+
+// 4. reStructuredText: nested lists are indented like the text of their item and set apart by blank lines
+type ReStructuredTextListStrategy struct {
+	levels listLevels
+}
+
+func (r *ReStructuredTextListStrategy) Start(builder *strings.Builder, ordered bool) {
+	if len(r.levels) > 0 {
+		builder.WriteString("\n")
+	}
+	r.levels.push(ordered)
+}
+
+func (r *ReStructuredTextListStrategy) End(builder *strings.Builder) {
+	r.levels.pop()
+	if len(r.levels) > 0 {
+		builder.WriteString("\n")
+	}
+}
+
+func (r *ReStructuredTextListStrategy) StartItem(builder *strings.Builder, item ListItem, number int) {
+	marker := "- "
+	if r.levels.top().ordered {
+		marker = fmt.Sprintf("%d. ", number)
+	}
+	r.levels.writeItem(builder, marker, escapeLines(item.Text, rstEscaper))
+}
+
+func (r *ReStructuredTextListStrategy) EndItem(builder *strings.Builder, item ListItem) {
+}
+
+// 5. LaTeX
+type LaTeXListStrategy struct {
+	levels listLevels
+}
+
+var latexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`$`, `\$`,
+	`#`, `\#`,
+	`_`, `\_`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+)
+
+func (l *LaTeXListStrategy) environment() string {
+	if l.levels.top().ordered {
+		return "enumerate"
+	}
+	return "itemize"
+}
+
+func (l *LaTeXListStrategy) Start(builder *strings.Builder, ordered bool) {
+	level := l.levels.push(ordered)
+	builder.WriteString(level.indent + `\begin{` + l.environment() + "}\n")
+	level.content = level.indent + "  "
+}
+
+func (l *LaTeXListStrategy) End(builder *strings.Builder) {
+	builder.WriteString(l.levels.top().indent + `\end{` + l.environment() + "}\n")
+	l.levels.pop()
+}
+
+func (l *LaTeXListStrategy) StartItem(builder *strings.Builder, item ListItem, number int) {
+	text := latexEscaper.Replace(item.Text)
+	if strings.HasPrefix(text, "[") {
+		// \item would take the bracket as the start of its optional label
+		text = "{[}" + text[1:]
+	}
+	builder.WriteString(l.levels.top().content + `\item ` + text + "\n")
+}
+
+func (l *LaTeXListStrategy) EndItem(builder *strings.Builder, item ListItem) {
+}
+
+// 6. Plain text: nothing needs escaping, the lines of an item are only indented like its first one
+type PlainTextListStrategy struct {
+	levels listLevels
+}
+
+func (p *PlainTextListStrategy) Start(builder *strings.Builder, ordered bool) {
+	p.levels.push(ordered)
+}
+
+func (p *PlainTextListStrategy) End(builder *strings.Builder) {
+	p.levels.pop()
+}
+
+func (p *PlainTextListStrategy) StartItem(builder *strings.Builder, item ListItem, number int) {
+	marker := "- "
+	if p.levels.top().ordered {
+		marker = fmt.Sprintf("%d. ", number)
+	}
+	p.levels.writeItem(builder, marker, item.Text)
+}
+
+func (p *PlainTextListStrategy) EndItem(builder *strings.Builder, item ListItem) {
 }
 
 type TextProcessor struct {
@@ -47,30 +354,55 @@ type TextProcessor struct {
 	listStrategy ListStrategy
 }
 
-func NewTextProcessor(f OutputFormat) *TextProcessor {
+func NewTextProcessor(f OutputFormat) (*TextProcessor, error) {
 	tp := &TextProcessor{builder: strings.Builder{}}
-	tp.SetOutputFormat(f)
-	return tp
+	if err := tp.SetOutputFormat(f); err != nil {
+		return nil, err
+	}
+	return tp, nil
 }
 
-func (t *TextProcessor) SetOutputFormat(f OutputFormat) {
-	switch f {
-	case Markdown:
-		t.listStrategy = &MarkdownListStrategy{}
-	case Html:
-		t.listStrategy = &HtmlListStrategy{}
+// SetOutputFormat keeps the current format when f is not registered
+func (t *TextProcessor) SetOutputFormat(f OutputFormat) error {
+	outputFormatsMu.RLock()
+	newStrategy, ok := outputFormats[f]
+	outputFormatsMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownOutputFormat, f)
 	}
+	t.listStrategy = newStrategy()
+	return nil
 }
 
 func (t *TextProcessor) AppendList(items []string) {
+	t.AppendNestedList(listOf(false, items))
+}
+
+func (t *TextProcessor) AppendOrderedList(items []string) {
+	t.AppendNestedList(listOf(true, items))
+}
+
+func (t *TextProcessor) AppendNestedList(list List) {
 	s := t.listStrategy
-	s.Start(&t.builder)
-	for _, item := range items {
-		s.AddListItem(&t.builder, item)
+	s.Start(&t.builder, list.Ordered)
+	for i, item := range list.Items {
+		s.StartItem(&t.builder, item, i+1)
+		if item.Sublist != nil {
+			t.AppendNestedList(*item.Sublist)
+		}
+		s.EndItem(&t.builder, item)
 	}
 	s.End(&t.builder)
 }
 
+func listOf(ordered bool, items []string) List {
+	list := List{Ordered: ordered, Items: make([]ListItem, len(items))}
+	for i, item := range items {
+		list.Items[i] = ListItem{Text: item}
+	}
+	return list
+}
+
 func (t *TextProcessor) Reset() {
 	t.builder.Reset()
 }
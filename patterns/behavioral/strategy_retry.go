@@ -0,0 +1,367 @@
+package behavioral
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Retry policies are strategies deciding how long to wait before trying a failed operation again, and when to give up.
+// The Retrier runs an operation with any of them.
+
+var (
+	ErrRetriesExhausted = errors.New("retries exhausted")
+	ErrCircuitOpen      = errors.New("circuit breaker is open")
+)
+
+type RetryPolicy interface {
+	// Backoff returns the wait before the given retry, counted from 1, knowing the previous wait.
+	// It returns false to give up.
+	Backoff(retry int, previous time.Duration) (time.Duration, bool)
+}
+
+// 1. Constant: the same wait every time
+type ConstantBackoff struct {
+	Delay      time.Duration
+	MaxRetries int
+}
+
+func (b ConstantBackoff) Backoff(retry int, previous time.Duration) (time.Duration, bool) {
+	return b.Delay, retry <= b.MaxRetries
+}
+
+// 2. Linear: the wait grows by the same increment every time, up to Max when it is positive
+type LinearBackoff struct {
+	Initial    time.Duration
+	Increment  time.Duration
+	Max        time.Duration
+	MaxRetries int
+}
+
+func (b LinearBackoff) Backoff(retry int, previous time.Duration) (time.Duration, bool) {
+	return capBackoff(b.Initial+time.Duration(retry-1)*b.Increment, b.Max), retry <= b.MaxRetries
+}
+
+// 3. Exponential: the wait is multiplied every time, up to Max when it is positive
+type ExponentialBackoff struct {
+	Initial    time.Duration
+	Multiplier float64 // 2 when not above 1
+	Max        time.Duration
+	MaxRetries int
+}
+
+func (b ExponentialBackoff) Backoff(retry int, previous time.Duration) (time.Duration, bool) {
+	multiplier := b.Multiplier
+	if multiplier <= 1 {
+		multiplier = 2
+	}
+	wait := float64(b.Initial)
+	for i := 1; i < retry && (b.Max <= 0 || wait < float64(b.Max)); i++ {
+		wait *= multiplier
+	}
+	return capBackoff(time.Duration(wait), b.Max), retry <= b.MaxRetries
+}
+
+// 4. Decorrelated jitter: a random wait between Base and three times the previous one, up to Cap.
+// Clients failing together spread their retries instead of hitting the server again at the same time.
+// See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+type DecorrelatedJitter struct {
+	Base       time.Duration // DefaultJitterBase when not positive, since every wait would be 0 otherwise
+	Cap        time.Duration
+	MaxRetries int
+	Rand       *rand.Rand // the global source when nil; a rand.Rand must not be shared between goroutines
+}
+
+const DefaultJitterBase = 100 * time.Millisecond
+
+func (b DecorrelatedJitter) Backoff(retry int, previous time.Duration) (time.Duration, bool) {
+	base := b.Base
+	if base <= 0 {
+		base = DefaultJitterBase
+	}
+	if previous < base {
+		previous = base
+	}
+	spread := int64(3*previous - base)
+	wait := base
+	if spread > 0 {
+		if b.Rand != nil {
+			wait += time.Duration(b.Rand.Int63n(spread))
+		} else {
+			wait += time.Duration(rand.Int63n(spread))
+		}
+	}
+	return capBackoff(wait, b.Cap), retry <= b.MaxRetries
+}
+
+func capBackoff(wait, max time.Duration) time.Duration {
+	if max > 0 && wait > max {
+		return max
+	}
+	return wait
+}
+
+// 5. Circuit breaker aware: retries are not attempted while the breaker is open, and waits last at least until it half-opens
+type CircuitBreakerPolicy struct {
+	Policy  RetryPolicy
+	Breaker *CircuitBreaker
+}
+
+func (b CircuitBreakerPolicy) Backoff(retry int, previous time.Duration) (time.Duration, bool) {
+	wait, ok := b.Policy.Backoff(retry, previous)
+	if !ok {
+		return 0, false
+	}
+	if cooldown := b.Breaker.remainingCooldown(); cooldown > wait {
+		wait = cooldown
+	}
+	return wait, true
+}
+
+func (b CircuitBreakerPolicy) allow() error {
+	return b.Breaker.Allow()
+}
+
+func (b CircuitBreakerPolicy) record(err error) {
+	b.Breaker.Record(err)
+}
+
+func (b CircuitBreakerPolicy) release() {
+	b.Breaker.Release()
+}
+
+// attemptGate is implemented by policies that decide whether an attempt may run at all, and learn its result
+type attemptGate interface {
+	allow() error
+	record(err error)
+	release() // the attempt tells nothing about the health of the service
+}
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// CircuitBreaker opens after Threshold consecutive failures, rejecting every call for Cooldown.
+// Then it half-opens: the next call is a trial, closing the breaker when it succeeds and opening it again when it fails.
+// The other calls are rejected until the trial is recorded.
+type CircuitBreaker struct {
+	mu            sync.Mutex
+	threshold     int
+	cooldown      time.Duration
+	clock         RetryClock
+	state         CircuitState
+	failures      int
+	openedAt      time.Time
+	trialInFlight bool
+}
+
+// NewCircuitBreaker uses the real clock when clock is nil
+func NewCircuitBreaker(threshold int, cooldown time.Duration, clock RetryClock) *CircuitBreaker {
+	if clock == nil {
+		clock = realClock{}
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown, clock: clock}
+}
+
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.halfOpenAfterCooldown()
+	return b.state
+}
+
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.halfOpenAfterCooldown()
+	switch {
+	case b.state == CircuitOpen:
+		return ErrCircuitOpen
+	case b.state == CircuitHalfOpen && b.trialInFlight:
+		return ErrCircuitOpen
+	case b.state == CircuitHalfOpen:
+		b.trialInFlight = true
+	}
+	return nil
+}
+
+// Record counts the outcome of a call. Outcomes recorded while the breaker is open come from calls started before it opened:
+// they change nothing, so that late failures do not extend the cooldown.
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen {
+		return
+	}
+	b.trialInFlight = false
+	if err == nil {
+		b.state, b.failures = CircuitClosed, 0
+		return
+	}
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		b.state, b.openedAt = CircuitOpen, b.clock.Now()
+	}
+}
+
+// Release ends an attempt without counting it either way, such as a call rejected for a bad request.
+// A trial released while half-open lets the next call be the trial.
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trialInFlight = false
+}
+
+func (b *CircuitBreaker) halfOpenAfterCooldown() {
+	if b.state == CircuitOpen && !b.clock.Now().Before(b.openedAt.Add(b.cooldown)) {
+		b.state = CircuitHalfOpen
+	}
+}
+
+func (b *CircuitBreaker) remainingCooldown() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != CircuitOpen {
+		return 0
+	}
+	return b.openedAt.Add(b.cooldown).Sub(b.clock.Now())
+}
+
+// RetryClock lets tests control time
+type RetryClock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// RetryError tells why the Retrier gave up, and wraps the error of the last attempt
+type RetryError struct {
+	Attempts int
+	Cause    error // ErrRetriesExhausted, ErrCircuitOpen, or the error of the context
+	Err      error // nil when no attempt was made
+}
+
+func (e *RetryError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("gave up after %d attempts: %v", e.Attempts, e.Cause)
+	}
+	return fmt.Sprintf("gave up after %d attempts: %v: %v", e.Attempts, e.Cause, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+func (e *RetryError) Is(target error) bool {
+	return target == e.Cause
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks an error that retrying cannot fix: the Retrier returns it at once
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+type Retrier struct {
+	Policy RetryPolicy
+	Clock  RetryClock // the real clock when nil
+}
+
+func NewRetrier(policy RetryPolicy) *Retrier {
+	return &Retrier{Policy: policy}
+}
+
+// Do runs fn until it succeeds, the policy gives up or the context ends.
+// It does not start a wait that would end after the deadline of the context.
+func (r *Retrier) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	clock := r.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+	gate, _ := r.Policy.(attemptGate)
+
+	var last error
+	var previous time.Duration
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return &RetryError{Attempts: attempt - 1, Cause: err, Err: last}
+		}
+		if gate != nil {
+			if err := gate.allow(); err != nil {
+				return &RetryError{Attempts: attempt - 1, Cause: err, Err: last}
+			}
+		}
+		err := fn(ctx)
+		// permanent errors are errors of the caller, such as a bad request: they must not trip a breaker shared with other callers
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			if gate != nil {
+				gate.release()
+			}
+			return permanent.err
+		}
+		if gate != nil {
+			gate.record(err)
+		}
+		if err == nil {
+			return nil
+		}
+		last = err
+
+		wait, ok := r.Policy.Backoff(attempt, previous)
+		if !ok {
+			return &RetryError{Attempts: attempt, Cause: ErrRetriesExhausted, Err: last}
+		}
+		if deadline, ok := ctx.Deadline(); ok && clock.Now().Add(wait).After(deadline) {
+			return &RetryError{Attempts: attempt, Cause: context.DeadlineExceeded, Err: last}
+		}
+		select {
+		case <-ctx.Done():
+			return &RetryError{Attempts: attempt, Cause: ctx.Err(), Err: last}
+		case <-clock.After(wait):
+		}
+		previous = wait
+	}
+}
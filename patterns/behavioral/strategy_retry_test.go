@@ -0,0 +1,274 @@
+package behavioral_test
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/fabricioandreis/design-patterns-go/patterns/behavioral"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock moves forward by the whole wait as soon as someone waits
+type fakeClock struct {
+	now   time.Time
+	waits []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Now()}
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.waits = append(c.waits, d)
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+var errUnavailable = errors.New("unavailable")
+
+// failing fails the first n calls
+func failing(n int, calls *int) func(context.Context) error {
+	return func(context.Context) error {
+		*calls++
+		if *calls <= n {
+			return errUnavailable
+		}
+		return nil
+	}
+}
+
+func TestRetryPolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy behavioral.RetryPolicy
+		waits  []time.Duration
+	}{
+		{
+			name:   "constant",
+			policy: behavioral.ConstantBackoff{Delay: time.Second, MaxRetries: 4},
+			waits:  []time.Duration{time.Second, time.Second, time.Second, time.Second},
+		},
+		{
+			name:   "linear",
+			policy: behavioral.LinearBackoff{Initial: time.Second, Increment: 2 * time.Second, Max: 6 * time.Second, MaxRetries: 4},
+			waits:  []time.Duration{time.Second, 3 * time.Second, 5 * time.Second, 6 * time.Second},
+		},
+		{
+			name:   "exponential",
+			policy: behavioral.ExponentialBackoff{Initial: 100 * time.Millisecond, Max: time.Second, MaxRetries: 4},
+			waits:  []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond},
+		},
+		{
+			name:   "exponential with multiplier",
+			policy: behavioral.ExponentialBackoff{Initial: 100 * time.Millisecond, Multiplier: 3, Max: time.Second, MaxRetries: 4},
+			waits:  []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond, time.Second},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := newFakeClock()
+			r := behavioral.Retrier{Policy: test.policy, Clock: clock}
+			calls := 0
+
+			err := r.Do(context.Background(), failing(10, &calls))
+
+			assert.ErrorIs(t, err, behavioral.ErrRetriesExhausted)
+			assert.ErrorIs(t, err, errUnavailable)
+			assert.Equal(t, 5, err.(*behavioral.RetryError).Attempts)
+			assert.Equal(t, 5, calls)
+			assert.Equal(t, test.waits, clock.waits)
+		})
+	}
+
+	t.Run("decorrelated jitter without a base", func(t *testing.T) {
+		policy := behavioral.DecorrelatedJitter{MaxRetries: 3, Rand: rand.New(rand.NewSource(1))}
+		wait, _ := policy.Backoff(1, 0)
+
+		assert.GreaterOrEqual(t, wait, behavioral.DefaultJitterBase)
+	})
+
+	t.Run("decorrelated jitter", func(t *testing.T) {
+		policy := behavioral.DecorrelatedJitter{Base: 100 * time.Millisecond, Cap: 2 * time.Second, MaxRetries: 50, Rand: rand.New(rand.NewSource(1))}
+		clock := newFakeClock()
+		r := behavioral.Retrier{Policy: policy, Clock: clock}
+		calls := 0
+
+		assert.Error(t, r.Do(context.Background(), failing(100, &calls)))
+
+		assert.Len(t, clock.waits, 50)
+		distinct := map[time.Duration]bool{}
+		previous := policy.Base
+		for _, wait := range clock.waits {
+			assert.GreaterOrEqual(t, wait, policy.Base)
+			assert.LessOrEqual(t, wait, policy.Cap)
+			assert.Less(t, wait, 3*previous)
+			distinct[wait] = true
+			previous = wait
+		}
+		assert.Greater(t, len(distinct), 40)
+	})
+}
+
+func TestRetrier(t *testing.T) {
+	t.Run("Should stop retrying once the operation succeeds", func(t *testing.T) {
+		clock := newFakeClock()
+		r := behavioral.Retrier{Policy: behavioral.ConstantBackoff{Delay: time.Second, MaxRetries: 5}, Clock: clock}
+		calls := 0
+
+		assert.NoError(t, r.Do(context.Background(), failing(2, &calls)))
+		assert.Equal(t, 3, calls)
+		assert.Len(t, clock.waits, 2)
+	})
+
+	t.Run("Should not retry permanent errors", func(t *testing.T) {
+		r := behavioral.Retrier{Policy: behavioral.ConstantBackoff{Delay: time.Second, MaxRetries: 5}, Clock: newFakeClock()}
+		calls := 0
+		errBadRequest := errors.New("bad request")
+
+		err := r.Do(context.Background(), func(context.Context) error {
+			calls++
+			return behavioral.Permanent(errBadRequest)
+		})
+
+		assert.Equal(t, errBadRequest, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Should not wait beyond the deadline of the context", func(t *testing.T) {
+		clock := newFakeClock()
+		r := behavioral.Retrier{Policy: behavioral.ExponentialBackoff{Initial: time.Minute, MaxRetries: 10}, Clock: clock}
+		ctx, cancel := context.WithDeadline(context.Background(), clock.Now().Add(5*time.Minute))
+		defer cancel()
+		calls := 0
+
+		err := r.Do(ctx, failing(10, &calls))
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorIs(t, err, errUnavailable)
+		assert.Equal(t, []time.Duration{time.Minute, 2 * time.Minute}, clock.waits) // the next wait of 4 minutes would end too late
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("Should stop when the context is cancelled", func(t *testing.T) {
+		r := behavioral.Retrier{Policy: behavioral.ConstantBackoff{Delay: time.Second, MaxRetries: 10}, Clock: newFakeClock()}
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+
+		err := r.Do(ctx, func(context.Context) error {
+			calls++
+			if calls == 2 {
+				cancel()
+			}
+			return errUnavailable
+		})
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Should wait for the circuit breaker to half-open", func(t *testing.T) {
+		clock := newFakeClock()
+		breaker := behavioral.NewCircuitBreaker(3, time.Minute, clock)
+		r := behavioral.Retrier{
+			Policy: behavioral.CircuitBreakerPolicy{
+				Policy:  behavioral.ConstantBackoff{Delay: time.Second, MaxRetries: 10},
+				Breaker: breaker,
+			},
+			Clock: clock,
+		}
+		calls := 0
+
+		assert.NoError(t, r.Do(context.Background(), failing(4, &calls)))
+		// the third failure opens the breaker, the trial after the cooldown fails and opens it again
+		assert.Equal(t, []time.Duration{time.Second, time.Second, time.Minute, time.Minute}, clock.waits)
+		assert.Equal(t, 5, calls)
+		assert.Equal(t, behavioral.CircuitClosed, breaker.State())
+	})
+
+	t.Run("Should not call while the circuit breaker is open", func(t *testing.T) {
+		clock := newFakeClock()
+		breaker := behavioral.NewCircuitBreaker(1, time.Minute, clock)
+		breaker.Record(errUnavailable)
+		r := behavioral.Retrier{
+			Policy: behavioral.CircuitBreakerPolicy{Policy: behavioral.ConstantBackoff{MaxRetries: 3}, Breaker: breaker},
+			Clock:  clock,
+		}
+		calls := 0
+
+		err := r.Do(context.Background(), failing(0, &calls))
+
+		assert.ErrorIs(t, err, behavioral.ErrCircuitOpen)
+		assert.Equal(t, 0, calls)
+		assert.Equal(t, behavioral.CircuitOpen, breaker.State())
+
+		clock.now = clock.now.Add(time.Minute)
+		assert.Equal(t, behavioral.CircuitHalfOpen, breaker.State())
+		assert.NoError(t, r.Do(context.Background(), failing(0, &calls)))
+		assert.Equal(t, behavioral.CircuitClosed, breaker.State())
+	})
+
+	t.Run("Should let a single trial through while the circuit breaker is half-open", func(t *testing.T) {
+		clock := newFakeClock()
+		breaker := behavioral.NewCircuitBreaker(1, time.Minute, clock)
+		breaker.Record(errUnavailable)
+		clock.now = clock.now.Add(time.Minute)
+
+		assert.NoError(t, breaker.Allow())
+		assert.ErrorIs(t, breaker.Allow(), behavioral.ErrCircuitOpen)
+		breaker.Record(errUnavailable)
+		assert.Equal(t, behavioral.CircuitOpen, breaker.State())
+
+		clock.now = clock.now.Add(time.Minute)
+		assert.NoError(t, breaker.Allow())
+		assert.ErrorIs(t, breaker.Allow(), behavioral.ErrCircuitOpen)
+		breaker.Record(nil)
+		assert.Equal(t, behavioral.CircuitClosed, breaker.State())
+		assert.NoError(t, breaker.Allow())
+		assert.NoError(t, breaker.Allow())
+	})
+
+	t.Run("Should never trip the circuit breaker with permanent errors", func(t *testing.T) {
+		clock := newFakeClock()
+		breaker := behavioral.NewCircuitBreaker(2, time.Minute, clock)
+		r := behavioral.Retrier{
+			Policy: behavioral.CircuitBreakerPolicy{Policy: behavioral.ConstantBackoff{MaxRetries: 3}, Breaker: breaker},
+			Clock:  clock,
+		}
+		badRequest := errors.New("bad request")
+
+		for i := 0; i < 5; i++ {
+			err := r.Do(context.Background(), func(ctx context.Context) error { return behavioral.Permanent(badRequest) })
+			assert.Equal(t, badRequest, err)
+		}
+		assert.Equal(t, behavioral.CircuitClosed, breaker.State())
+
+		breaker.Record(errUnavailable)
+		breaker.Record(errUnavailable)
+		clock.now = clock.now.Add(time.Minute)
+		assert.Equal(t, badRequest, r.Do(context.Background(), func(ctx context.Context) error { return behavioral.Permanent(badRequest) }))
+		assert.Equal(t, behavioral.CircuitHalfOpen, breaker.State(), "a permanent error is no verdict on the trial")
+		assert.NoError(t, breaker.Allow(), "the next call is the trial")
+	})
+
+	t.Run("Should not extend the cooldown with failures of calls started before the breaker opened", func(t *testing.T) {
+		clock := newFakeClock()
+		breaker := behavioral.NewCircuitBreaker(1, time.Minute, clock)
+		breaker.Record(errUnavailable)
+
+		clock.now = clock.now.Add(30 * time.Second)
+		breaker.Record(errUnavailable)
+		clock.now = clock.now.Add(30 * time.Second)
+
+		assert.Equal(t, behavioral.CircuitHalfOpen, breaker.State())
+	})
+}
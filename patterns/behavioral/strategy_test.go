@@ -1,6 +1,7 @@
 package behavioral_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/fabricioandreis/design-patterns-go/patterns/behavioral"
@@ -21,7 +22,8 @@ import (
 
 func TestStrategy(t *testing.T) {
 	t.Run("Should be able to switch strategies at runtime", func(t *testing.T) {
-		tp := behavioral.NewTextProcessor(behavioral.Html)
+		tp, err := behavioral.NewTextProcessor(behavioral.Html)
+		assert.NoError(t, err)
 
 		input := [][]string{
 			{"item 1", "item 2"},
@@ -44,11 +46,186 @@ func TestStrategy(t *testing.T) {
 
 		for i, input := range input {
 			tp.Reset()
-			tp.SetOutputFormat(strategy[i])
+			assert.NoError(t, tp.SetOutputFormat(strategy[i]))
 			tp.AppendList(input)
 			result := tp.String()
 
 			assert.Equal(t, output[i], result)
 		}
 	})
+	nested := behavioral.List{Items: []behavioral.ListItem{
+		{Text: "fruits", Sublist: &behavioral.List{Ordered: true, Items: []behavioral.ListItem{
+			{Text: "apple"},
+			{Text: "pear", Sublist: &behavioral.List{Items: []behavioral.ListItem{{Text: "williams"}}}},
+		}}},
+		{Text: "vegetables"},
+	}}
+
+	t.Run("Should write nested and ordered lists in every format", func(t *testing.T) {
+		output := map[behavioral.OutputFormat]string{
+			behavioral.Markdown: "" +
+				" * fruits\n" +
+				"    1. apple\n" +
+				"    2. pear\n" +
+				"        * williams\n" +
+				" * vegetables\n",
+			behavioral.Html: "" +
+				"<ul>\n" +
+				"  <li>fruits\n" +
+				"    <ol>\n" +
+				"      <li>apple</li>\n" +
+				"      <li>pear\n" +
+				"        <ul>\n" +
+				"          <li>williams</li>\n" +
+				"        </ul>\n" +
+				"      </li>\n" +
+				"    </ol>\n" +
+				"  </li>\n" +
+				"  <li>vegetables</li>\n" +
+				"</ul>\n",
+			behavioral.AsciiDoc: "" +
+				"* fruits\n" +
+				".. apple\n" +
+				".. pear\n" +
+				"*** williams\n" +
+				"* vegetables\n",
+			behavioral.ReStructuredText: "" +
+				"- fruits\n" +
+				"\n" +
+				"  1. apple\n" +
+				"  2. pear\n" +
+				"\n" +
+				"     - williams\n" +
+				"\n" +
+				"\n" +
+				"- vegetables\n",
+			behavioral.LaTeX: "" +
+				"\\begin{itemize}\n" +
+				"  \\item fruits\n" +
+				"  \\begin{enumerate}\n" +
+				"    \\item apple\n" +
+				"    \\item pear\n" +
+				"    \\begin{itemize}\n" +
+				"      \\item williams\n" +
+				"    \\end{itemize}\n" +
+				"  \\end{enumerate}\n" +
+				"  \\item vegetables\n" +
+				"\\end{itemize}\n",
+			behavioral.PlainText: "" +
+				"- fruits\n" +
+				"  1. apple\n" +
+				"  2. pear\n" +
+				"     - williams\n" +
+				"- vegetables\n",
+		}
+
+		for format, expected := range output {
+			tp, err := behavioral.NewTextProcessor(format)
+			assert.NoError(t, err)
+			tp.AppendNestedList(nested)
+
+			assert.Equal(t, expected, tp.String(), format)
+		}
+	})
+
+	t.Run("Should write flat ordered lists", func(t *testing.T) {
+		tp, _ := behavioral.NewTextProcessor(behavioral.Markdown)
+		tp.AppendOrderedList([]string{"first", "second"})
+
+		assert.Equal(t, " 1. first\n 2. second\n", tp.String())
+	})
+
+	t.Run("Should escape items", func(t *testing.T) {
+		tp, _ := behavioral.NewTextProcessor(behavioral.Html)
+		tp.AppendList([]string{`<script>alert("x")</script> & co`})
+		assert.Equal(t, "<ul>\n  <li>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &amp; co</li>\n</ul>\n", tp.String())
+
+		tp.Reset()
+		assert.NoError(t, tp.SetOutputFormat(behavioral.LaTeX))
+		tp.AppendList([]string{`50% of $x_1 & {y} #1 \ ~^`})
+		assert.Equal(t, "\\begin{itemize}\n  \\item 50\\% of \\$x\\_1 \\& \\{y\\} \\#1 \\textbackslash{} \\textasciitilde{}\\textasciicircum{}\n\\end{itemize}\n", tp.String())
+
+		tp.Reset()
+		tp.AppendList([]string{"[draft] notes", "see [1]"})
+		assert.Equal(t, "\\begin{itemize}\n  \\item {[}draft] notes\n  \\item see [1]\n\\end{itemize}\n", tp.String())
+
+		special := []string{"*bold* _it_ `code` [link](x) #1 a|b \\ back", "1. x", "- dash\nsecond line\n(ii) next", "plain"}
+		output := map[behavioral.OutputFormat]string{
+			behavioral.Markdown: "" +
+				" * \\*bold\\* \\_it\\_ \\`code\\` \\[link\\](x) \\#1 a\\|b \\\\ back\n" +
+				" * 1\\. x\n" +
+				" * \\- dash\n" +
+				"   second line\n" +
+				"   (ii\\) next\n" +
+				" * plain\n",
+			behavioral.AsciiDoc: "" +
+				"* pass:c[*bold* _it_ `code` [link\\](x) #1 a|b \\ back]\n" +
+				"* 1. x\n" +
+				"* - dash second line (ii) next\n" +
+				"* plain\n",
+			behavioral.ReStructuredText: "" +
+				"- \\*bold\\* \\_it\\_ \\`code\\` \\[link\\](x) #1 a\\|b \\\\ back\n" +
+				"- 1\\. x\n" +
+				"- \\- dash\n" +
+				"  second line\n" +
+				"  (ii\\) next\n" +
+				"- plain\n",
+			behavioral.PlainText: "" +
+				"- *bold* _it_ `code` [link](x) #1 a|b \\ back\n" +
+				"- 1. x\n" +
+				"- - dash\n" +
+				"  second line\n" +
+				"  (ii) next\n" +
+				"- plain\n",
+		}
+		for format, expected := range output {
+			tp, _ := behavioral.NewTextProcessor(format)
+			tp.AppendList(special)
+
+			assert.Equal(t, expected, tp.String(), format)
+		}
+	})
+
+	t.Run("Should fail on unknown formats", func(t *testing.T) {
+		_, err := behavioral.NewTextProcessor("docx")
+		assert.ErrorIs(t, err, behavioral.ErrUnknownOutputFormat)
+
+		tp, _ := behavioral.NewTextProcessor(behavioral.Markdown)
+		assert.ErrorIs(t, tp.SetOutputFormat("docx"), behavioral.ErrUnknownOutputFormat)
+		tp.AppendList([]string{"item"})
+		assert.Equal(t, " * item\n", tp.String(), "the previous format is kept")
+	})
+
+	t.Run("Should use formats registered by other packages", func(t *testing.T) {
+		behavioral.RegisterOutputFormat("csv", func() behavioral.ListStrategy { return &csvListStrategy{} })
+
+		assert.Contains(t, behavioral.OutputFormats(), behavioral.OutputFormat("csv"))
+		assert.Panics(t, func() {
+			behavioral.RegisterOutputFormat("csv", func() behavioral.ListStrategy { return &csvListStrategy{} })
+		})
+
+		tp, err := behavioral.NewTextProcessor("csv")
+		assert.NoError(t, err)
+		tp.AppendList([]string{"a", "b", "c"})
+		assert.Equal(t, "a,b,c\n", tp.String())
+	})
+}
+
+type csvListStrategy struct{}
+
+func (c *csvListStrategy) Start(builder *strings.Builder, ordered bool) {
+}
+
+func (c *csvListStrategy) End(builder *strings.Builder) {
+	builder.WriteString("\n")
+}
+
+func (c *csvListStrategy) StartItem(builder *strings.Builder, item behavioral.ListItem, number int) {
+	if number > 1 {
+		builder.WriteString(",")
+	}
+	builder.WriteString(item.Text)
+}
+
+func (c *csvListStrategy) EndItem(builder *strings.Builder, item behavioral.ListItem) {
 }
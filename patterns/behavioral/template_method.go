@@ -1,74 +1,240 @@
 package behavioral
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
 
 // Template Method is a behavioral design pattern that defines the skeleton of an algorithm in the superclass but lets subclasses override specific steps of the algorithm without changing its structure.
 
 // https://refactoring.guru/design-patterns/template-method
 
 // 1. Common Template Method
-type Game interface {
-	Start()
-	TakeTurn()
-	HaveWinner() bool
-	WinningPlayer() int
-	Timeline() []string
+// The Tournament runs any turn-based Game: the game knows its rules, the players choose the moves,
+// and the tournament plays the turns in order, enforcing the limits and telling the hooks about every turn.
+
+const NoWinner = -1
+
+type Game[M comparable] interface {
+	Name() string
+	Players() int
+	Start()             // sets up a new game
+	CurrentPlayer() int // from 0 to Players()-1
+	LegalMoves() []M
+	Play(move M)                      // plays a legal move for the current player
+	Outcome() (over bool, winner int) // winner is NoWinner for a draw
+	Clone() Game[M]
 }
 
-type Tournament struct {
-	Game
+// Player is a strategy choosing moves. It gets a copy of the game, which it may play on freely.
+type Player[M comparable] interface {
+	ChooseMove(g Game[M]) M
 }
 
-func NewTournament(g Game) *Tournament {
-	return &Tournament{g}
+var (
+	ErrIllegalMove  = errors.New("illegal move")
+	ErrNoLegalMoves = errors.New("no legal moves in a game that is not over")
+)
+
+type GameEnd int
+
+const (
+	GameOver      GameEnd = iota // the rules ended the game
+	GameMaxTurns                 // the game reached the maximum number of turns
+	GameTimeLimit                // the game ran out of time
+)
+
+func (e GameEnd) String() string {
+	switch e {
+	case GameOver:
+		return "game over"
+	case GameMaxTurns:
+		return "maximum turns reached"
+	case GameTimeLimit:
+		return "time limit reached"
+	}
+	return fmt.Sprintf("GameEnd(%d)", int(e))
+}
+
+type TurnEvent[M comparable] struct {
+	Turn     int // from 1
+	Player   int
+	Move     M
+	Duration time.Duration // time the player took to choose the move
+}
+
+type GameResult[M comparable] struct {
+	Game     string
+	End      GameEnd
+	Winner   int // NoWinner for a draw, and when a limit stopped the game
+	Turns    int
+	Moves    []M
+	Timeline []string
+	Duration time.Duration
 }
 
-func (t *Tournament) PlayGame() {
-	t.Game.Start()
-	for !t.Game.HaveWinner() {
-		t.Game.TakeTurn()
+func (r GameResult[M]) Draw() bool {
+	return r.Winner == NoWinner
+}
+
+type Tournament[M comparable] struct {
+	game      Game[M]
+	players   []Player[M]
+	maxTurns  int
+	timeLimit time.Duration
+	hooks     []func(TurnEvent[M])
+	now       func() time.Time
+}
+
+// NewTournament seats the players in turn order. Missing players play random moves.
+func NewTournament[M comparable](g Game[M], players ...Player[M]) *Tournament[M] {
+	t := &Tournament[M]{game: g, players: players, now: time.Now}
+	for len(t.players) < g.Players() {
+		t.players = append(t.players, RandomPlayer[M]{})
 	}
-	fmt.Printf("Player %d wins.\n", t.Game.WinningPlayer())
+	return t
 }
 
-func (t *Tournament) Timeline() []string {
-	return t.Game.Timeline()
+// SetMaxTurns stops the game without a winner after max turns. A non-positive max plays until the game is over.
+func (t *Tournament[M]) SetMaxTurns(max int) {
+	t.maxTurns = max
 }
 
+// SetTimeLimit stops the game without a winner once it has lasted longer than limit. The limit is checked between turns.
+func (t *Tournament[M]) SetTimeLimit(limit time.Duration) {
+	t.timeLimit = limit
+}
+
+// OnTurn registers a hook called after every turn
+func (t *Tournament[M]) OnTurn(hook func(TurnEvent[M])) {
+	t.hooks = append(t.hooks, hook)
+}
+
+func (t *Tournament[M]) PlayGame() (GameResult[M], error) {
+	g := t.game
+	start := t.now()
+	result := GameResult[M]{Game: g.Name(), Winner: NoWinner, Moves: []M{}}
+	result.Timeline = append(result.Timeline, fmt.Sprintf("Starting a new game of %s.", g.Name()))
+	g.Start()
+	for {
+		if over, winner := g.Outcome(); over {
+			result.End, result.Winner = GameOver, winner
+			break
+		}
+		if t.maxTurns > 0 && result.Turns >= t.maxTurns {
+			result.End = GameMaxTurns
+			break
+		}
+		if t.timeLimit > 0 && t.now().Sub(start) >= t.timeLimit {
+			result.End = GameTimeLimit
+			break
+		}
+
+		player := g.CurrentPlayer()
+		if len(g.LegalMoves()) == 0 {
+			return result, fmt.Errorf("%w: player %d in %s", ErrNoLegalMoves, player, g.Name())
+		}
+		turnStart := t.now()
+		move := t.players[player].ChooseMove(g.Clone())
+		if !isLegalMove(g, move) {
+			return result, fmt.Errorf("%w: player %d played %v in %s", ErrIllegalMove, player, move, g.Name())
+		}
+		g.Play(move)
+
+		result.Turns++
+		result.Moves = append(result.Moves, move)
+		result.Timeline = append(result.Timeline, fmt.Sprintf("Turn %d taken by player %d: %v", result.Turns, player, move))
+		event := TurnEvent[M]{Turn: result.Turns, Player: player, Move: move, Duration: t.now().Sub(turnStart)}
+		for _, hook := range t.hooks {
+			hook(event)
+		}
+	}
+
+	switch {
+	case result.End != GameOver:
+		result.Timeline = append(result.Timeline, fmt.Sprintf("Game stopped: %s", result.End))
+	case result.Draw():
+		result.Timeline = append(result.Timeline, "The game is a draw")
+	default:
+		result.Timeline = append(result.Timeline, fmt.Sprintf("Player %d won the game", result.Winner))
+	}
+	result.Duration = t.now().Sub(start)
+	return result, nil
+}
+
+func isLegalMove[M comparable](g Game[M], move M) bool {
+	for _, m := range g.LegalMoves() {
+		if m == move {
+			return true
+		}
+	}
+	return false
+}
+
+// A stub of chess, where the only move is to pass and the player to move on the tenth turn wins
 type chess struct {
 	turn, maxTurns, currentPlayer int
-	timeline                      []string
 }
 
-func NewGameOfChess() Game {
-	return &chess{
-		turn:          1,
-		maxTurns:      10,
-		currentPlayer: 0,
-	}
+func NewGameOfChess() Game[string] {
+	return &chess{maxTurns: 10}
+}
+
+func (c *chess) Name() string {
+	return "chess"
+}
+
+func (c *chess) Players() int {
+	return 2
 }
 
 func (c *chess) Start() {
-	c.timeline = append(c.timeline, "Starting a new game of chess.")
+	c.turn, c.currentPlayer = 1, 0
+}
+
+func (c *chess) CurrentPlayer() int {
+	return c.currentPlayer
+}
+
+func (c *chess) LegalMoves() []string {
+	return []string{"pass"}
 }
 
-func (c *chess) TakeTurn() {
+func (c *chess) Play(move string) {
 	c.turn++
-	c.timeline = append(c.timeline, fmt.Sprintf("Turn %d taken by player %d\n", c.turn, c.currentPlayer))
 	c.currentPlayer = 1 - c.currentPlayer
 }
 
-func (c *chess) HaveWinner() bool {
-	return c.turn == c.maxTurns
+func (c *chess) Outcome() (bool, int) {
+	if c.turn == c.maxTurns {
+		return true, c.currentPlayer
+	}
+	return false, NoWinner
 }
 
-func (c *chess) WinningPlayer() int {
-	c.timeline = append(c.timeline, fmt.Sprintf("Player %d won the game", c.currentPlayer))
-	return c.currentPlayer
+func (c *chess) Clone() Game[string] {
+	clone := *c
+	return &clone
 }
 
-func (c *chess) Timeline() []string {
-	return c.timeline
+// RandomPlayer plays any legal move. Called directly on a game without legal moves, it returns the zero move rather than panicking:
+// the Tournament never gets there, as it stops such games with ErrNoLegalMoves.
+type RandomPlayer[M comparable] struct {
+	Rand *rand.Rand // the global source when nil; a rand.Rand must not be shared between goroutines
+}
+
+func (p RandomPlayer[M]) ChooseMove(g Game[M]) M {
+	moves := g.LegalMoves()
+	if len(moves) == 0 {
+		var none M
+		return none
+	}
+	if p.Rand != nil {
+		return moves[p.Rand.Intn(len(moves))]
+	}
+	return moves[rand.Intn(len(moves))]
 }
 
 // 2. Function Template Method
@@ -76,10 +242,10 @@ func PlayGame(
 	start, takeTurn func(),
 	haveWinner func() bool,
 	winningPlayer func() int,
-) {
+) int {
 	start()
 	for !haveWinner() {
 		takeTurn()
 	}
-	fmt.Printf("Player %d wins.\n", winningPlayer())
+	return winningPlayer()
 }
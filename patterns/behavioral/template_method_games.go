@@ -0,0 +1,296 @@
+package behavioral
+
+import (
+	"math"
+	"strings"
+)
+
+// Games and players for the Tournament template
+
+// TicTacToe moves are the cells of the board, numbered from 0 to 8 row by row
+type TicTacToe struct {
+	board  [9]int8 // 0 for empty, player+1 otherwise
+	player int
+	moves  int
+	winner int
+}
+
+func NewTicTacToe() *TicTacToe {
+	g := &TicTacToe{}
+	g.Start()
+	return g
+}
+
+var ticTacToeLines = [8][3]int{
+	{0, 1, 2}, {3, 4, 5}, {6, 7, 8},
+	{0, 3, 6}, {1, 4, 7}, {2, 5, 8},
+	{0, 4, 8}, {2, 4, 6},
+}
+
+func (g *TicTacToe) Name() string {
+	return "tic-tac-toe"
+}
+
+func (g *TicTacToe) Players() int {
+	return 2
+}
+
+func (g *TicTacToe) Start() {
+	*g = TicTacToe{winner: NoWinner}
+}
+
+func (g *TicTacToe) CurrentPlayer() int {
+	return g.player
+}
+
+func (g *TicTacToe) LegalMoves() []int {
+	if over, _ := g.Outcome(); over {
+		return nil
+	}
+	moves := []int{}
+	for cell, mark := range g.board {
+		if mark == 0 {
+			moves = append(moves, cell)
+		}
+	}
+	return moves
+}
+
+func (g *TicTacToe) Play(cell int) {
+	g.board[cell] = int8(g.player + 1)
+	g.moves++
+	for _, line := range ticTacToeLines {
+		if g.board[line[0]] != 0 && g.board[line[0]] == g.board[line[1]] && g.board[line[1]] == g.board[line[2]] {
+			g.winner = g.player
+		}
+	}
+	g.player = 1 - g.player
+}
+
+func (g *TicTacToe) Outcome() (bool, int) {
+	return g.winner != NoWinner || g.moves == len(g.board), g.winner
+}
+
+func (g *TicTacToe) Clone() Game[int] {
+	clone := *g
+	return &clone
+}
+
+func (g *TicTacToe) String() string {
+	sb := strings.Builder{}
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			sb.WriteByte(".XO"[g.board[3*row+col]])
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// ConnectFour moves are the columns the pieces are dropped in, numbered from 0 to 6
+type ConnectFour struct {
+	board   [connectFourRows][connectFourColumns]int8 // 0 for empty, player+1 otherwise; row 0 is the bottom
+	heights [connectFourColumns]int
+	player  int
+	moves   int
+	winner  int
+}
+
+const (
+	connectFourRows    = 6
+	connectFourColumns = 7
+)
+
+func NewConnectFour() *ConnectFour {
+	g := &ConnectFour{}
+	g.Start()
+	return g
+}
+
+func (g *ConnectFour) Name() string {
+	return "connect four"
+}
+
+func (g *ConnectFour) Players() int {
+	return 2
+}
+
+func (g *ConnectFour) Start() {
+	*g = ConnectFour{winner: NoWinner}
+}
+
+func (g *ConnectFour) CurrentPlayer() int {
+	return g.player
+}
+
+// LegalMoves lists the columns from the center out, so that searches look at the strongest moves first
+func (g *ConnectFour) LegalMoves() []int {
+	if over, _ := g.Outcome(); over {
+		return nil
+	}
+	moves := []int{}
+	for _, col := range [connectFourColumns]int{3, 2, 4, 1, 5, 0, 6} {
+		if g.heights[col] < connectFourRows {
+			moves = append(moves, col)
+		}
+	}
+	return moves
+}
+
+func (g *ConnectFour) Play(col int) {
+	row := g.heights[col]
+	g.board[row][col] = int8(g.player + 1)
+	g.heights[col]++
+	g.moves++
+	if g.connects(row, col) {
+		g.winner = g.player
+	}
+	g.player = 1 - g.player
+}
+
+// connects tells whether the piece at row and col is part of four in a row
+func (g *ConnectFour) connects(row, col int) bool {
+	piece := g.board[row][col]
+	for _, d := range [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}} {
+		count := 1
+		for _, sign := range [2]int{1, -1} {
+			r, c := row+sign*d[0], col+sign*d[1]
+			for r >= 0 && r < connectFourRows && c >= 0 && c < connectFourColumns && g.board[r][c] == piece {
+				count++
+				r, c = r+sign*d[0], c+sign*d[1]
+			}
+		}
+		if count >= 4 {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *ConnectFour) Outcome() (bool, int) {
+	return g.winner != NoWinner || g.moves == connectFourRows*connectFourColumns, g.winner
+}
+
+func (g *ConnectFour) Clone() Game[int] {
+	clone := *g
+	return &clone
+}
+
+func (g *ConnectFour) String() string {
+	sb := strings.Builder{}
+	for row := connectFourRows - 1; row >= 0; row-- {
+		for col := 0; col < connectFourColumns; col++ {
+			sb.WriteByte(".XO"[g.board[row][col]])
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// ConnectFourHeuristic scores a game for a player by the rows of four that are still open to each side,
+// weighting those closer to completion
+func ConnectFourHeuristic(g Game[int], player int) float64 {
+	c, ok := g.(*ConnectFour)
+	if !ok {
+		return 0
+	}
+	weights := [4]float64{0, 1, 4, 16}
+	mine := int8(player + 1)
+	score := 0.0
+	for row := 0; row < connectFourRows; row++ {
+		for col := 0; col < connectFourColumns; col++ {
+			for _, d := range [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}} {
+				endRow, endCol := row+3*d[0], col+3*d[1]
+				if endRow >= connectFourRows || endCol < 0 || endCol >= connectFourColumns {
+					continue
+				}
+				own, others := 0, 0
+				for i := 0; i < 4; i++ {
+					switch piece := c.board[row+i*d[0]][col+i*d[1]]; {
+					case piece == mine:
+						own++
+					case piece != 0:
+						others++
+					}
+				}
+				if others == 0 && own < 4 {
+					score += weights[own]
+				} else if own == 0 && others < 4 {
+					score -= weights[others]
+				}
+			}
+		}
+	}
+	return score
+}
+
+// MinimaxPlayer searches the moves of every player with alpha-beta pruning, assuming that all the other players
+// play against it. Winning sooner and losing later score better. Like RandomPlayer, it returns the zero move without legal moves.
+type MinimaxPlayer[M comparable] struct {
+	Depth int // how many turns ahead to search, until the end of the game when not positive
+	// Evaluate scores a game that is not over yet when the search stops, in favour of player. Unfinished games score 0 when nil.
+	// Scores should stay well below minimaxWin.
+	Evaluate func(g Game[M], player int) float64
+}
+
+const minimaxWin = 1e6
+
+func (p MinimaxPlayer[M]) ChooseMove(g Game[M]) M {
+	me := g.CurrentPlayer()
+	moves := g.LegalMoves()
+	if len(moves) == 0 {
+		var none M
+		return none
+	}
+	best, bestScore := moves[0], math.Inf(-1)
+	for _, move := range moves {
+		next := g.Clone()
+		next.Play(move)
+		score := p.search(next, 1, me, bestScore, math.Inf(1))
+		if score > bestScore {
+			best, bestScore = move, score
+		}
+	}
+	return best
+}
+
+func (p MinimaxPlayer[M]) search(g Game[M], depth, me int, alpha, beta float64) float64 {
+	if over, winner := g.Outcome(); over {
+		switch winner {
+		case NoWinner:
+			return 0
+		case me:
+			return minimaxWin - float64(depth)
+		default:
+			return -minimaxWin + float64(depth)
+		}
+	}
+	if p.Depth > 0 && depth >= p.Depth {
+		if p.Evaluate == nil {
+			return 0
+		}
+		return p.Evaluate(g, me)
+	}
+
+	maximizing := g.CurrentPlayer() == me
+	best := math.Inf(1)
+	if maximizing {
+		best = math.Inf(-1)
+	}
+	for _, move := range g.LegalMoves() {
+		next := g.Clone()
+		next.Play(move)
+		score := p.search(next, depth+1, me, alpha, beta)
+		if maximizing {
+			best = math.Max(best, score)
+			alpha = math.Max(alpha, best)
+		} else {
+			best = math.Min(best, score)
+			beta = math.Min(beta, best)
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return best
+}
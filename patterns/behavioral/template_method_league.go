@@ -0,0 +1,536 @@
+package behavioral
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A League runs tournaments between competitors: every match is a new game played through the Tournament template.
+// The matches of a round are played in parallel by a pool of workers, while rounds are played in order,
+// since knockout and Swiss pairings depend on the results of the previous round.
+// Ratings carry over from one tournament to the next, like in a real league.
+
+var (
+	ErrNotEnoughCompetitors = errors.New("not enough competitors")
+	ErrNotHeadToHead        = errors.New("league games must have two players")
+	ErrDuplicateCompetitor  = errors.New("duplicate competitor")
+)
+
+const (
+	DefaultEloRating  = 1500.0
+	DefaultEloKFactor = 32.0
+)
+
+// Competitor creates a new player for every match, so that players need not be safe for concurrent use
+type Competitor[M comparable] struct {
+	Name      string
+	NewPlayer func() Player[M]
+}
+
+type Match[M comparable] struct {
+	Round      int // from 1
+	Home, Away string
+	Winner     string // empty for a draw
+	Result     GameResult[M]
+}
+
+type Standing struct {
+	Rank            int
+	Name            string
+	Played          int
+	Wins            int
+	Draws           int
+	Losses          int
+	Byes            int
+	Points          float64 // 1 for a win or a bye, 0.5 for a draw
+	Buchholz        float64 // sum of the points of the opponents met
+	SonnebornBerger float64 // sum of the points of the opponents beaten, and half of those drawn
+	RoundReached    int     // in knockouts, the last round played, one more for the champion
+	Rating          float64 // Elo rating after the tournament
+	RatingChange    float64
+}
+
+type LeagueResult[M comparable] struct {
+	Format    string
+	Rounds    int
+	Matches   []Match[M] // in round order, then in schedule order
+	Standings []Standing // ranked
+}
+
+func (r *LeagueResult[M]) Champion() string {
+	return r.Standings[0].Name
+}
+
+type League[M comparable] struct {
+	newGame     func() Game[M]
+	competitors []Competitor[M]
+	workers     int
+	maxTurns    int
+	timeLimit   time.Duration
+	kFactor     float64
+
+	mu      sync.Mutex
+	ratings map[string]float64
+}
+
+func NewLeague[M comparable](newGame func() Game[M], competitors ...Competitor[M]) (*League[M], error) {
+	if players := newGame().Players(); players != 2 {
+		return nil, fmt.Errorf("%w: got %d", ErrNotHeadToHead, players)
+	}
+	if len(competitors) < 2 {
+		return nil, fmt.Errorf("%w: got %d", ErrNotEnoughCompetitors, len(competitors))
+	}
+	l := &League[M]{newGame: newGame, competitors: competitors, workers: 1, kFactor: DefaultEloKFactor, ratings: map[string]float64{}}
+	for _, c := range competitors {
+		if _, ok := l.ratings[c.Name]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateCompetitor, c.Name)
+		}
+		l.ratings[c.Name] = DefaultEloRating
+	}
+	return l, nil
+}
+
+// SetWorkers sets how many matches are played at the same time
+func (l *League[M]) SetWorkers(n int) {
+	if n < 1 {
+		n = 1
+	}
+	l.workers = n
+}
+
+// SetMaxTurns and SetTimeLimit apply to every match, see Tournament
+func (l *League[M]) SetMaxTurns(max int) {
+	l.maxTurns = max
+}
+
+func (l *League[M]) SetTimeLimit(limit time.Duration) {
+	l.timeLimit = limit
+}
+
+func (l *League[M]) SetEloKFactor(k float64) {
+	l.kFactor = k
+}
+
+func (l *League[M]) Ratings() map[string]float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ratings := make(map[string]float64, len(l.ratings))
+	for name, r := range l.ratings {
+		ratings[name] = r
+	}
+	return ratings
+}
+
+// pairing seats two competitors, by index, home playing first
+type pairing struct {
+	home, away int
+}
+
+// playRound plays the matches of a round with the worker pool, returning them in schedule order
+func (l *League[M]) playRound(round int, pairings []pairing) ([]Match[M], error) {
+	matches := make([]Match[M], len(pairings))
+	errs := make([]error, len(pairings))
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < l.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				matches[i], errs[i] = l.play(round, pairings[i])
+			}
+		}()
+	}
+	for i := range pairings {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	// ratings are updated in schedule order, so that they do not depend on which match ended first
+	for _, m := range matches {
+		l.rate(m)
+	}
+	return matches, nil
+}
+
+func (l *League[M]) play(round int, p pairing) (Match[M], error) {
+	home, away := l.competitors[p.home], l.competitors[p.away]
+	t := NewTournament(l.newGame(), home.NewPlayer(), away.NewPlayer())
+	t.SetMaxTurns(l.maxTurns)
+	t.SetTimeLimit(l.timeLimit)
+	result, err := t.PlayGame()
+	if err != nil {
+		return Match[M]{}, fmt.Errorf("round %d, %s against %s: %w", round, home.Name, away.Name, err)
+	}
+	m := Match[M]{Round: round, Home: home.Name, Away: away.Name, Result: result}
+	switch result.Winner {
+	case 0:
+		m.Winner = home.Name
+	case 1:
+		m.Winner = away.Name
+	}
+	return m, nil
+}
+
+func (l *League[M]) rate(m Match[M]) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	home, away := l.ratings[m.Home], l.ratings[m.Away]
+	expected := 1 / (1 + math.Pow(10, (away-home)/400))
+	score := 0.5
+	switch m.Winner {
+	case m.Home:
+		score = 1
+	case m.Away:
+		score = 0
+	}
+	l.ratings[m.Home] = home + l.kFactor*(score-expected)
+	l.ratings[m.Away] = away - l.kFactor*(score-expected)
+}
+
+func (l *League[M]) rating(i int) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ratings[l.competitors[i].Name]
+}
+
+// RoundRobin has every competitor meet every other one, twice with swapped sides when double is set.
+// Rounds are scheduled with the circle method, so that everyone plays once per round.
+func (l *League[M]) RoundRobin(double bool) (*LeagueResult[M], error) {
+	before := l.Ratings()
+	n := len(l.competitors)
+	seats := make([]int, 0, n+1) // -1 is a bye
+	for i := 0; i < n; i++ {
+		seats = append(seats, i)
+	}
+	if n%2 == 1 {
+		seats = append(seats, -1)
+	}
+
+	schedule := [][]pairing{}
+	for round := 0; round < len(seats)-1; round++ {
+		pairings := []pairing{}
+		for i := 0; i < len(seats)/2; i++ {
+			p := pairing{home: seats[i], away: seats[len(seats)-1-i]}
+			if p.home == -1 || p.away == -1 {
+				continue
+			}
+			if (i == 0 && round%2 == 1) || (i > 0 && i%2 == 1) {
+				p.home, p.away = p.away, p.home // share out the home games
+			}
+			pairings = append(pairings, p)
+		}
+		schedule = append(schedule, pairings)
+		// keep the first seat, rotate the others
+		last := seats[len(seats)-1]
+		copy(seats[2:], seats[1:len(seats)-1])
+		seats[1] = last
+	}
+	if double {
+		for _, pairings := range schedule {
+			swapped := make([]pairing, len(pairings))
+			for i, p := range pairings {
+				swapped[i] = pairing{home: p.away, away: p.home}
+			}
+			schedule = append(schedule, swapped)
+		}
+	}
+
+	result := &LeagueResult[M]{Format: "round robin", Rounds: len(schedule)}
+	for round, pairings := range schedule {
+		matches, err := l.playRound(round+1, pairings)
+		if err != nil {
+			return nil, err
+		}
+		result.Matches = append(result.Matches, matches...)
+	}
+	result.Standings = l.standings(result.Matches, nil, before)
+	rank(result.Standings, nil)
+	return result, nil
+}
+
+// knockoutReplays is how many times a drawn knockout match is replayed, with swapped sides, before the higher rated competitor goes through
+const knockoutReplays = 2
+
+// SingleElimination seeds the competitors by rating, so that the best ones meet as late as possible.
+// When their number is not a power of two, the top seeds skip the first round.
+func (l *League[M]) SingleElimination() (*LeagueResult[M], error) {
+	before := l.Ratings()
+	seeds := l.byRating()
+	size := 1
+	for size < len(seeds) {
+		size *= 2
+	}
+	alive := make([]int, size) // competitor in each slot of the bracket, -1 for an empty slot
+	for i, seed := range bracketOrder(size) {
+		alive[i] = -1
+		if seed < len(seeds) {
+			alive[i] = seeds[seed]
+		}
+	}
+
+	result := &LeagueResult[M]{Format: "single elimination"}
+	reached := map[int]int{}
+	for round := 1; len(alive) > 1; round++ {
+		result.Rounds = round
+		next := make([]int, len(alive)/2)
+		pending := map[int]pairing{} // slot of next to the pairing deciding it
+		for i := range next {
+			a, b := alive[2*i], alive[2*i+1]
+			switch {
+			case a == -1:
+				next[i] = b
+			case b == -1:
+				next[i] = a
+			default:
+				pending[i] = pairing{home: a, away: b}
+				reached[a], reached[b] = round, round
+			}
+		}
+
+		for replay := 0; replay <= knockoutReplays && len(pending) > 0; replay++ {
+			slots := make([]int, 0, len(pending))
+			for slot := range pending {
+				slots = append(slots, slot)
+			}
+			sort.Ints(slots)
+			pairings := make([]pairing, len(slots))
+			for i, slot := range slots {
+				pairings[i] = pending[slot]
+			}
+
+			matches, err := l.playRound(round, pairings)
+			if err != nil {
+				return nil, err
+			}
+			result.Matches = append(result.Matches, matches...)
+			for i, m := range matches {
+				p := pairings[i]
+				switch {
+				case m.Winner == m.Home:
+					next[slots[i]] = p.home
+				case m.Winner == m.Away:
+					next[slots[i]] = p.away
+				case replay < knockoutReplays:
+					pending[slots[i]] = pairing{home: p.away, away: p.home}
+					continue
+				case l.rating(p.away) > l.rating(p.home):
+					next[slots[i]] = p.away
+				default:
+					next[slots[i]] = p.home
+				}
+				delete(pending, slots[i])
+			}
+		}
+		alive = next
+	}
+	if alive[0] != -1 {
+		reached[alive[0]] = result.Rounds + 1
+	}
+
+	result.Standings = l.standings(result.Matches, nil, before)
+	for i := range result.Standings {
+		result.Standings[i].RoundReached = reached[l.index(result.Standings[i].Name)]
+	}
+	rank(result.Standings, func(a, b Standing) bool {
+		if a.RoundReached != b.RoundReached {
+			return a.RoundReached > b.RoundReached
+		}
+		return a.Rating > b.Rating
+	})
+	return result, nil
+}
+
+// bracketOrder lists the seeds in bracket order, so that seed 0 and 1 can only meet in the final, 0 to 3 in the semi-finals, and so on
+func bracketOrder(size int) []int {
+	order := []int{0}
+	for len(order) < size {
+		next := make([]int, 0, 2*len(order))
+		for _, seed := range order {
+			next = append(next, seed, 2*len(order)-1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// Swiss pairs competitors with the same score in every round, never twice the same ones when it can be avoided.
+// With an odd number of competitors, the lowest ranked one that has not had a bye yet sits out the round and scores a point.
+// Rounds are enough to tell a single winner apart when not positive.
+func (l *League[M]) Swiss(rounds int) (*LeagueResult[M], error) {
+	before := l.Ratings()
+	n := len(l.competitors)
+	if rounds <= 0 {
+		for rounds = 0; 1<<rounds < n; rounds++ {
+		}
+	}
+
+	result := &LeagueResult[M]{Format: "swiss", Rounds: rounds}
+	byes := map[string]int{}
+	for round := 1; round <= rounds; round++ {
+		standings := l.standings(result.Matches, byes, before)
+		rank(standings, nil)
+		order := make([]int, len(standings))
+		for i, s := range standings {
+			order[i] = l.index(s.Name)
+		}
+
+		if len(order)%2 == 1 {
+			bye := len(order) - 1
+			for i := len(order) - 1; i >= 0; i-- {
+				if byes[l.competitors[order[i]].Name] == 0 {
+					bye = i
+					break
+				}
+			}
+			byes[l.competitors[order[bye]].Name]++
+			order = append(order[:bye], order[bye+1:]...)
+		}
+
+		met := map[[2]int]bool{}
+		homeGames := map[int]int{}
+		for _, m := range result.Matches {
+			h, a := l.index(m.Home), l.index(m.Away)
+			met[[2]int{h, a}], met[[2]int{a, h}] = true, true
+			homeGames[h]++
+		}
+		pairs, ok := swissPairs(order, met)
+		if !ok {
+			pairs, _ = swissPairs(order, map[[2]int]bool{})
+		}
+		for i, p := range pairs {
+			if homeGames[p.away] < homeGames[p.home] {
+				pairs[i] = pairing{home: p.away, away: p.home}
+			}
+		}
+
+		matches, err := l.playRound(round, pairs)
+		if err != nil {
+			return nil, err
+		}
+		result.Matches = append(result.Matches, matches...)
+	}
+
+	result.Standings = l.standings(result.Matches, byes, before)
+	rank(result.Standings, nil)
+	return result, nil
+}
+
+// swissPairs pairs the competitors in ranking order, each with the highest ranked one it has not met, backtracking when stuck
+func swissPairs(order []int, met map[[2]int]bool) ([]pairing, bool) {
+	if len(order) == 0 {
+		return []pairing{}, true
+	}
+	first := order[0]
+	for i := 1; i < len(order); i++ {
+		if met[[2]int{first, order[i]}] {
+			continue
+		}
+		rest := make([]int, 0, len(order)-2)
+		rest = append(rest, order[1:i]...)
+		rest = append(rest, order[i+1:]...)
+		if pairs, ok := swissPairs(rest, met); ok {
+			return append([]pairing{{home: first, away: order[i]}}, pairs...), true
+		}
+	}
+	return nil, false
+}
+
+func (l *League[M]) index(name string) int {
+	for i, c := range l.competitors {
+		if c.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// byRating lists the competitors from the highest rated, keeping their order among equals
+func (l *League[M]) byRating() []int {
+	ratings := l.Ratings()
+	order := make([]int, len(l.competitors))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return ratings[l.competitors[order[i]].Name] > ratings[l.competitors[order[j]].Name]
+	})
+	return order
+}
+
+// standings adds up the matches and byes, and the tie-breakers that depend on the points of the opponents
+func (l *League[M]) standings(matches []Match[M], byes map[string]int, before map[string]float64) []Standing {
+	ratings := l.Ratings()
+	byName := map[string]*Standing{}
+	standings := make([]Standing, len(l.competitors))
+	for i, c := range l.competitors {
+		standings[i] = Standing{Name: c.Name, Byes: byes[c.Name], Points: float64(byes[c.Name]), Rating: ratings[c.Name], RatingChange: ratings[c.Name] - before[c.Name]}
+		byName[c.Name] = &standings[i]
+	}
+
+	for _, m := range matches {
+		home, away := byName[m.Home], byName[m.Away]
+		home.Played++
+		away.Played++
+		switch m.Winner {
+		case m.Home:
+			home.Wins, away.Losses, home.Points = home.Wins+1, away.Losses+1, home.Points+1
+		case m.Away:
+			away.Wins, home.Losses, away.Points = away.Wins+1, home.Losses+1, away.Points+1
+		default:
+			home.Draws, away.Draws, home.Points, away.Points = home.Draws+1, away.Draws+1, home.Points+0.5, away.Points+0.5
+		}
+	}
+	for _, m := range matches {
+		home, away := byName[m.Home], byName[m.Away]
+		home.Buchholz += away.Points
+		away.Buchholz += home.Points
+		switch m.Winner {
+		case m.Home:
+			home.SonnebornBerger += away.Points
+		case m.Away:
+			away.SonnebornBerger += home.Points
+		default:
+			home.SonnebornBerger += away.Points / 2
+			away.SonnebornBerger += home.Points / 2
+		}
+	}
+	return standings
+}
+
+// rank sorts the standings by the format's own order when given, then by points and tie-breakers, and numbers them.
+// The rating is the last tie-breaker, before the name.
+func rank(standings []Standing, before func(a, b Standing) bool) {
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if before != nil && (before(a, b) || before(b, a)) {
+			return before(a, b)
+		}
+		switch {
+		case a.Points != b.Points:
+			return a.Points > b.Points
+		case a.Buchholz != b.Buchholz:
+			return a.Buchholz > b.Buchholz
+		case a.SonnebornBerger != b.SonnebornBerger:
+			return a.SonnebornBerger > b.SonnebornBerger
+		case a.Wins != b.Wins:
+			return a.Wins > b.Wins
+		case a.Rating != b.Rating:
+			return a.Rating > b.Rating
+		}
+		return a.Name < b.Name
+	})
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+}
@@ -0,0 +1,185 @@
+package behavioral_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/fabricioandreis/design-patterns-go/patterns/behavioral"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTicTacToe() behavioral.Game[int] {
+	return behavioral.NewTicTacToe()
+}
+
+func minimaxCompetitor(name string) behavioral.Competitor[int] {
+	return behavioral.Competitor[int]{Name: name, NewPlayer: func() behavioral.Player[int] { return behavioral.MinimaxPlayer[int]{} }}
+}
+
+func randomCompetitor(name string, seed int64) behavioral.Competitor[int] {
+	return behavioral.Competitor[int]{Name: name, NewPlayer: func() behavioral.Player[int] {
+		return behavioral.RandomPlayer[int]{Rand: rand.New(rand.NewSource(seed))}
+	}}
+}
+
+func randomCompetitors(n int) []behavioral.Competitor[int] {
+	competitors := []behavioral.Competitor[int]{}
+	for i := 0; i < n; i++ {
+		competitors = append(competitors, randomCompetitor(fmt.Sprintf("random %d", i), int64(i)))
+	}
+	return competitors
+}
+
+func assertRatingsAddUp(t *testing.T, l *behavioral.League[int], n int) {
+	total := 0.0
+	for _, r := range l.Ratings() {
+		total += r
+	}
+	assert.InDelta(t, float64(n)*behavioral.DefaultEloRating, total, 1e-6)
+}
+
+func TestLeague(t *testing.T) {
+	t.Run("Should reject leagues that cannot be played", func(t *testing.T) {
+		_, err := behavioral.NewLeague(newTicTacToe, minimaxCompetitor("alone"))
+		assert.ErrorIs(t, err, behavioral.ErrNotEnoughCompetitors)
+
+		_, err = behavioral.NewLeague(newTicTacToe, minimaxCompetitor("twin"), minimaxCompetitor("twin"))
+		assert.ErrorIs(t, err, behavioral.ErrDuplicateCompetitor)
+
+		_, err = behavioral.NewLeague(func() behavioral.Game[int] { return &race{players: 3, goal: 8} }, minimaxCompetitor("a"), minimaxCompetitor("b"))
+		assert.ErrorIs(t, err, behavioral.ErrNotHeadToHead)
+	})
+
+	t.Run("Should play everyone against everyone in a round robin", func(t *testing.T) {
+		competitors := append(randomCompetitors(4), minimaxCompetitor("minimax"))
+		l, _ := behavioral.NewLeague(newTicTacToe, competitors...)
+		l.SetWorkers(4)
+
+		result, err := l.RoundRobin(false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 5, result.Rounds)
+		assert.Len(t, result.Matches, 10)
+		met := map[[2]string]bool{}
+		homeGames := map[string]int{}
+		for _, m := range result.Matches {
+			assert.False(t, met[[2]string{m.Home, m.Away}] || met[[2]string{m.Away, m.Home}], "%s against %s twice", m.Home, m.Away)
+			met[[2]string{m.Home, m.Away}] = true
+			homeGames[m.Home]++
+		}
+		for _, s := range result.Standings {
+			assert.Equal(t, 4, s.Played, s.Name)
+			assert.Equal(t, s.Played, s.Wins+s.Draws+s.Losses, s.Name)
+			assert.Contains(t, []int{1, 2, 3}, homeGames[s.Name], s.Name)
+		}
+		assert.Equal(t, "minimax", result.Champion())
+		assert.Equal(t, 0, result.Standings[0].Losses)
+		assert.Greater(t, result.Standings[0].RatingChange, 0.0)
+		assertRatingsAddUp(t, l, 5)
+	})
+
+	t.Run("Should swap sides in a double round robin", func(t *testing.T) {
+		l, _ := behavioral.NewLeague(newTicTacToe, randomCompetitors(4)...)
+
+		result, err := l.RoundRobin(true)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 6, result.Rounds)
+		assert.Len(t, result.Matches, 12)
+		sides := map[[2]string]bool{}
+		for _, m := range result.Matches {
+			sides[[2]string{m.Home, m.Away}] = true
+		}
+		assert.Len(t, sides, 12)
+	})
+
+	t.Run("Should rank by points and tie-breakers", func(t *testing.T) {
+		l, _ := behavioral.NewLeague(newTicTacToe, randomCompetitors(6)...)
+
+		result, _ := l.RoundRobin(false)
+
+		for i, s := range result.Standings {
+			assert.Equal(t, i+1, s.Rank)
+			assert.Equal(t, float64(s.Wins)+float64(s.Draws)/2, s.Points)
+			if i > 0 {
+				previous := result.Standings[i-1]
+				assert.True(t, previous.Points > s.Points || (previous.Points == s.Points && previous.Buchholz >= s.Buchholz), "%+v before %+v", previous, s)
+			}
+		}
+	})
+
+	t.Run("Should not depend on the number of workers", func(t *testing.T) {
+		results := []*behavioral.LeagueResult[int]{}
+		for _, workers := range []int{1, 8} {
+			l, _ := behavioral.NewLeague(newTicTacToe, randomCompetitors(8)...)
+			l.SetWorkers(workers)
+			result, err := l.Swiss(0)
+			assert.NoError(t, err)
+			for i := range result.Matches {
+				result.Matches[i].Result.Duration = 0
+			}
+			results = append(results, result)
+		}
+
+		assert.Equal(t, results[0], results[1])
+	})
+
+	t.Run("Should seed a knockout so that the best competitors meet last", func(t *testing.T) {
+		competitors := append(randomCompetitors(4), minimaxCompetitor("minimax"))
+		l, _ := behavioral.NewLeague(newTicTacToe, competitors...)
+		l.RoundRobin(false) // rates the competitors
+
+		seeds := l.Ratings()
+		result, err := l.SingleElimination()
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, result.Rounds)
+		assert.Equal(t, "minimax", result.Champion())
+		assert.Equal(t, 4, result.Standings[0].RoundReached)
+		assert.Equal(t, 3, result.Standings[1].RoundReached)
+		firstRound := 0
+		for _, m := range result.Matches {
+			if m.Round == 1 {
+				firstRound++
+				assert.Less(t, seeds[m.Home], seeds["minimax"])
+			}
+		}
+		assert.Equal(t, 1, firstRound, "5 competitors in a bracket of 8: only the fourth and fifth seeds play the first round")
+		assertRatingsAddUp(t, l, 5)
+	})
+
+	t.Run("Should replay drawn knockout matches and then favour the higher rating", func(t *testing.T) {
+		l, _ := behavioral.NewLeague(newTicTacToe, minimaxCompetitor("a"), minimaxCompetitor("b"))
+
+		result, err := l.SingleElimination()
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Matches, 3, "perfect players always draw")
+		assert.Equal(t, result.Matches[0].Home, result.Matches[1].Away)
+		assert.Equal(t, "a", result.Champion(), "equal ratings favour the first seed")
+	})
+
+	t.Run("Should pair equal scores without rematches in a Swiss tournament", func(t *testing.T) {
+		l, _ := behavioral.NewLeague(newTicTacToe, randomCompetitors(7)...)
+
+		result, err := l.Swiss(4)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, result.Rounds)
+		assert.Len(t, result.Matches, 12)
+		met := map[[2]string]bool{}
+		for _, m := range result.Matches {
+			assert.False(t, met[[2]string{m.Home, m.Away}] || met[[2]string{m.Away, m.Home}], "%s against %s twice", m.Home, m.Away)
+			met[[2]string{m.Home, m.Away}] = true
+		}
+		byes := 0
+		for _, s := range result.Standings {
+			assert.LessOrEqual(t, s.Byes, 1, s.Name)
+			assert.Equal(t, 4, s.Played+s.Byes, s.Name)
+			byes += s.Byes
+		}
+		assert.Equal(t, 4, byes)
+		assertRatingsAddUp(t, l, 7)
+	})
+}
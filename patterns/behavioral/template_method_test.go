@@ -2,7 +2,9 @@ package behavioral_test
 
 import (
 	"fmt"
+	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/fabricioandreis/design-patterns-go/patterns/behavioral"
 	"github.com/stretchr/testify/assert"
@@ -13,9 +15,13 @@ func TestTemplateMethod(t *testing.T) {
 		g := behavioral.NewGameOfChess()
 		tour := behavioral.NewTournament(g)
 
-		tour.PlayGame()
+		result, err := tour.PlayGame()
 
-		assert.Len(t, tour.Timeline(), 11)
+		assert.NoError(t, err)
+		assert.Len(t, result.Timeline, 11)
+		assert.Equal(t, 1, result.Winner)
+		assert.Equal(t, 9, result.Turns)
+		assert.Equal(t, behavioral.GameOver, result.End)
 	})
 
 	t.Run("Should implement functional template method", func(t *testing.T) {
@@ -40,8 +46,202 @@ func TestTemplateMethod(t *testing.T) {
 			return currentPlayer
 		}
 
-		behavioral.PlayGame(start, takeTurn, haveWinner, winningPlayer)
+		winner := behavioral.PlayGame(start, takeTurn, haveWinner, winningPlayer)
 
 		assert.Len(t, timeline, 11)
+		assert.Equal(t, 1, winner)
 	})
+
+	t.Run("Should draw tic-tac-toe between perfect players", func(t *testing.T) {
+		tour := behavioral.NewTournament[int](behavioral.NewTicTacToe(), behavioral.MinimaxPlayer[int]{}, behavioral.MinimaxPlayer[int]{})
+
+		result, err := tour.PlayGame()
+
+		assert.NoError(t, err)
+		assert.True(t, result.Draw())
+		assert.Equal(t, behavioral.GameOver, result.End)
+		assert.Equal(t, 9, result.Turns)
+		assert.Equal(t, "The game is a draw", result.Timeline[len(result.Timeline)-1])
+	})
+
+	t.Run("Should never lose tic-tac-toe with minimax", func(t *testing.T) {
+		for seed := int64(0); seed < 20; seed++ {
+			random := behavioral.RandomPlayer[int]{Rand: rand.New(rand.NewSource(seed))}
+			players := []behavioral.Player[int]{behavioral.MinimaxPlayer[int]{}, random}
+			minimax := int(seed % 2)
+			if minimax == 1 {
+				players[0], players[1] = players[1], players[0]
+			}
+
+			result, err := behavioral.NewTournament(behavioral.Game[int](behavioral.NewTicTacToe()), players...).PlayGame()
+
+			assert.NoError(t, err)
+			assert.Contains(t, []int{minimax, behavioral.NoWinner}, result.Winner, "seed %d", seed)
+		}
+	})
+
+	t.Run("Should beat a random player at connect four with minimax", func(t *testing.T) {
+		minimax := behavioral.MinimaxPlayer[int]{Depth: 4, Evaluate: behavioral.ConnectFourHeuristic}
+		for seed := int64(0); seed < 4; seed++ {
+			random := behavioral.RandomPlayer[int]{Rand: rand.New(rand.NewSource(seed))}
+			tour := behavioral.NewTournament[int](behavioral.NewConnectFour(), random, minimax)
+
+			result, err := tour.PlayGame()
+
+			assert.NoError(t, err)
+			assert.Equal(t, 1, result.Winner, "seed %d", seed)
+		}
+	})
+
+	t.Run("Should take a win and block a loss at connect four", func(t *testing.T) {
+		minimax := behavioral.MinimaxPlayer[int]{Depth: 2, Evaluate: behavioral.ConnectFourHeuristic}
+
+		g := behavioral.NewConnectFour()
+		for _, col := range []int{0, 6, 1, 6, 2, 5} {
+			g.Play(col)
+		}
+		assert.Equal(t, 3, minimax.ChooseMove(g.Clone())) // completes the bottom row
+
+		g = behavioral.NewConnectFour()
+		for _, col := range []int{0, 6, 1, 6, 2} {
+			g.Play(col)
+		}
+		assert.Equal(t, 3, minimax.ChooseMove(g.Clone())) // stops the bottom row
+	})
+
+	t.Run("Should stop at the maximum number of turns", func(t *testing.T) {
+		tour := behavioral.NewTournament[int](behavioral.NewConnectFour())
+		tour.SetMaxTurns(5)
+
+		result, err := tour.PlayGame()
+
+		assert.NoError(t, err)
+		assert.Equal(t, behavioral.GameMaxTurns, result.End)
+		assert.Equal(t, behavioral.NoWinner, result.Winner)
+		assert.Equal(t, 5, result.Turns)
+		assert.Len(t, result.Moves, 5)
+	})
+
+	t.Run("Should stop at the time limit and call the turn hooks", func(t *testing.T) {
+		now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		tour := behavioral.NewTournament[int](behavioral.NewConnectFour())
+		behavioral.ExportSetTournamentClock(tour, func() time.Time { return now })
+		tour.SetTimeLimit(10 * time.Second)
+		events := []behavioral.TurnEvent[int]{}
+		tour.OnTurn(func(e behavioral.TurnEvent[int]) {
+			now = now.Add(3 * time.Second)
+			events = append(events, e)
+		})
+
+		result, err := tour.PlayGame()
+
+		assert.NoError(t, err)
+		assert.Equal(t, behavioral.GameTimeLimit, result.End)
+		assert.Equal(t, 4, result.Turns)
+		assert.Equal(t, 12*time.Second, result.Duration)
+		assert.Len(t, events, 4)
+		for i, e := range events {
+			assert.Equal(t, i+1, e.Turn)
+			assert.Equal(t, i%2, e.Player)
+			assert.Equal(t, result.Moves[i], e.Move)
+		}
+	})
+
+	t.Run("Should reject illegal moves", func(t *testing.T) {
+		g := behavioral.NewTicTacToe()
+		tour := behavioral.NewTournament[int](g, fixedPlayer(4), fixedPlayer(4))
+
+		_, err := tour.PlayGame()
+
+		assert.ErrorIs(t, err, behavioral.ErrIllegalMove)
+		assert.Equal(t, "...\n.X.\n...\n", g.String())
+	})
+
+	t.Run("Should support any number of players", func(t *testing.T) {
+		// minimax assumes that both other players play against it, and still finds the way to reach 8 first
+		tour := behavioral.NewTournament[int](&race{players: 3, goal: 8}, behavioral.MinimaxPlayer[int]{}, fixedPlayer(1), fixedPlayer(1))
+
+		result, err := tour.PlayGame()
+
+		assert.NoError(t, err)
+		assert.Equal(t, behavioral.GameOver, result.End)
+		assert.Equal(t, 0, result.Winner)
+		assert.Equal(t, 0, (result.Turns-1)%3, "player 0 played the last turn")
+	})
+
+	t.Run("Should stop games without legal moves that are not over", func(t *testing.T) {
+		g := stuck{&race{players: 2, goal: 8}}
+		assert.Equal(t, 0, behavioral.RandomPlayer[int]{}.ChooseMove(g), "no move to choose from")
+		assert.Equal(t, 0, behavioral.MinimaxPlayer[int]{}.ChooseMove(g), "no move to choose from")
+
+		tour := behavioral.NewTournament[int](g)
+		_, err := tour.PlayGame()
+
+		assert.ErrorIs(t, err, behavioral.ErrNoLegalMoves)
+	})
+}
+
+type fixedPlayer int
+
+func (p fixedPlayer) ChooseMove(g behavioral.Game[int]) int {
+	return int(p)
+}
+
+// race is a game where players add 1 to 3 to a count in turn, and the player reaching the goal wins
+type race struct {
+	players, goal, count, player, winner int
+}
+
+func (r *race) Name() string {
+	return "race"
+}
+
+func (r *race) Players() int {
+	return r.players
+}
+
+func (r *race) Start() {
+	r.count, r.player, r.winner = 0, 0, behavioral.NoWinner
+}
+
+func (r *race) CurrentPlayer() int {
+	return r.player
+}
+
+func (r *race) LegalMoves() []int {
+	moves := []int{}
+	for m := 1; m <= 3 && r.count+m <= r.goal; m++ {
+		moves = append(moves, m)
+	}
+	return moves
+}
+
+func (r *race) Play(move int) {
+	r.count += move
+	if r.count == r.goal {
+		r.winner = r.player
+	}
+	r.player = (r.player + 1) % r.players
+}
+
+func (r *race) Outcome() (bool, int) {
+	return r.winner != behavioral.NoWinner, r.winner
+}
+
+func (r *race) Clone() behavioral.Game[int] {
+	clone := *r
+	return &clone
+}
+
+// stuck is a race in which no move is ever legal
+type stuck struct {
+	*race
+}
+
+func (s stuck) LegalMoves() []int {
+	return nil
+}
+
+func (s stuck) Clone() behavioral.Game[int] {
+	return stuck{s.race.Clone().(*race)}
 }
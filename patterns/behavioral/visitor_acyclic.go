@@ -0,0 +1,88 @@
+package behavioral
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 4. Acyclic Visitor
+// ExpressionVisitor names every expression type, so adding one breaks every visitor.
+// Instead, a visitor opts into the types it handles by implementing small interfaces, one per type,
+// and each expression checks whether the visitor handles it. New expression types come with their own interface
+// without touching the existing visitors, and only implement VisitableExpression, not Expression.
+//
+// Every expression follows the same rule for visitors that do not handle it:
+// a composite expression passes the visitor on to its operands, and any other expression is skipped.
+
+// Visitor is any value: what it visits depends on which of the interfaces below it implements
+type Visitor interface{}
+
+type DoubleExpressionVisitor interface {
+	VisitDoubleExpression(e *DoubleExpression)
+}
+
+type AdditionExpressionVisitor interface {
+	VisitAdditionExpression(e *AdditionExpression)
+}
+
+// VisitableExpression is an expression that accepts acyclic visitors
+type VisitableExpression interface {
+	AcceptVisitor(v Visitor)
+}
+
+// VisitExpression lets v visit e
+func VisitExpression(e VisitableExpression, v Visitor) {
+	e.AcceptVisitor(v)
+}
+
+// VisitOperand lets v visit an operand of the expressions above, which are typed Expression, if it accepts acyclic visitors
+func VisitOperand(e Expression, v Visitor) {
+	if ve, ok := e.(VisitableExpression); ok {
+		ve.AcceptVisitor(v)
+	}
+}
+
+func (d *DoubleExpression) AcceptVisitor(v Visitor) {
+	if dv, ok := v.(DoubleExpressionVisitor); ok {
+		dv.VisitDoubleExpression(d)
+	}
+}
+
+// AcceptVisitor goes on to the operands when v does not handle additions
+func (a *AdditionExpression) AcceptVisitor(v Visitor) {
+	if av, ok := v.(AdditionExpressionVisitor); ok {
+		av.VisitAdditionExpression(a)
+		return
+	}
+	VisitOperand(a.Left, v)
+	VisitOperand(a.Right, v)
+}
+
+type AcyclicExpressionPrinter struct {
+	sb strings.Builder
+}
+
+func (ep *AcyclicExpressionPrinter) VisitDoubleExpression(e *DoubleExpression) {
+	ep.sb.WriteString(fmt.Sprintf("%g", e.Value))
+}
+
+func (ep *AcyclicExpressionPrinter) VisitAdditionExpression(e *AdditionExpression) {
+	ep.sb.WriteRune('(')
+	VisitOperand(e.Left, ep)
+	ep.sb.WriteRune('+')
+	VisitOperand(e.Right, ep)
+	ep.sb.WriteRune(')')
+}
+
+func (ep *AcyclicExpressionPrinter) String() string {
+	return ep.sb.String()
+}
+
+// ConstantCollector only handles numbers, and lets composite expressions walk it through their operands
+type ConstantCollector struct {
+	Values []float64
+}
+
+func (cc *ConstantCollector) VisitDoubleExpression(e *DoubleExpression) {
+	cc.Values = append(cc.Values, e.Value)
+}
@@ -0,0 +1,90 @@
+package behavioral
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// 5. Reflective Visitor
+// The visitor only declares methods named Visit... taking one expression type, and a dispatcher finds them by
+// reflection. The methods of each visitor type are looked up once and cached as a table from expression type to
+// method, which then works as a type switch that grows with the visitor.
+
+type ReflectiveDispatcher struct {
+	handlers map[reflect.Type]reflect.Value
+}
+
+// visitorMethods caches, for each visitor type, the index of the method handling each expression type
+var visitorMethods sync.Map
+
+func visitMethodsOf(t reflect.Type) map[reflect.Type]int {
+	if methods, ok := visitorMethods.Load(t); ok {
+		return methods.(map[reflect.Type]int)
+	}
+	methods := map[reflect.Type]int{}
+	expression := reflect.TypeOf((*Expression)(nil)).Elem()
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		if !strings.HasPrefix(m.Name, "Visit") || m.Type.NumIn() != 2 || m.Type.NumOut() != 0 {
+			continue
+		}
+		if in := m.Type.In(1); in.Kind() != reflect.Interface && in.Implements(expression) {
+			methods[in] = i
+		}
+	}
+	actual, _ := visitorMethods.LoadOrStore(t, methods)
+	return actual.(map[reflect.Type]int)
+}
+
+func NewReflectiveDispatcher(visitor interface{}) *ReflectiveDispatcher {
+	v := reflect.ValueOf(visitor)
+	methods := visitMethodsOf(v.Type())
+	handlers := make(map[reflect.Type]reflect.Value, len(methods))
+	for t, i := range methods {
+		handlers[t] = v.Method(i)
+	}
+	return &ReflectiveDispatcher{handlers: handlers}
+}
+
+// Dispatch calls the visitor method for the type of e, and tells whether there was one
+func (d *ReflectiveDispatcher) Dispatch(e Expression) bool {
+	handler, ok := d.handlers[reflect.TypeOf(e)]
+	if !ok {
+		return false
+	}
+	handler.Call([]reflect.Value{reflect.ValueOf(e)})
+	return true
+}
+
+type ReflectiveExpressionPrinter struct {
+	sb         strings.Builder
+	dispatcher *ReflectiveDispatcher
+}
+
+func NewReflectiveExpressionPrinter() *ReflectiveExpressionPrinter {
+	ep := &ReflectiveExpressionPrinter{}
+	ep.dispatcher = NewReflectiveDispatcher(ep)
+	return ep
+}
+
+func (ep *ReflectiveExpressionPrinter) Print(e Expression) {
+	ep.dispatcher.Dispatch(e)
+}
+
+func (ep *ReflectiveExpressionPrinter) VisitDoubleExpression(e *DoubleExpression) {
+	ep.sb.WriteString(fmt.Sprintf("%g", e.Value))
+}
+
+func (ep *ReflectiveExpressionPrinter) VisitAdditionExpression(e *AdditionExpression) {
+	ep.sb.WriteRune('(')
+	ep.dispatcher.Dispatch(e.Left)
+	ep.sb.WriteRune('+')
+	ep.dispatcher.Dispatch(e.Right)
+	ep.sb.WriteRune(')')
+}
+
+func (ep *ReflectiveExpressionPrinter) String() string {
+	return ep.sb.String()
+}
@@ -76,4 +76,194 @@ func TestVisitor(t *testing.T) {
 
 		assert.Equal(t, 6.0, output)
 	})
+	t.Run("Should visit elements using an acyclic visitor", func(t *testing.T) {
+		// Evaluating 1 + (2 + 3)
+		e := &behavioral.AdditionExpression{
+			Left: &behavioral.DoubleExpression{1},
+			Right: &behavioral.AdditionExpression{
+				Left:  &behavioral.DoubleExpression{2},
+				Right: &behavioral.DoubleExpression{3},
+			},
+		}
+		ep := &behavioral.AcyclicExpressionPrinter{} // Visitor
+
+		behavioral.VisitExpression(e, ep)
+		output := ep.String()
+
+		assert.Equal(t, "(1+(2+3))", output)
+	})
+
+	t.Run("Should let an acyclic visitor handle only some expressions", func(t *testing.T) {
+		e := &behavioral.AdditionExpression{
+			Left: &behavioral.DoubleExpression{1},
+			Right: &behavioral.AdditionExpression{
+				Left:  &behavioral.DoubleExpression{2},
+				Right: &behavioral.DoubleExpression{3},
+			},
+		}
+		cc := &behavioral.ConstantCollector{} // Visitor
+
+		behavioral.VisitExpression(e, cc)
+
+		assert.Equal(t, []float64{1, 2, 3}, cc.Values)
+	})
+
+	t.Run("Should add an expression without changing the acyclic visitors", func(t *testing.T) {
+		// Evaluating (1 + 5) - 2
+		e := &subtractionExpression{
+			Left: &behavioral.AdditionExpression{
+				Left:  &behavioral.DoubleExpression{1},
+				Right: &behavioral.DoubleExpression{5},
+			},
+			Right: &behavioral.DoubleExpression{2},
+		}
+		cc := &behavioral.ConstantCollector{}
+		sp := &subtractionPrinter{}
+
+		behavioral.VisitExpression(e, cc)
+		behavioral.VisitExpression(e, sp)
+
+		assert.Equal(t, []float64{1, 5, 2}, cc.Values, "the collector does not know subtractions, which pass it on to their operands")
+		assert.Equal(t, "((1+5)-2)", sp.String())
+	})
+
+	t.Run("Should visit elements using a reflective dispatcher", func(t *testing.T) {
+		// Evaluating 1 + (2 + 3)
+		e := &behavioral.AdditionExpression{
+			Left: &behavioral.DoubleExpression{1},
+			Right: &behavioral.AdditionExpression{
+				Left:  &behavioral.DoubleExpression{2},
+				Right: &behavioral.DoubleExpression{3},
+			},
+		}
+		ep := behavioral.NewReflectiveExpressionPrinter() // Visitor
+
+		ep.Print(e)
+		output := ep.String()
+
+		assert.Equal(t, "(1+(2+3))", output)
+	})
+
+	t.Run("Should dispatch only to methods visiting an expression type", func(t *testing.T) {
+		v := &doubleCounter{}
+		d := behavioral.NewReflectiveDispatcher(v)
+
+		assert.True(t, d.Dispatch(&behavioral.DoubleExpression{1}))
+		assert.False(t, d.Dispatch(&behavioral.AdditionExpression{}))
+		assert.True(t, d.Dispatch(&negation{}))
+		assert.Equal(t, 1, v.doubles)
+		assert.Equal(t, 1, v.negations)
+		assert.Equal(t, 0, v.others)
+	})
+}
+
+// subtractionExpression is added outside the package, with its own visitor interface
+type subtractionExpression struct {
+	Left, Right behavioral.VisitableExpression
+}
+
+type subtractionVisitor interface {
+	VisitSubtractionExpression(e *subtractionExpression)
+}
+
+func (s *subtractionExpression) AcceptVisitor(v behavioral.Visitor) {
+	if sv, ok := v.(subtractionVisitor); ok {
+		sv.VisitSubtractionExpression(s)
+		return
+	}
+	behavioral.VisitExpression(s.Left, v)
+	behavioral.VisitExpression(s.Right, v)
+}
+
+// subtractionPrinter is a new acyclic visitor that also knows subtractions
+type subtractionPrinter struct {
+	sb strings.Builder
+}
+
+func (sp *subtractionPrinter) VisitDoubleExpression(e *behavioral.DoubleExpression) {
+	e.Print(&sp.sb)
+}
+
+func (sp *subtractionPrinter) VisitAdditionExpression(e *behavioral.AdditionExpression) {
+	sp.sb.WriteRune('(')
+	behavioral.VisitOperand(e.Left, sp)
+	sp.sb.WriteRune('+')
+	behavioral.VisitOperand(e.Right, sp)
+	sp.sb.WriteRune(')')
+}
+
+func (sp *subtractionPrinter) VisitSubtractionExpression(e *subtractionExpression) {
+	sp.sb.WriteRune('(')
+	behavioral.VisitExpression(e.Left, sp)
+	sp.sb.WriteRune('-')
+	behavioral.VisitExpression(e.Right, sp)
+	sp.sb.WriteRune(')')
+}
+
+func (sp *subtractionPrinter) String() string {
+	return sp.sb.String()
+}
+
+// negation is an Expression added outside the package, which the reflective dispatcher finds visits of like any other
+type negation struct {
+	behavioral.DoubleExpression
+}
+
+// doubleCounter has methods the reflective dispatcher must not mistake for visits
+type doubleCounter struct {
+	doubles, negations, others int
+}
+
+func (dc *doubleCounter) VisitDoubleExpression(e *behavioral.DoubleExpression) {
+	dc.doubles++
+}
+
+func (dc *doubleCounter) VisitNegation(e *negation) {
+	dc.negations++
+}
+
+func (dc *doubleCounter) VisitAnyExpression(e behavioral.Expression) {
+	dc.others++
+}
+
+func (dc *doubleCounter) VisitNumber(n *float64) {
+	dc.others++
+}
+
+func (dc *doubleCounter) VisitCount() int {
+	return dc.doubles
+}
+
+// buildExpression adds up 2^depth numbers in a balanced tree
+func buildExpression(depth int) behavioral.Expression {
+	if depth == 0 {
+		return &behavioral.DoubleExpression{1}
+	}
+	return &behavioral.AdditionExpression{Left: buildExpression(depth - 1), Right: buildExpression(depth - 1)}
+}
+
+func BenchmarkVisitor(b *testing.B) {
+	e := buildExpression(8)
+
+	b.Run("double dispatch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			e.Accept(behavioral.NewExpressionPrinter())
+		}
+	})
+	b.Run("type switch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			sb := strings.Builder{}
+			behavioral.PrintExpression(e, &sb)
+		}
+	})
+	b.Run("acyclic", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			behavioral.VisitOperand(e, &behavioral.AcyclicExpressionPrinter{})
+		}
+	})
+	b.Run("reflective", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			behavioral.NewReflectiveExpressionPrinter().Print(e)
+		}
+	})
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorToken is what an opaque cursor holds: the query and the position to resume after
+type cursorToken struct {
+	NamePrefix string          `json:"p,omitempty"`
+	MinAge     int             `json:"min,omitempty"`
+	MaxAge     int             `json:"max,omitempty"`
+	SortBy     sortKey         `json:"s"`
+	Descending bool            `json:"d,omitempty"`
+	PageSize   int             `json:"n"`
+	After      *cursorPosition `json:"a,omitempty"`
+}
+
+type cursorPosition struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+	ID   int    `json:"id"`
+}
+
+func encodeCursor(q userQuery, after *position) string {
+	t := cursorToken{
+		NamePrefix: q.filter.namePrefix,
+		MinAge:     q.filter.minAge,
+		MaxAge:     q.filter.maxAge,
+		SortBy:     q.sortBy,
+		Descending: q.descending,
+		PageSize:   q.pageSize,
+	}
+	if after != nil {
+		t.After = &cursorPosition{after.name, after.age, after.id}
+	}
+	data, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token string) (userQuery, *position, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return userQuery{}, nil, ErrInvalidCursor
+	}
+	var t cursorToken
+	if err := json.Unmarshal(data, &t); err != nil {
+		return userQuery{}, nil, ErrInvalidCursor
+	}
+	q := userQuery{
+		filter:     userFilter{namePrefix: t.NamePrefix, minAge: t.MinAge, maxAge: t.MaxAge},
+		sortBy:     t.SortBy,
+		descending: t.Descending,
+		pageSize:   t.PageSize,
+	}
+	if q.validate() != nil {
+		return userQuery{}, nil, ErrInvalidCursor
+	}
+	if t.After == nil {
+		return q, nil, nil
+	}
+	return q, &position{name: t.After.Name, age: t.After.Age, id: t.After.ID}, nil
+}
@@ -1,20 +1,20 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 func main() {
 
-	user1 := &user{
-		name: "a",
-		age:  30,
-	}
-	user2 := &user{
-		name: "b",
-		age:  20,
-	}
-
 	userCollection := &userCollection{
-		users: []*user{user1, user2},
+		source: newMemorySource(
+			&user{id: 1, name: "a", age: 30},
+			&user{id: 2, name: "b", age: 20},
+			&user{id: 3, name: "c", age: 45},
+			&user{id: 4, name: "d", age: 20},
+			&user{id: 5, name: "e", age: 38},
+		),
 	}
 
 	iterator := userCollection.createIterator()
@@ -23,4 +23,24 @@ func main() {
 		user := iterator.getNext()
 		fmt.Printf("User is %+v\n", user)
 	}
+
+	ctx := context.Background()
+	fmt.Println("Users older than 25, oldest first, two per page:")
+	older := userCollection.iterate(ctx, userQuery{
+		filter:     userFilter{minAge: 25},
+		sortBy:     sortByAge,
+		descending: true,
+		pageSize:   2,
+	})
+	fmt.Printf("User is %+v\n", older.getNext())
+	cursor := older.cursor()
+	older.close()
+
+	resumed, err := userCollection.resume(ctx, cursor)
+	if err != nil {
+		panic(err)
+	}
+	for resumed.hasNext() {
+		fmt.Printf("User is %+v (resumed)\n", resumed.getNext())
+	}
 }
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrUnknownSortKey  = errors.New("unknown sort key")
+	ErrInvalidPageSize = errors.New("page size must be positive")
+)
+
+type sortKey string
+
+const (
+	sortByID   sortKey = "id"
+	sortByName sortKey = "name"
+	sortByAge  sortKey = "age"
+)
+
+// userFilter selects users on the server side. Zero values do not filter.
+type userFilter struct {
+	namePrefix     string
+	minAge, maxAge int
+}
+
+func (f userFilter) matches(u *user) bool {
+	return strings.HasPrefix(u.name, f.namePrefix) &&
+		u.age >= f.minAge &&
+		(f.maxAge == 0 || u.age <= f.maxAge)
+}
+
+type userQuery struct {
+	filter     userFilter
+	sortBy     sortKey
+	descending bool
+	pageSize   int
+}
+
+func (q userQuery) validate() error {
+	switch q.sortBy {
+	case sortByID, sortByName, sortByAge:
+	default:
+		return ErrUnknownSortKey
+	}
+	if q.pageSize <= 0 {
+		return ErrInvalidPageSize
+	}
+	return nil
+}
+
+// position is where a page or an iterator stopped: the sort key and id of the last user seen.
+// Paging by position rather than by offset means users added or removed before it do not shift the next pages.
+type position struct {
+	name string
+	age  int
+	id   int
+}
+
+func positionOf(u *user) position {
+	return position{name: u.name, age: u.age, id: u.id}
+}
+
+// compare orders positions by the sort key, then by id so that ties have a stable order
+func (q userQuery) compare(a, b position) int {
+	c := 0
+	switch q.sortBy {
+	case sortByName:
+		c = strings.Compare(a.name, b.name)
+	case sortByAge:
+		c = a.age - b.age
+	}
+	if c == 0 {
+		c = a.id - b.id
+	}
+	if q.descending {
+		return -c
+	}
+	return c
+}
@@ -1,6 +1,7 @@
 package main
 
 type user struct {
+	id   int
 	name string
 	age  int
 }
@@ -1,11 +1,26 @@
 package main
 
+import "context"
+
+const defaultPageSize = 20
+
 type userCollection struct {
-	users []*user
+	source userSource
 }
 
 func (u *userCollection) createIterator() iterator {
-	return &userIterator{
-		users: u.users,
+	return u.iterate(context.Background(), userQuery{sortBy: sortByID, pageSize: defaultPageSize})
+}
+
+func (u *userCollection) iterate(ctx context.Context, q userQuery) *userIterator {
+	return newUserIterator(ctx, u.source, q, nil)
+}
+
+// resume continues an iteration from the cursor of another one, possibly in another process
+func (u *userCollection) resume(ctx context.Context, cursor string) (*userIterator, error) {
+	q, after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
 	}
+	return newUserIterator(ctx, u.source, q, after), nil
 }
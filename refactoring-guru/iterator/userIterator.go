@@ -1,22 +1,96 @@
 package main
 
+import "context"
+
+type fetchResult struct {
+	page page
+	err  error
+}
+
+// userIterator pages through a userSource. As soon as a page arrives, the next one is fetched in the background,
+// so that the source is queried while the current page is being read.
 type userIterator struct {
-	index int
-	users []*user
+	ctx    context.Context
+	cancel context.CancelFunc
+	source userSource
+	query  userQuery
+
+	users   []*user
+	pending chan fetchResult
+	last    *position
+	failure error
+}
+
+func newUserIterator(ctx context.Context, source userSource, q userQuery, after *position) *userIterator {
+	ctx, cancel := context.WithCancel(ctx)
+	it := &userIterator{ctx: ctx, cancel: cancel, source: source, query: q, last: after}
+	it.fetch(after)
+	return it
+}
+
+func (u *userIterator) fetch(after *position) {
+	// Buffered, so that the fetch never blocks on an iterator that was abandoned
+	pending := make(chan fetchResult, 1)
+	u.pending = pending
+	go func() {
+		p, err := u.source.fetchPage(u.ctx, u.query, after)
+		pending <- fetchResult{p, err}
+	}()
 }
 
 func (u *userIterator) hasNext() bool {
-	if u.index < len(u.users) {
+	if len(u.users) > 0 {
 		return true
 	}
-	return false
+	if u.failure != nil || u.pending == nil {
+		return false
+	}
+
+	var r fetchResult
+	select {
+	case r = <-u.pending:
+	case <-u.ctx.Done():
+		r.err = u.ctx.Err()
+	}
+	u.pending = nil
+	if r.err != nil {
+		u.failure = r.err
+		u.cancel()
+		return false
+	}
 
+	u.users = r.page.users
+	if r.page.more && len(u.users) > 0 {
+		after := positionOf(u.users[len(u.users)-1])
+		u.fetch(&after)
+	}
+	return len(u.users) > 0
 }
+
 func (u *userIterator) getNext() *user {
-	if u.hasNext() {
-		user := u.users[u.index]
-		u.index++
-		return user
+	if !u.hasNext() {
+		return nil
 	}
-	return nil
+	user := u.users[0]
+	u.users = u.users[1:]
+	after := positionOf(user)
+	u.last = &after
+	return user
+}
+
+// err is why the iteration stopped early, if it did
+func (u *userIterator) err() error {
+	return u.failure
+}
+
+// cursor is an opaque token to resume the iteration after the last user returned by getNext
+func (u *userIterator) cursor() string {
+	return encodeCursor(u.query, u.last)
+}
+
+// close stops fetching pages. The iterator is exhausted afterwards.
+func (u *userIterator) close() {
+	u.cancel()
+	u.users = nil
+	u.pending = nil
 }
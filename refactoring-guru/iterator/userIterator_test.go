@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRemote is a slow userSource that counts its calls, and fails them from failAt on
+type fakeRemote struct {
+	source userSource
+	delay  time.Duration
+	failAt int
+
+	mu       sync.Mutex
+	calls    int
+	canceled int
+}
+
+var errRemote = errors.New("remote unavailable")
+
+func (r *fakeRemote) fetchPage(ctx context.Context, q userQuery, after *position) (page, error) {
+	r.mu.Lock()
+	r.calls++
+	call := r.calls
+	r.mu.Unlock()
+
+	select {
+	case <-time.After(r.delay):
+	case <-ctx.Done():
+		r.mu.Lock()
+		r.canceled++
+		r.mu.Unlock()
+		return page{}, ctx.Err()
+	}
+	if r.failAt > 0 && call >= r.failAt {
+		return page{}, errRemote
+	}
+	return r.source.fetchPage(ctx, q, after)
+}
+
+func (r *fakeRemote) callCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+func newUsers(ages ...int) *memorySource {
+	s := newMemorySource()
+	for i, age := range ages {
+		s.add(&user{id: i + 1, name: fmt.Sprintf("user%02d", i+1), age: age})
+	}
+	return s
+}
+
+func ids(it iterator) []int {
+	var ids []int
+	for it.hasNext() {
+		ids = append(ids, it.getNext().id)
+	}
+	return ids
+}
+
+func TestUserIterator(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("iterates over every page", func(t *testing.T) {
+		c := &userCollection{source: newUsers(30, 20, 45, 20, 38)}
+
+		it := c.iterate(ctx, userQuery{sortBy: sortByID, pageSize: 2})
+
+		assert.Equal(t, []int{1, 2, 3, 4, 5}, ids(it))
+		assert.NoError(t, it.err())
+		assert.Nil(t, it.getNext())
+		assert.Equal(t, []int{1, 2, 3, 4, 5}, ids(c.createIterator()))
+	})
+
+	t.Run("filters and sorts on the source, breaking ties by id", func(t *testing.T) {
+		c := &userCollection{source: newUsers(30, 20, 45, 20, 38, 20, 61)}
+
+		tests := []struct {
+			name string
+			q    userQuery
+			want []int
+		}{
+			{"by age", userQuery{sortBy: sortByAge, pageSize: 2}, []int{2, 4, 6, 1, 5, 3, 7}},
+			{"by age descending", userQuery{sortBy: sortByAge, descending: true, pageSize: 3}, []int{7, 3, 5, 1, 6, 4, 2}},
+			{"by name descending", userQuery{sortBy: sortByName, descending: true, pageSize: 4}, []int{7, 6, 5, 4, 3, 2, 1}},
+			{"age range", userQuery{filter: userFilter{minAge: 25, maxAge: 45}, sortBy: sortByAge, pageSize: 1}, []int{1, 5, 3}},
+			{"name prefix", userQuery{filter: userFilter{namePrefix: "user0"}, sortBy: sortByID, pageSize: 5}, []int{1, 2, 3, 4, 5, 6, 7}},
+			{"no match", userQuery{filter: userFilter{namePrefix: "admin"}, sortBy: sortByID, pageSize: 5}, nil},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				assert.Equal(t, test.want, ids(c.iterate(ctx, test.q)))
+			})
+		}
+	})
+
+	t.Run("reports invalid queries", func(t *testing.T) {
+		c := &userCollection{source: newUsers(30)}
+
+		it := c.iterate(ctx, userQuery{sortBy: "height", pageSize: 2})
+		assert.False(t, it.hasNext())
+		assert.ErrorIs(t, it.err(), ErrUnknownSortKey)
+
+		it = c.iterate(ctx, userQuery{sortBy: sortByID})
+		assert.False(t, it.hasNext())
+		assert.ErrorIs(t, it.err(), ErrInvalidPageSize)
+	})
+
+	t.Run("prefetches the next page while the current one is read", func(t *testing.T) {
+		remote := &fakeRemote{source: newUsers(1, 2, 3, 4, 5), delay: time.Millisecond}
+		c := &userCollection{source: remote}
+
+		it := c.iterate(ctx, userQuery{sortBy: sortByID, pageSize: 2})
+		defer it.close()
+		assert.Equal(t, 1, it.getNext().id)
+
+		assert.Eventually(t, func() bool { return remote.callCount() == 2 }, time.Second, time.Millisecond)
+		assert.Equal(t, 2, it.getNext().id)
+		assert.Equal(t, 3, it.getNext().id)
+		assert.Eventually(t, func() bool { return remote.callCount() == 3 }, time.Second, time.Millisecond)
+	})
+
+	t.Run("does not fetch past the last page", func(t *testing.T) {
+		remote := &fakeRemote{source: newUsers(1, 2, 3, 4), delay: time.Millisecond}
+		c := &userCollection{source: remote}
+
+		assert.Equal(t, []int{1, 2, 3, 4}, ids(c.iterate(ctx, userQuery{sortBy: sortByID, pageSize: 2})))
+		time.Sleep(5 * time.Millisecond)
+		assert.Equal(t, 2, remote.callCount(), "the source tells when a page is the last one")
+	})
+
+	t.Run("resumes from a cursor", func(t *testing.T) {
+		c := &userCollection{source: newUsers(30, 20, 45, 20, 38, 20, 61)}
+		q := userQuery{filter: userFilter{minAge: 21}, sortBy: sortByAge, descending: true, pageSize: 3}
+
+		first := c.iterate(ctx, q)
+		var got []int
+		for i := 0; i < 2; i++ {
+			got = append(got, first.getNext().id)
+		}
+		cursor := first.cursor()
+		first.close()
+
+		resumed, err := c.resume(ctx, cursor)
+		assert.NoError(t, err)
+		got = append(got, ids(resumed)...)
+
+		assert.Equal(t, ids(c.iterate(ctx, q)), got)
+		assert.Equal(t, []int{7, 3, 5, 1}, got)
+	})
+
+	t.Run("resumes from the start with the cursor of an unread iterator", func(t *testing.T) {
+		c := &userCollection{source: newUsers(30, 20)}
+
+		resumed, err := c.resume(ctx, c.iterate(ctx, userQuery{sortBy: sortByAge, pageSize: 1}).cursor())
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 1}, ids(resumed))
+	})
+
+	t.Run("rejects forged cursors", func(t *testing.T) {
+		c := &userCollection{source: newUsers(30)}
+
+		for _, cursor := range []string{"", "not a cursor!", encodeCursor(userQuery{sortBy: "height", pageSize: 1}, nil)} {
+			_, err := c.resume(ctx, cursor)
+			assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+		}
+	})
+
+	t.Run("neither skips nor repeats users added while paging", func(t *testing.T) {
+		source := newUsers(10, 20, 30, 40)
+		c := &userCollection{source: source}
+		it := c.iterate(ctx, userQuery{sortBy: sortByAge, pageSize: 2})
+
+		var ages []int
+		ages = append(ages, it.getNext().age, it.getNext().age)
+		source.add(&user{id: 5, name: "early", age: 5})
+		source.add(&user{id: 6, name: "late", age: 35})
+		for it.hasNext() {
+			ages = append(ages, it.getNext().age)
+		}
+
+		assert.Equal(t, []int{10, 20, 30, 35, 40}, ages)
+	})
+
+	t.Run("stops on a source error", func(t *testing.T) {
+		remote := &fakeRemote{source: newUsers(1, 2, 3, 4, 5), failAt: 2}
+		c := &userCollection{source: remote}
+
+		it := c.iterate(ctx, userQuery{sortBy: sortByID, pageSize: 2})
+
+		assert.Equal(t, []int{1, 2}, ids(it))
+		assert.ErrorIs(t, it.err(), errRemote)
+	})
+
+	t.Run("cancels the prefetch on close", func(t *testing.T) {
+		remote := &fakeRemote{source: newUsers(1, 2, 3, 4, 5), delay: time.Hour}
+		c := &userCollection{source: remote}
+
+		it := c.iterate(ctx, userQuery{sortBy: sortByID, pageSize: 2})
+		it.close()
+
+		assert.False(t, it.hasNext())
+		assert.Eventually(t, func() bool {
+			remote.mu.Lock()
+			defer remote.mu.Unlock()
+			return remote.canceled == 1
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("stops when the context is canceled", func(t *testing.T) {
+		remote := &fakeRemote{source: newUsers(1, 2, 3), delay: time.Hour}
+		c := &userCollection{source: remote}
+		cctx, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+		defer cancel()
+
+		it := c.iterate(cctx, userQuery{sortBy: sortByID, pageSize: 2})
+
+		assert.False(t, it.hasNext())
+		assert.ErrorIs(t, it.err(), context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+type page struct {
+	users []*user
+	more  bool
+}
+
+// userSource is where the users live, such as a remote API. It filters and sorts them, and returns the page of users
+// that come after a position, or the first page when after is nil.
+type userSource interface {
+	fetchPage(ctx context.Context, q userQuery, after *position) (page, error)
+}
+
+type memorySource struct {
+	mu    sync.RWMutex
+	users []*user
+}
+
+func newMemorySource(users ...*user) *memorySource {
+	return &memorySource{users: users}
+}
+
+func (s *memorySource) add(u *user) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = append(s.users, u)
+}
+
+func (s *memorySource) fetchPage(ctx context.Context, q userQuery, after *position) (page, error) {
+	if err := q.validate(); err != nil {
+		return page{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return page{}, err
+	}
+
+	s.mu.RLock()
+	var matching []*user
+	for _, u := range s.users {
+		if q.filter.matches(u) && (after == nil || q.compare(positionOf(u), *after) > 0) {
+			matching = append(matching, u)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matching, func(i, j int) bool {
+		return q.compare(positionOf(matching[i]), positionOf(matching[j])) < 0
+	})
+	if len(matching) > q.pageSize {
+		return page{users: matching[:q.pageSize], more: true}, nil
+	}
+	return page{users: matching}, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import "time"
+
+// simulatedClock only moves when the simulation advances it, so scenarios run instantly and deterministically
+type simulatedClock struct {
+	current time.Time
+}
+
+func newSimulatedClock(start time.Time) *simulatedClock {
+	return &simulatedClock{current: start}
+}
+
+func (c *simulatedClock) now() time.Time {
+	return c.current
+}
+
+func (c *simulatedClock) advanceTo(t time.Time) {
+	if t.After(c.current) {
+		c.current = t
+	}
+}
+
+// at parses a time of the simulated day, such as "08:15"
+func at(hhmm string) time.Time {
+	t, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
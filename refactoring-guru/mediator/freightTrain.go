@@ -1,25 +1,33 @@
 package main
 
-import "fmt"
-
 type freightTrain struct {
+	name     string
+	schedule schedule
 	mediator mediator
+	platform int
+}
+
+func (g *freightTrain) getName() string {
+	return "FreightTrain " + g.name
+}
+
+func (g *freightTrain) getPriority() int {
+	return freightPriority
+}
+
+func (g *freightTrain) getSchedule() schedule {
+	return g.schedule
 }
 
 func (g *freightTrain) arrive() {
-	if !g.mediator.canArrive(g) {
-		fmt.Println("FreightTrain: Arrival blocked, waiting")
-		return
-	}
-    fmt.Println("FreightTrain: Arrived")
+	g.mediator.canArrive(g)
 }
 
 func (g *freightTrain) depart() {
-	fmt.Println("FreightTrain: Leaving")
-	g.mediator.notifyAboutDeparture()
+	g.platform = 0
+	g.mediator.notifyAboutDeparture(g)
 }
 
-func (g *freightTrain) permitArrival() {
-	fmt.Println("FreightTrain: Arrival permitted")
-	g.arrive()
+func (g *freightTrain) permitArrival(platform int) {
+	g.platform = platform
 }
@@ -1,16 +1,24 @@
 package main
 
+import (
+	"fmt"
+	"time"
+)
+
 func main() {
-	stationManager := newStationManger()
+	clock := newSimulatedClock(at("08:00"))
+	stationManager := newStationManger(2, clock)
 
-	passengerTrain := &passengerTrain{
-		mediator: stationManager,
-	}
-	freightTrain := &freightTrain{
-		mediator: stationManager,
+	trains := []train{
+		&freightTrain{name: "F1", schedule: newSchedule("08:00", "09:00", 30*time.Minute), mediator: stationManager},
+		&passengerTrain{name: "P1", schedule: newSchedule("08:00", "08:05", 10*time.Minute), mediator: stationManager},
+		&freightTrain{name: "F2", schedule: newSchedule("08:02", "09:00", 20*time.Minute), mediator: stationManager},
+		&passengerTrain{name: "P2", schedule: newSchedule("08:05", "08:10", 5*time.Minute), mediator: stationManager},
+		&passengerTrain{name: "P3", schedule: newSchedule("08:08", "08:12", 5*time.Minute), mediator: stationManager},
 	}
 
-	passengerTrain.arrive()
-	freightTrain.arrive()
-	passengerTrain.depart()
+	newSimulation(stationManager, trains...).run()
+
+	fmt.Println()
+	fmt.Print(stationManager.timeline.report())
 }
@@ -2,5 +2,5 @@ package main
 
 type mediator interface {
 	canArrive(train) bool
-	notifyAboutDeparture()
+	notifyAboutDeparture(train)
 }
@@ -1,25 +1,33 @@
 package main
 
-import "fmt"
-
 type passengerTrain struct {
+	name     string
+	schedule schedule
 	mediator mediator
+	platform int
+}
+
+func (g *passengerTrain) getName() string {
+	return "PassengerTrain " + g.name
+}
+
+func (g *passengerTrain) getPriority() int {
+	return passengerPriority
+}
+
+func (g *passengerTrain) getSchedule() schedule {
+	return g.schedule
 }
 
 func (g *passengerTrain) arrive() {
-	if !g.mediator.canArrive(g) {
-		fmt.Println("PassengerTrain: Arrival blocked, waiting")
-		return
-	}
-    fmt.Println("PassengerTrain: Arrived")
+	g.mediator.canArrive(g)
 }
 
 func (g *passengerTrain) depart() {
-	fmt.Println("PassengerTrain: Leaving")
-	g.mediator.notifyAboutDeparture()
+	g.platform = 0
+	g.mediator.notifyAboutDeparture(g)
 }
 
-func (g *passengerTrain) permitArrival() {
-	fmt.Println("PassengerTrain: Arrival permitted, arriving")
-	g.arrive()
+func (g *passengerTrain) permitArrival(platform int) {
+	g.platform = platform
 }
@@ -0,0 +1,13 @@
+package main
+
+import "time"
+
+// schedule is the window in which a train is expected to arrive and how long it occupies a platform
+type schedule struct {
+	earliest, latest time.Time
+	dwell            time.Duration
+}
+
+func newSchedule(earliest, latest string, dwell time.Duration) schedule {
+	return schedule{earliest: at(earliest), latest: at(latest), dwell: dwell}
+}
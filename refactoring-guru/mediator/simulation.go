@@ -0,0 +1,38 @@
+package main
+
+import "sort"
+
+// simulation drives the trains against the simulated clock: every train asks to arrive at the start of its window and departs after its dwell time
+type simulation struct {
+	station *stationManager
+	trains  []train
+}
+
+func newSimulation(station *stationManager, trains ...train) *simulation {
+	return &simulation{station: station, trains: trains}
+}
+
+func (s *simulation) run() {
+	pending := make([]train, len(s.trains))
+	copy(pending, s.trains)
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].getSchedule().earliest.Before(pending[j].getSchedule().earliest)
+	})
+
+	for {
+		departing, departsAt, docked := s.station.nextDeparture()
+		if len(pending) == 0 && !docked {
+			return
+		}
+		// departures first, so trains arriving at the same time find the platform free
+		if docked && (len(pending) == 0 || !departsAt.After(pending[0].getSchedule().earliest)) {
+			s.station.clock.advanceTo(departsAt)
+			departing.depart()
+			continue
+		}
+		next := pending[0]
+		pending = pending[1:]
+		s.station.clock.advanceTo(next.getSchedule().earliest)
+		next.arrive()
+	}
+}
@@ -1,32 +1,87 @@
 package main
 
+import (
+	"container/heap"
+	"fmt"
+	"time"
+)
+
 type stationManager struct {
-	isPlatformFree bool
-	trainQueue     []train
+	clock     *simulatedClock
+	platforms []train // nil when the platform is free
+	queue     trainQueue
+	requests  int
+	timeline  *timeline
 }
 
-func newStationManger() *stationManager {
+func newStationManger(platforms int, clock *simulatedClock) *stationManager {
 	return &stationManager{
-		isPlatformFree: true,
+		clock:     clock,
+		platforms: make([]train, platforms),
+		timeline:  newTimeline(),
 	}
 }
 
 func (s *stationManager) canArrive(t train) bool {
-	if s.isPlatformFree {
-		s.isPlatformFree = false
+	s.timeline.requested(t, s.clock.now())
+	if platform, ok := s.freePlatform(); ok {
+		s.dock(t, platform)
 		return true
 	}
-	s.trainQueue = append(s.trainQueue, t)
+	s.log("%s: Arrival blocked, waiting", t.getName())
+	heap.Push(&s.queue, &waitingTrain{train: t, seq: s.requests})
+	s.requests++
 	return false
 }
 
-func (s *stationManager) notifyAboutDeparture() {
-	if !s.isPlatformFree {
-		s.isPlatformFree = true
+func (s *stationManager) notifyAboutDeparture(t train) {
+	for i, docked := range s.platforms {
+		if docked != t {
+			continue
+		}
+		s.platforms[i] = nil
+		s.timeline.departed(t, s.clock.now())
+		s.log("%s: Left platform %d", t.getName(), i+1)
+		if s.queue.Len() > 0 {
+			next := heap.Pop(&s.queue).(*waitingTrain).train
+			s.dock(next, i)
+		}
+		return
+	}
+}
+
+func (s *stationManager) dock(t train, platform int) {
+	s.platforms[platform] = t
+	s.timeline.arrived(t, s.clock.now(), platform+1)
+	s.log("%s: Arrived on platform %d", t.getName(), platform+1)
+	t.permitArrival(platform + 1)
+}
+
+func (s *stationManager) freePlatform() (int, bool) {
+	for i, t := range s.platforms {
+		if t == nil {
+			return i, true
+		}
 	}
-	if len(s.trainQueue) > 0 {
-		firstTrainInQueue := s.trainQueue[0]
-		s.trainQueue = s.trainQueue[1:]
-		firstTrainInQueue.permitArrival()
+	return 0, false
+}
+
+// nextDeparture returns the docked train that will leave first
+func (s *stationManager) nextDeparture() (train, time.Time, bool) {
+	var next train
+	var when time.Time
+	for _, t := range s.platforms {
+		if t == nil {
+			continue
+		}
+		leaves := s.timeline.entry(t).arrived.Add(t.getSchedule().dwell)
+		if next == nil || leaves.Before(when) {
+			next, when = t, leaves
+		}
 	}
+	return next, when, next != nil
+}
+
+func (s *stationManager) log(format string, args ...any) {
+	fmt.Printf("[%s] %s\n", s.clock.now().Format("15:04"), fmt.Sprintf(format, args...))
 }
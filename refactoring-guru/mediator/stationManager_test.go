@@ -0,0 +1,186 @@
+package main
+
+import (
+	"container/heap"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type trainSpec struct {
+	name                    string
+	freight                 bool
+	earliest, latest, dwell string
+}
+
+func newTrains(station *stationManager, specs []trainSpec) []train {
+	trains := make([]train, len(specs))
+	for i, s := range specs {
+		dwell, err := time.ParseDuration(s.dwell)
+		if err != nil {
+			panic(err)
+		}
+		schedule := newSchedule(s.earliest, s.latest, dwell)
+		if s.freight {
+			trains[i] = &freightTrain{name: s.name, schedule: schedule, mediator: station}
+		} else {
+			trains[i] = &passengerTrain{name: s.name, schedule: schedule, mediator: station}
+		}
+	}
+	return trains
+}
+
+// visit is where and when a train stopped at the station
+type visit struct {
+	platform          int
+	arrived, departed string
+}
+
+func TestStationManager(t *testing.T) {
+	tests := []struct {
+		name      string
+		platforms int
+		trains    []trainSpec
+		visits    map[string]visit
+	}{
+		{
+			name:      "trains take the first free platform",
+			platforms: 3,
+			trains: []trainSpec{
+				{"P1", false, "08:00", "08:05", "30m"},
+				{"P2", false, "08:01", "08:05", "30m"},
+				{"F1", true, "08:02", "09:00", "30m"},
+			},
+			visits: map[string]visit{
+				"PassengerTrain P1": {1, "08:00", "08:30"},
+				"PassengerTrain P2": {2, "08:01", "08:31"},
+				"FreightTrain F1":   {3, "08:02", "08:32"},
+			},
+		},
+		{
+			name:      "a platform freed by a departure is reused",
+			platforms: 2,
+			trains: []trainSpec{
+				{"P1", false, "08:00", "08:05", "10m"},
+				{"P2", false, "08:00", "08:05", "30m"},
+				{"P3", false, "08:10", "08:15", "5m"},
+			},
+			visits: map[string]visit{
+				"PassengerTrain P1": {1, "08:00", "08:10"},
+				"PassengerTrain P2": {2, "08:00", "08:30"},
+				"PassengerTrain P3": {1, "08:10", "08:15"},
+			},
+		},
+		{
+			name:      "a train arriving while every platform is busy waits for the first departure",
+			platforms: 1,
+			trains: []trainSpec{
+				{"F1", true, "08:00", "09:00", "20m"},
+				{"P1", false, "08:05", "08:10", "5m"},
+			},
+			visits: map[string]visit{
+				"FreightTrain F1":   {1, "08:00", "08:20"},
+				"PassengerTrain P1": {1, "08:20", "08:25"},
+			},
+		},
+		{
+			name:      "waiting passenger trains go before freight trains that waited longer",
+			platforms: 1,
+			trains: []trainSpec{
+				{"P1", false, "08:00", "08:05", "30m"},
+				{"F1", true, "08:01", "09:00", "10m"},
+				{"P2", false, "08:02", "08:40", "10m"},
+			},
+			visits: map[string]visit{
+				"PassengerTrain P1": {1, "08:00", "08:30"},
+				"PassengerTrain P2": {1, "08:30", "08:40"},
+				"FreightTrain F1":   {1, "08:40", "08:50"},
+			},
+		},
+		{
+			name:      "trains of the same priority go by deadline",
+			platforms: 1,
+			trains: []trainSpec{
+				{"P1", false, "08:00", "08:05", "30m"},
+				{"P2", false, "08:01", "09:00", "10m"},
+				{"P3", false, "08:02", "08:35", "10m"},
+			},
+			visits: map[string]visit{
+				"PassengerTrain P1": {1, "08:00", "08:30"},
+				"PassengerTrain P3": {1, "08:30", "08:40"},
+				"PassengerTrain P2": {1, "08:40", "08:50"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			station := newStationManger(test.platforms, newSimulatedClock(at("08:00")))
+			trains := newTrains(station, test.trains)
+
+			newSimulation(station, trains...).run()
+
+			for _, tr := range trains {
+				e := station.timeline.entry(tr)
+				want := test.visits[tr.getName()]
+				assert.Equal(t, want.platform, e.platform, tr.getName())
+				assert.Equal(t, at(want.arrived), e.arrived, tr.getName())
+				assert.Equal(t, at(want.departed), e.departed, tr.getName())
+			}
+			assert.Len(t, station.timeline.entries, len(trains))
+		})
+	}
+}
+
+func TestStationManagerBlocksWhenFull(t *testing.T) {
+	clock := newSimulatedClock(at("08:00"))
+	station := newStationManger(2, clock)
+	trains := newTrains(station, []trainSpec{
+		{"P1", false, "08:00", "08:05", "10m"},
+		{"P2", false, "08:00", "08:05", "10m"},
+		{"P3", false, "08:00", "08:05", "10m"},
+	})
+	p1, p3 := trains[0].(*passengerTrain), trains[2].(*passengerTrain)
+
+	assert.True(t, station.canArrive(trains[0]))
+	assert.True(t, station.canArrive(trains[1]))
+	assert.False(t, station.canArrive(p3))
+	assert.Equal(t, 0, p3.platform)
+	assert.Equal(t, 1, station.queue.Len())
+
+	clock.advanceTo(at("08:07"))
+	p1.depart()
+
+	assert.Equal(t, 1, p3.platform, "the waiting train docks on the platform that was freed")
+	assert.Equal(t, 0, station.queue.Len())
+	assert.Equal(t, 7*time.Minute, station.timeline.entry(p3).waited())
+	assert.Equal(t, 2*time.Minute, station.timeline.entry(p3).late())
+}
+
+func TestTrainQueue(t *testing.T) {
+	trains := newTrains(nil, []trainSpec{
+		{"F-late", true, "08:00", "10:00", "1m"},
+		{"P-late", false, "08:00", "09:00", "1m"},
+		{"F-soon", true, "08:00", "08:30", "1m"},
+		{"P-soon", false, "08:00", "08:30", "1m"},
+		{"P-soon-again", false, "08:00", "08:30", "1m"},
+	})
+
+	var q trainQueue
+	for i, tr := range trains {
+		heap.Push(&q, &waitingTrain{train: tr, seq: i})
+	}
+	var order []string
+	for q.Len() > 0 {
+		order = append(order, heap.Pop(&q).(*waitingTrain).train.getName())
+	}
+
+	assert.Equal(t, []string{
+		"PassengerTrain P-soon",
+		"PassengerTrain P-soon-again",
+		"PassengerTrain P-late",
+		"FreightTrain F-soon",
+		"FreightTrain F-late",
+	}, order)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type timelineEntry struct {
+	train                        train
+	requested, arrived, departed time.Time
+	platform                     int
+}
+
+func (e *timelineEntry) waited() time.Duration {
+	return e.arrived.Sub(e.requested)
+}
+
+// late is how long after the end of its scheduled window the train arrived
+func (e *timelineEntry) late() time.Duration {
+	if d := e.arrived.Sub(e.train.getSchedule().latest); d > 0 {
+		return d
+	}
+	return 0
+}
+
+type timeline struct {
+	entries []*timelineEntry
+	byTrain map[train]*timelineEntry
+}
+
+func newTimeline() *timeline {
+	return &timeline{byTrain: map[train]*timelineEntry{}}
+}
+
+func (l *timeline) entry(t train) *timelineEntry {
+	e, ok := l.byTrain[t]
+	if !ok {
+		e = &timelineEntry{train: t}
+		l.byTrain[t] = e
+		l.entries = append(l.entries, e)
+	}
+	return e
+}
+
+func (l *timeline) requested(t train, at time.Time) {
+	l.entry(t).requested = at
+}
+
+func (l *timeline) arrived(t train, at time.Time, platform int) {
+	e := l.entry(t)
+	e.arrived = at
+	e.platform = platform
+}
+
+func (l *timeline) departed(t train, at time.Time) {
+	l.entry(t).departed = at
+}
+
+func (l *timeline) report() string {
+	sb := strings.Builder{}
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Train\tRequested\tArrived\tPlatform\tDeparted\tWaited\tLate")
+	for _, e := range l.entries {
+		late := "-"
+		if e.late() > 0 {
+			late = e.late().String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			e.train.getName(),
+			e.requested.Format("15:04"),
+			e.arrived.Format("15:04"),
+			e.platform,
+			e.departed.Format("15:04"),
+			e.waited(),
+			late,
+		)
+	}
+	w.Flush()
+	return sb.String()
+}
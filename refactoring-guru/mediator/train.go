@@ -1,7 +1,15 @@
 package main
 
+const (
+	passengerPriority = iota // lower values are served first
+	freightPriority
+)
+
 type train interface {
+	getName() string
+	getPriority() int
+	getSchedule() schedule
 	arrive()
 	depart()
-	permitArrival()
+	permitArrival(platform int)
 }
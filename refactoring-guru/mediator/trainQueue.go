@@ -0,0 +1,36 @@
+package main
+
+// trainQueue is a container/heap of waiting trains: higher priority first, then the earliest deadline, then arrival order
+type trainQueue []*waitingTrain
+
+type waitingTrain struct {
+	train train
+	seq   int
+}
+
+func (q trainQueue) Len() int { return len(q) }
+
+func (q trainQueue) Less(i, j int) bool {
+	a, b := q[i].train, q[j].train
+	if a.getPriority() != b.getPriority() {
+		return a.getPriority() < b.getPriority()
+	}
+	if !a.getSchedule().latest.Equal(b.getSchedule().latest) {
+		return a.getSchedule().latest.Before(b.getSchedule().latest)
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q trainQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *trainQueue) Push(x any) {
+	*q = append(*q, x.(*waitingTrain))
+}
+
+func (q *trainQueue) Pop() any {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	*q = old[:n-1]
+	return w
+}
@@ -6,25 +6,44 @@ type hasItemState struct {
 	vendingMachine *vendingMachine
 }
 
-func (i *hasItemState) requestItem() error {
-	if i.vendingMachine.itemCount == 0 {
-		i.vendingMachine.setState(i.vendingMachine.noItem)
-		return fmt.Errorf("No item present")
+func (i *hasItemState) requestItem(code string) error {
+	p, ok := i.vendingMachine.products[code]
+	if !ok {
+		return fmt.Errorf("%w: %s", errUnknownProduct, code)
 	}
-	fmt.Printf("Item requestd\n")
+	if p.stock == 0 {
+		return fmt.Errorf("%w: %s", errOutOfStock, code)
+	}
+	fmt.Printf("%s requested, please insert %d\n", p.name, p.price)
+	i.vendingMachine.selected = code
 	i.vendingMachine.setState(i.vendingMachine.itemRequested)
 	return nil
 }
 
-func (i *hasItemState) addItem(count int) error {
-	fmt.Printf("%d items added\n", count)
-	i.vendingMachine.incrementItemCount(count)
-	return nil
-}
-
 func (i *hasItemState) insertMoney(money int) error {
-	return fmt.Errorf("Please select item first")
+	return errSelectItemFirst
 }
+
 func (i *hasItemState) dispenseItem() error {
-	return fmt.Errorf("Please select item first")
+	return errSelectItemFirst
+}
+
+func (i *hasItemState) cancel() error {
+	return errNothingToCancel
+}
+
+func (i *hasItemState) addItem(code string, count int) error {
+	return errNotUnderMaintenance
+}
+
+func (i *hasItemState) startMaintenance(pin string) error {
+	if pin != i.vendingMachine.pin {
+		return errWrongPin
+	}
+	i.vendingMachine.setState(i.vendingMachine.maintenance)
+	return nil
+}
+
+func (i *hasItemState) endMaintenance() error {
+	return errNotUnderMaintenance
 }
@@ -6,24 +6,55 @@ type hasMoneyState struct {
 	vendingMachine *vendingMachine
 }
 
-func (i *hasMoneyState) requestItem() error {
-	return fmt.Errorf("Item dispense in progress")
-}
-
-func (i *hasMoneyState) addItem(count int) error {
-	return fmt.Errorf("Item dispense in progress")
+func (i *hasMoneyState) requestItem(code string) error {
+	return errDispenseInProgress
 }
 
 func (i *hasMoneyState) insertMoney(money int) error {
-	return fmt.Errorf("Item out of stock")
+	return errDispenseInProgress
 }
+
 func (i *hasMoneyState) dispenseItem() error {
-	fmt.Println("Dispensing Item")
-	i.vendingMachine.itemCount = i.vendingMachine.itemCount - 1
-	if i.vendingMachine.itemCount == 0 {
-		i.vendingMachine.setState(i.vendingMachine.noItem)
-	} else {
-		i.vendingMachine.setState(i.vendingMachine.hasItem)
+	v := i.vendingMachine
+	p := v.products[v.selected]
+	change, ok := v.makeChange(v.credit() - p.price)
+	if !ok {
+		// insertMoney checked the change could be given, and the machine did not give any money since
+		return errNoChange
+	}
+	for _, m := range change {
+		v.cash[m]--
 	}
+	p.stock--
+	fmt.Printf("Dispensing %s", p.name)
+	if len(change) > 0 {
+		fmt.Printf(" with change %v", change)
+	}
+	fmt.Println()
+
+	v.tray.items = append(v.tray.items, p.name)
+	v.tray.money = append(v.tray.money, change...)
+	v.inserted = nil
+	v.selected = ""
+	v.setIdleState()
 	return nil
 }
+
+func (i *hasMoneyState) cancel() error {
+	fmt.Println("Purchase cancelled")
+	i.vendingMachine.refund()
+	i.vendingMachine.setIdleState()
+	return nil
+}
+
+func (i *hasMoneyState) addItem(code string, count int) error {
+	return errNotUnderMaintenance
+}
+
+func (i *hasMoneyState) startMaintenance(pin string) error {
+	return errSessionInProgress
+}
+
+func (i *hasMoneyState) endMaintenance() error {
+	return errNotUnderMaintenance
+}
@@ -6,22 +6,57 @@ type itemRequestedState struct {
 	vendingMachine *vendingMachine
 }
 
-func (i *itemRequestedState) requestItem() error {
-	return fmt.Errorf("Item already requested")
-}
-
-func (i *itemRequestedState) addItem(count int) error {
-	return fmt.Errorf("Item Dispense in progress")
+func (i *itemRequestedState) requestItem(code string) error {
+	return errAlreadyRequested
 }
 
+// insertMoney accepts money until the price is covered. Money is refused when the machine could not give the change back.
 func (i *itemRequestedState) insertMoney(money int) error {
-	if money < i.vendingMachine.itemPrice {
-		fmt.Errorf("Inserted money is less. Please insert %d", i.vendingMachine.itemPrice)
+	v := i.vendingMachine
+	if _, ok := v.cash[money]; !ok {
+		return fmt.Errorf("%w: %d", errUnknownMoney, money)
+	}
+	v.cash[money]++
+	v.inserted = append(v.inserted, money)
+
+	price := v.products[v.selected].price
+	credit := v.credit()
+	if credit < price {
+		fmt.Printf("Inserted %d, please insert %d more\n", credit, price-credit)
+		return nil
+	}
+	if _, ok := v.makeChange(credit - price); !ok {
+		v.cash[money]--
+		v.inserted = v.inserted[:len(v.inserted)-1]
+		return errNoChange
 	}
 	fmt.Println("Money entered is ok")
-	i.vendingMachine.setState(i.vendingMachine.hasMoney)
+	v.setState(v.hasMoney)
 	return nil
 }
+
 func (i *itemRequestedState) dispenseItem() error {
-	return fmt.Errorf("Please insert money first")
+	if len(i.vendingMachine.inserted) == 0 {
+		return errInsertMoneyFirst
+	}
+	return errNotEnoughMoney
+}
+
+func (i *itemRequestedState) cancel() error {
+	fmt.Println("Purchase cancelled")
+	i.vendingMachine.refund()
+	i.vendingMachine.setIdleState()
+	return nil
+}
+
+func (i *itemRequestedState) addItem(code string, count int) error {
+	return errNotUnderMaintenance
+}
+
+func (i *itemRequestedState) startMaintenance(pin string) error {
+	return errSessionInProgress
+}
+
+func (i *itemRequestedState) endMaintenance() error {
+	return errNotUnderMaintenance
 }
@@ -6,44 +6,55 @@ import (
 )
 
 func main() {
-	vendingMachine := newVendingMachine(1, 10)
+	vendingMachine := newVendingMachine("1234", 5, 10, 25, 100)
+
+	// load the machine
+	must(vendingMachine.startMaintenance("1234"))
+	must(vendingMachine.addProduct("A1", "Chips", 65))
+	must(vendingMachine.addProduct("A2", "Soda", 100))
+	must(vendingMachine.addItem("A1", 2))
+	must(vendingMachine.addItem("A2", 1))
+	must(vendingMachine.loadMoney(10, 3))
+	must(vendingMachine.loadMoney(5, 1))
+	must(vendingMachine.endMaintenance())
 
-	err := vendingMachine.requestItem()
-	if err != nil {
-		log.Fatalf(err.Error())
-	}
-
-	err = vendingMachine.insertMoney(10)
-	if err != nil {
-		log.Fatalf(err.Error())
-	}
+	fmt.Println()
 
-	err = vendingMachine.dispenseItem()
-	if err != nil {
-		log.Fatalf(err.Error())
-	}
+	// buy with change
+	must(vendingMachine.requestItem("A1"))
+	must(vendingMachine.insertMoney(25))
+	must(vendingMachine.insertMoney(100))
+	must(vendingMachine.dispenseItem())
+	fmt.Printf("Tray: %+v\n", vendingMachine.takeTray())
 
 	fmt.Println()
 
-	err = vendingMachine.addItem(2)
-	if err != nil {
-		log.Fatalf(err.Error())
+	// change cannot be given, so the customer pays the exact amount
+	must(vendingMachine.requestItem("A1"))
+	if err := vendingMachine.insertMoney(100); err != nil {
+		fmt.Println(err)
 	}
+	must(vendingMachine.insertMoney(25))
+	must(vendingMachine.insertMoney(25))
+	must(vendingMachine.insertMoney(10))
+	must(vendingMachine.insertMoney(5))
+	must(vendingMachine.dispenseItem())
+	fmt.Printf("Tray: %+v\n", vendingMachine.takeTray())
 
 	fmt.Println()
 
-	err = vendingMachine.requestItem()
-	if err != nil {
-		log.Fatalf(err.Error())
-	}
-
-	err = vendingMachine.insertMoney(10)
-	if err != nil {
-		log.Fatalf(err.Error())
+	// change of mind
+	must(vendingMachine.requestItem("A2"))
+	must(vendingMachine.insertMoney(25))
+	must(vendingMachine.cancel())
+	fmt.Printf("Tray: %+v\n", vendingMachine.takeTray())
+	if err := vendingMachine.requestItem("A1"); err != nil {
+		fmt.Println(err)
 	}
+}
 
-	err = vendingMachine.dispenseItem()
+func must(err error) {
 	if err != nil {
-		log.Fatalf(err.Error())
+		log.Fatal(err)
 	}
 }
@@ -0,0 +1,42 @@
+package main
+
+import "fmt"
+
+type maintenanceState struct {
+	vendingMachine *vendingMachine
+}
+
+func (i *maintenanceState) requestItem(code string) error {
+	return errUnderMaintenance
+}
+
+func (i *maintenanceState) insertMoney(money int) error {
+	return errUnderMaintenance
+}
+
+func (i *maintenanceState) dispenseItem() error {
+	return errUnderMaintenance
+}
+
+func (i *maintenanceState) cancel() error {
+	return errUnderMaintenance
+}
+
+func (i *maintenanceState) addItem(code string, count int) error {
+	p, ok := i.vendingMachine.products[code]
+	if !ok {
+		return fmt.Errorf("%w: %s", errUnknownProduct, code)
+	}
+	fmt.Printf("%d %s added\n", count, p.name)
+	p.stock += count
+	return nil
+}
+
+func (i *maintenanceState) startMaintenance(pin string) error {
+	return nil
+}
+
+func (i *maintenanceState) endMaintenance() error {
+	i.vendingMachine.setIdleState()
+	return nil
+}
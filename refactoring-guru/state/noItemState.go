@@ -1,24 +1,37 @@
 package main
 
-import "fmt"
-
 type noItemState struct {
 	vendingMachine *vendingMachine
 }
 
-func (i *noItemState) requestItem() error {
-	return fmt.Errorf("Item out of stock")
-}
-
-func (i *noItemState) addItem(count int) error {
-	i.vendingMachine.incrementItemCount(count)
-	i.vendingMachine.setState(i.vendingMachine.hasItem)
-	return nil
+func (i *noItemState) requestItem(code string) error {
+	return errOutOfStock
 }
 
 func (i *noItemState) insertMoney(money int) error {
-	return fmt.Errorf("Item out of stock")
+	return errOutOfStock
 }
+
 func (i *noItemState) dispenseItem() error {
-	return fmt.Errorf("Item out of stock")
+	return errOutOfStock
+}
+
+func (i *noItemState) cancel() error {
+	return errNothingToCancel
+}
+
+func (i *noItemState) addItem(code string, count int) error {
+	return errNotUnderMaintenance
+}
+
+func (i *noItemState) startMaintenance(pin string) error {
+	if pin != i.vendingMachine.pin {
+		return errWrongPin
+	}
+	i.vendingMachine.setState(i.vendingMachine.maintenance)
+	return nil
+}
+
+func (i *noItemState) endMaintenance() error {
+	return errNotUnderMaintenance
 }
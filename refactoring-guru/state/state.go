@@ -1,8 +1,30 @@
 package main
 
+import "errors"
+
+var (
+	errUnknownProduct      = errors.New("Unknown product")
+	errOutOfStock          = errors.New("Item out of stock")
+	errAlreadyRequested    = errors.New("Item already requested")
+	errSelectItemFirst     = errors.New("Please select item first")
+	errInsertMoneyFirst    = errors.New("Please insert money first")
+	errNotEnoughMoney      = errors.New("Inserted money is less than the price")
+	errDispenseInProgress  = errors.New("Item dispense in progress")
+	errUnknownMoney        = errors.New("Money not accepted")
+	errNoChange            = errors.New("Cannot return change, please insert exact amount")
+	errNothingToCancel     = errors.New("Nothing to cancel")
+	errWrongPin            = errors.New("Wrong maintenance pin")
+	errUnderMaintenance    = errors.New("Machine under maintenance")
+	errNotUnderMaintenance = errors.New("Machine is not under maintenance")
+	errSessionInProgress   = errors.New("Purchase in progress, please cancel it first")
+)
+
 type state interface {
-	addItem(int) error
-	requestItem() error
+	requestItem(code string) error
 	insertMoney(money int) error
 	dispenseItem() error
+	cancel() error
+	addItem(code string, count int) error
+	startMaintenance(pin string) error
+	endMaintenance() error
 }
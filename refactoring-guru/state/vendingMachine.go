@@ -1,51 +1,62 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
+
+type product struct {
+	name  string
+	price int
+	stock int
+}
+
+// tray is where the customer collects dispensed items, change and refunds
+type tray struct {
+	items []string
+	money []int
+}
 
 type vendingMachine struct {
 	hasItem       state
 	itemRequested state
 	hasMoney      state
 	noItem        state
+	maintenance   state
 
 	currentState state
 
-	itemCount int
-	itemPrice int
+	products map[string]*product // by slot code
+	cash     map[int]int         // count of each accepted coin or note held by the machine
+	pin      string
+
+	selected string
+	inserted []int
+	tray     tray
 }
 
-func newVendingMachine(itemCount, itemPrice int) *vendingMachine {
+// newVendingMachine creates an empty machine that accepts the given denominations: products and change are loaded in maintenance
+func newVendingMachine(pin string, denominations ...int) *vendingMachine {
 	v := &vendingMachine{
-		itemCount: itemCount,
-		itemPrice: itemPrice,
-	}
-	hasItemState := &hasItemState{
-		vendingMachine: v,
-	}
-	itemRequestedState := &itemRequestedState{
-		vendingMachine: v,
-	}
-	hasMoneyState := &hasMoneyState{
-		vendingMachine: v,
+		products: map[string]*product{},
+		cash:     map[int]int{},
+		pin:      pin,
 	}
-	noItemState := &noItemState{
-		vendingMachine: v,
+	for _, d := range denominations {
+		v.cash[d] = 0
 	}
+	v.hasItem = &hasItemState{vendingMachine: v}
+	v.itemRequested = &itemRequestedState{vendingMachine: v}
+	v.hasMoney = &hasMoneyState{vendingMachine: v}
+	v.noItem = &noItemState{vendingMachine: v}
+	v.maintenance = &maintenanceState{vendingMachine: v}
 
-	v.setState(hasItemState)
-	v.hasItem = hasItemState
-	v.itemRequested = itemRequestedState
-	v.hasMoney = hasMoneyState
-	v.noItem = noItemState
+	v.setState(v.noItem)
 	return v
 }
 
-func (v *vendingMachine) requestItem() error {
-	return v.currentState.requestItem()
-}
-
-func (v *vendingMachine) addItem(count int) error {
-	return v.currentState.addItem(count)
+func (v *vendingMachine) requestItem(code string) error {
+	return v.currentState.requestItem(code)
 }
 
 func (v *vendingMachine) insertMoney(money int) error {
@@ -56,11 +67,171 @@ func (v *vendingMachine) dispenseItem() error {
 	return v.currentState.dispenseItem()
 }
 
+func (v *vendingMachine) cancel() error {
+	return v.currentState.cancel()
+}
+
+func (v *vendingMachine) addItem(code string, count int) error {
+	return v.currentState.addItem(code, count)
+}
+
+func (v *vendingMachine) startMaintenance(pin string) error {
+	return v.currentState.startMaintenance(pin)
+}
+
+func (v *vendingMachine) endMaintenance() error {
+	return v.currentState.endMaintenance()
+}
+
 func (v *vendingMachine) setState(s state) {
 	v.currentState = s
 }
 
-func (v *vendingMachine) incrementItemCount(count int) {
-	fmt.Printf("Adding %d items\n", count)
-	v.itemCount = v.itemCount + count
+// takeTray empties the tray
+func (v *vendingMachine) takeTray() tray {
+	t := v.tray
+	v.tray = tray{}
+	return t
+}
+
+// Maintenance operations, only allowed while the machine is under maintenance
+
+func (v *vendingMachine) addProduct(code, name string, price int) error {
+	if v.currentState != v.maintenance {
+		return errNotUnderMaintenance
+	}
+	if p, ok := v.products[code]; ok {
+		p.name, p.price = name, price
+		return nil
+	}
+	v.products[code] = &product{name: name, price: price}
+	return nil
+}
+
+func (v *vendingMachine) setPrice(code string, price int) error {
+	if v.currentState != v.maintenance {
+		return errNotUnderMaintenance
+	}
+	p, ok := v.products[code]
+	if !ok {
+		return fmt.Errorf("%w: %s", errUnknownProduct, code)
+	}
+	p.price = price
+	return nil
+}
+
+func (v *vendingMachine) loadMoney(money, count int) error {
+	if v.currentState != v.maintenance {
+		return errNotUnderMaintenance
+	}
+	if _, ok := v.cash[money]; !ok {
+		return fmt.Errorf("%w: %d", errUnknownMoney, money)
+	}
+	v.cash[money] += count
+	return nil
+}
+
+// collectCash takes out all the money held by the machine, including the change
+func (v *vendingMachine) collectCash() (map[int]int, error) {
+	if v.currentState != v.maintenance {
+		return nil, errNotUnderMaintenance
+	}
+	collected := map[int]int{}
+	for d, count := range v.cash {
+		if count > 0 {
+			collected[d] = count
+		}
+		v.cash[d] = 0
+	}
+	return collected, nil
+}
+
+func (v *vendingMachine) stock(code string) int {
+	if p, ok := v.products[code]; ok {
+		return p.stock
+	}
+	return 0
+}
+
+func (v *vendingMachine) hasStock() bool {
+	for _, p := range v.products {
+		if p.stock > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// setIdleState moves to the state waiting for a customer
+func (v *vendingMachine) setIdleState() {
+	if v.hasStock() {
+		v.setState(v.hasItem)
+	} else {
+		v.setState(v.noItem)
+	}
+}
+
+func (v *vendingMachine) credit() int {
+	credit := 0
+	for _, m := range v.inserted {
+		credit += m
+	}
+	return credit
+}
+
+// refund returns the money inserted in the current session and forgets the selection
+func (v *vendingMachine) refund() {
+	for _, m := range v.inserted {
+		v.cash[m]--
+	}
+	v.tray.money = append(v.tray.money, v.inserted...)
+	v.inserted = nil
+	v.selected = ""
+}
+
+// makeChange finds the fewest coins and notes adding up to amount, using only what the machine holds
+func (v *vendingMachine) makeChange(amount int) ([]int, bool) {
+	denominations := make([]int, 0, len(v.cash))
+	for d := range v.cash {
+		denominations = append(denominations, d)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(denominations)))
+
+	const impossible = -1
+	// fewest[i][a] is the fewest pieces making a with the first i denominations, and used[i][a] how many of the i-th are used
+	fewest := make([][]int, len(denominations)+1)
+	used := make([][]int, len(denominations)+1)
+	for i := range fewest {
+		fewest[i] = make([]int, amount+1)
+		used[i] = make([]int, amount+1)
+	}
+	for a := 1; a <= amount; a++ {
+		fewest[0][a] = impossible
+	}
+	for i, d := range denominations {
+		for a := 0; a <= amount; a++ {
+			fewest[i+1][a] = impossible
+			for k := 0; k <= v.cash[d] && k*d <= a; k++ {
+				rest := fewest[i][a-k*d]
+				if rest != impossible && (fewest[i+1][a] == impossible || rest+k < fewest[i+1][a]) {
+					fewest[i+1][a] = rest + k
+					used[i+1][a] = k
+				}
+			}
+		}
+	}
+	if fewest[len(denominations)][amount] == impossible {
+		return nil, false
+	}
+
+	change := []int{}
+	for i, a := len(denominations), amount; i > 0; i-- {
+		k := used[i][a]
+		for j := 0; j < k; j++ {
+			change = append(change, denominations[i-1])
+		}
+		a -= k * denominations[i-1]
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(change)))
+	return change, true
 }
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// step is one action of a customer or an operator, with the error it should get
+type step struct {
+	do  func(v *vendingMachine) error
+	err error
+}
+
+func request(code string) step {
+	return step{do: func(v *vendingMachine) error { return v.requestItem(code) }}
+}
+
+func insert(money int) step {
+	return step{do: func(v *vendingMachine) error { return v.insertMoney(money) }}
+}
+
+func dispense() step {
+	return step{do: func(v *vendingMachine) error { return v.dispenseItem() }}
+}
+
+func cancel() step {
+	return step{do: func(v *vendingMachine) error { return v.cancel() }}
+}
+
+func maintain(pin string, ops ...func(v *vendingMachine) error) step {
+	return step{do: func(v *vendingMachine) error {
+		if err := v.startMaintenance(pin); err != nil {
+			return err
+		}
+		for _, op := range ops {
+			if err := op(v); err != nil {
+				return err
+			}
+		}
+		return v.endMaintenance()
+	}}
+}
+
+func (s step) fails(err error) step {
+	s.err = err
+	return s
+}
+
+// newStockedMachine has 2 Chips at 65 and 1 Soda at 100, and three 10 and one 5 for change
+func newStockedMachine() *vendingMachine {
+	v := newVendingMachine("1234", 5, 10, 25, 100)
+	v.startMaintenance("1234")
+	v.addProduct("A1", "Chips", 65)
+	v.addProduct("A2", "Soda", 100)
+	v.addItem("A1", 2)
+	v.addItem("A2", 1)
+	v.loadMoney(10, 3)
+	v.loadMoney(5, 1)
+	v.endMaintenance()
+	return v
+}
+
+func TestVendingMachine(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []step
+		tray  tray
+		state func(v *vendingMachine) state
+		stock map[string]int
+		cash  map[int]int
+	}{
+		{
+			name:  "exact amount",
+			steps: []step{request("A2"), insert(100), dispense()},
+			tray:  tray{items: []string{"Soda"}},
+			state: func(v *vendingMachine) state { return v.hasItem },
+			stock: map[string]int{"A1": 2, "A2": 0},
+			cash:  map[int]int{5: 1, 10: 3, 25: 0, 100: 1},
+		},
+		{
+			name:  "change with the fewest coins",
+			steps: []step{request("A1"), insert(100), dispense()},
+			tray:  tray{items: []string{"Chips"}, money: []int{10, 10, 10, 5}},
+			state: func(v *vendingMachine) state { return v.hasItem },
+			stock: map[string]int{"A1": 1, "A2": 1},
+			cash:  map[int]int{5: 0, 10: 0, 25: 0, 100: 1},
+		},
+		{
+			name:  "inserted coins are used as change",
+			steps: []step{request("A1"), insert(25), insert(100), dispense()},
+			tray:  tray{items: []string{"Chips"}, money: []int{25, 10, 10, 10, 5}},
+			state: func(v *vendingMachine) state { return v.hasItem },
+			stock: map[string]int{"A1": 1, "A2": 1},
+			cash:  map[int]int{5: 0, 10: 0, 25: 0, 100: 1},
+		},
+		{
+			name: "money refused when change is impossible",
+			steps: []step{
+				request("A1"), insert(100), dispense(),
+				request("A1"), insert(100).fails(errNoChange), insert(25), insert(25), insert(10), insert(5), dispense(),
+			},
+			tray:  tray{items: []string{"Chips", "Chips"}, money: []int{10, 10, 10, 5}},
+			state: func(v *vendingMachine) state { return v.hasItem },
+			stock: map[string]int{"A1": 0, "A2": 1},
+			cash:  map[int]int{5: 1, 10: 1, 25: 2, 100: 1},
+		},
+		{
+			name:  "refund on cancel",
+			steps: []step{request("A2"), insert(25), insert(10), cancel(), dispense().fails(errSelectItemFirst)},
+			tray:  tray{money: []int{25, 10}},
+			state: func(v *vendingMachine) state { return v.hasItem },
+			stock: map[string]int{"A1": 2, "A2": 1},
+			cash:  map[int]int{5: 1, 10: 3, 25: 0, 100: 0},
+		},
+		{
+			name:  "refund on cancel after paying",
+			steps: []step{request("A2"), insert(100), insert(5).fails(errDispenseInProgress), cancel()},
+			tray:  tray{money: []int{100}},
+			state: func(v *vendingMachine) state { return v.hasItem },
+			stock: map[string]int{"A1": 2, "A2": 1},
+			cash:  map[int]int{5: 1, 10: 3, 25: 0, 100: 0},
+		},
+		{
+			name: "wrong requests",
+			steps: []step{
+				insert(25).fails(errSelectItemFirst),
+				cancel().fails(errNothingToCancel),
+				request("B1").fails(errUnknownProduct),
+				request("A1"),
+				request("A2").fails(errAlreadyRequested),
+				dispense().fails(errInsertMoneyFirst),
+				insert(3).fails(errUnknownMoney),
+				insert(25),
+				dispense().fails(errNotEnoughMoney),
+			},
+			tray:  tray{},
+			state: func(v *vendingMachine) state { return v.itemRequested },
+			stock: map[string]int{"A1": 2, "A2": 1},
+			cash:  map[int]int{5: 1, 10: 3, 25: 1, 100: 0},
+		},
+		{
+			name: "sold out",
+			steps: []step{
+				request("A2"), insert(100), dispense(),
+				request("A2").fails(errOutOfStock),
+				request("A1"), insert(25), insert(25), insert(10), insert(5), dispense(),
+				request("A1"), insert(25), insert(25), insert(10), insert(5), dispense(),
+				request("A1").fails(errOutOfStock),
+				insert(25).fails(errOutOfStock),
+			},
+			tray:  tray{items: []string{"Soda", "Chips", "Chips"}},
+			state: func(v *vendingMachine) state { return v.noItem },
+			stock: map[string]int{"A1": 0, "A2": 0},
+			cash:  map[int]int{5: 3, 10: 5, 25: 4, 100: 1},
+		},
+		{
+			name: "maintenance",
+			steps: []step{
+				maintain("0000").fails(errWrongPin),
+				request("A1"),
+				maintain("1234").fails(errSessionInProgress),
+				cancel(),
+				maintain("1234",
+					func(v *vendingMachine) error { return v.addItem("A1", 3) },
+					func(v *vendingMachine) error { return v.setPrice("A1", 50) },
+					func(v *vendingMachine) error { return v.loadMoney(25, 2) },
+				),
+				request("A1"), insert(100), dispense(),
+			},
+			tray:  tray{items: []string{"Chips"}, money: []int{25, 25}},
+			state: func(v *vendingMachine) state { return v.hasItem },
+			stock: map[string]int{"A1": 4, "A2": 1},
+			cash:  map[int]int{5: 1, 10: 3, 25: 0, 100: 1},
+		},
+		{
+			name: "no sales under maintenance",
+			steps: []step{
+				{do: func(v *vendingMachine) error { return v.startMaintenance("1234") }},
+				request("A1").fails(errUnderMaintenance),
+				insert(25).fails(errUnderMaintenance),
+				step{do: func(v *vendingMachine) error { return v.addItem("B1", 1) }}.fails(errUnknownProduct),
+			},
+			tray:  tray{},
+			state: func(v *vendingMachine) state { return v.maintenance },
+			stock: map[string]int{"A1": 2, "A2": 1},
+			cash:  map[int]int{5: 1, 10: 3, 25: 0, 100: 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := newStockedMachine()
+			for i, s := range test.steps {
+				err := s.do(v)
+				if s.err == nil {
+					assert.NoError(t, err, "step %d", i)
+				} else {
+					assert.ErrorIs(t, err, s.err, "step %d", i)
+				}
+			}
+
+			assert.Equal(t, test.tray, v.takeTray())
+			assert.Equal(t, test.state(v), v.currentState)
+			for code, stock := range test.stock {
+				assert.Equal(t, stock, v.stock(code), code)
+			}
+			assert.Equal(t, test.cash, v.cash)
+		})
+	}
+}
+
+func TestOperationsOutsideMaintenance(t *testing.T) {
+	v := newStockedMachine()
+
+	assert.ErrorIs(t, v.addItem("A1", 1), errNotUnderMaintenance)
+	assert.ErrorIs(t, v.addProduct("B1", "Candy", 30), errNotUnderMaintenance)
+	assert.ErrorIs(t, v.setPrice("A1", 10), errNotUnderMaintenance)
+	assert.ErrorIs(t, v.loadMoney(10, 100), errNotUnderMaintenance)
+	_, err := v.collectCash()
+	assert.ErrorIs(t, err, errNotUnderMaintenance)
+
+	assert.NoError(t, v.startMaintenance("1234"))
+	cash, err := v.collectCash()
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{5: 1, 10: 3}, cash)
+	assert.Equal(t, map[int]int{5: 0, 10: 0, 25: 0, 100: 0}, v.cash)
+}
@@ -0,0 +1,134 @@
+package main
+
+import "container/list"
+
+// arc is the Adaptive Replacement Cache of Megiddo and Modha. It balances recency and frequency:
+// t1 holds keys used once recently and t2 keys used at least twice, while the ghost lists b1 and b2 remember
+// the keys recently evicted from each. A miss on a ghost key shows which of the two lists deserved more room,
+// and moves the target size p of t1 accordingly. A one-off scan only goes through t1, so it cannot flush t2.
+type arc[K comparable] struct {
+	capacity int // set by the cache, so that t1 and t2 hold as many keys as the cache holds entries
+	p        int
+	t1, t2   *list.List // least recently used first
+	b1, b2   *list.List
+	entries  map[K]*arcEntry
+
+	adapted    bool // victim already adapted p to the incoming key
+	adaptedKey K
+}
+
+type arcEntry struct {
+	list    *list.List
+	element *list.Element
+}
+
+func newArc[K comparable]() *arc[K] {
+	return &arc[K]{
+		t1:      list.New(),
+		t2:      list.New(),
+		b1:      list.New(),
+		b2:      list.New(),
+		entries: map[K]*arcEntry{},
+	}
+}
+
+func (a *arc[K]) setCapacity(capacity int) {
+	a.capacity = capacity
+}
+
+// adapt grows t1 on a hit in b1, and shrinks it on a hit in b2
+func (a *arc[K]) adapt(key K) {
+	e, ok := a.entries[key]
+	if !ok {
+		return
+	}
+	switch e.list {
+	case a.b1:
+		a.p = minInt(a.capacity, a.p+maxInt(a.b2.Len()/a.b1.Len(), 1))
+	case a.b2:
+		a.p = maxInt(0, a.p-maxInt(a.b1.Len()/a.b2.Len(), 1))
+	}
+}
+
+func (a *arc[K]) moveTo(key K, to *list.List) {
+	e := a.entries[key]
+	e.list.Remove(e.element)
+	e.list, e.element = to, to.PushBack(key)
+}
+
+func (a *arc[K]) drop(l *list.List) {
+	key := l.Remove(l.Front()).(K)
+	delete(a.entries, key)
+}
+
+func (a *arc[K]) add(key K) {
+	if !a.adapted || a.adaptedKey != key {
+		a.adapt(key)
+	}
+	a.adapted = false
+
+	if _, ok := a.entries[key]; ok {
+		a.moveTo(key, a.t2) // the key was used before it was evicted
+	} else {
+		a.entries[key] = &arcEntry{list: a.t1, element: a.t1.PushBack(key)}
+	}
+
+	for a.t1.Len()+a.b1.Len() > a.capacity && a.b1.Len() > 0 {
+		a.drop(a.b1)
+	}
+	for a.t1.Len()+a.t2.Len()+a.b1.Len()+a.b2.Len() > 2*a.capacity && a.b2.Len() > 0 {
+		a.drop(a.b2)
+	}
+}
+
+func (a *arc[K]) access(key K) {
+	if e, ok := a.entries[key]; ok && (e.list == a.t1 || e.list == a.t2) {
+		a.moveTo(key, a.t2)
+	}
+}
+
+func (a *arc[K]) remove(key K) {
+	if e, ok := a.entries[key]; ok && (e.list == a.t1 || e.list == a.t2) {
+		e.list.Remove(e.element)
+		delete(a.entries, key)
+	}
+}
+
+func (a *arc[K]) victim(incoming K) (K, bool) {
+	a.adapt(incoming)
+	a.adapted, a.adaptedKey = true, incoming
+
+	inB2 := false
+	if e, ok := a.entries[incoming]; ok {
+		inB2 = e.list == a.b2
+	}
+	from, ghost := a.t2, a.b2
+	if a.t1.Len() > 0 && (a.t1.Len() > a.p || (inB2 && a.t1.Len() == a.p) || a.t2.Len() == 0) {
+		from, ghost = a.t1, a.b1
+	}
+	if from.Len() == 0 {
+		var zero K
+		return zero, false
+	}
+	key := from.Front().Value.(K)
+	a.moveTo(key, ghost)
+	return key, true
+}
+
+func (a *arc[K]) keys() []K {
+	return append(listKeys[K](a.t1), listKeys[K](a.t2)...)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -1,39 +1,120 @@
 package main
 
-type cache struct {
-	storage      map[string]string
-	evictionAlgo evictionAlgo
-	capacity     int
+import "sync"
+
+type cache[K comparable, V any] struct {
+	mu           sync.Mutex
+	storage      map[K]V
+	evictionAlgo evictionAlgo[K]
 	maxCapacity  int
+	stats        cacheStats
+}
+
+type cacheStats struct {
+	hits      int
+	misses    int
+	evictions int
+}
+
+func (s cacheStats) hitRate() float64 {
+	if s.hits+s.misses == 0 {
+		return 0
+	}
+	return float64(s.hits) / float64(s.hits+s.misses)
 }
 
-func initCache(e evictionAlgo) *cache {
-	storage := make(map[string]string)
-	return &cache{
-		storage:      storage,
+func initCache[K comparable, V any](e evictionAlgo[K], maxCapacity int) *cache[K, V] {
+	if s, ok := e.(sizer); ok {
+		s.setCapacity(maxCapacity)
+	}
+	return &cache[K, V]{
+		storage:      make(map[K]V, maxCapacity),
 		evictionAlgo: e,
-		capacity:     0,
-		maxCapacity:  2,
+		maxCapacity:  maxCapacity,
 	}
 }
 
-func (c *cache) setEvictionAlgo(e evictionAlgo) {
+// setEvictionAlgo replaces the algorithm, keeping every entry: the new algorithm learns the keys in the eviction order of the old one
+func (c *cache[K, V]) setEvictionAlgo(e evictionAlgo[K]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := e.(sizer); ok {
+		s.setCapacity(c.maxCapacity)
+	}
+	for _, key := range c.evictionAlgo.keys() {
+		e.add(key)
+	}
 	c.evictionAlgo = e
 }
 
-func (c *cache) add(key, value string) {
-	if c.capacity == c.maxCapacity {
-		c.evict()
+func (c *cache[K, V]) add(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.storage[key]; ok && !c.expired(key) {
+		c.storage[key] = value
+		c.evictionAlgo.access(key)
+		return
+	}
+	if len(c.storage) >= c.maxCapacity {
+		c.evict(key)
 	}
-	c.capacity++
 	c.storage[key] = value
+	c.evictionAlgo.add(key)
+}
+
+func (c *cache[K, V]) get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.storage[key]
+	if !ok || c.expired(key) {
+		c.stats.misses++
+		var zero V
+		return zero, false
+	}
+	c.stats.hits++
+	c.evictionAlgo.access(key)
+	return value, true
+}
+
+func (c *cache[K, V]) remove(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.storage[key]; !ok {
+		return false
+	}
+	delete(c.storage, key)
+	c.evictionAlgo.remove(key)
+	return true
+}
+
+func (c *cache[K, V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.storage)
+}
+
+func (c *cache[K, V]) getStats() cacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
 }
 
-func (c *cache) get(key string) {
+// expired removes the key if the algorithm says it expired
+func (c *cache[K, V]) expired(key K) bool {
+	e, ok := c.evictionAlgo.(expirer[K])
+	if !ok || !e.expired(key) {
+		return false
+	}
 	delete(c.storage, key)
+	c.evictionAlgo.remove(key)
+	return true
 }
 
-func (c *cache) evict() {
-	c.evictionAlgo.evict(c)
-	c.capacity--
+func (c *cache[K, V]) evict(incoming K) {
+	key, ok := c.evictionAlgo.victim(incoming)
+	if !ok {
+		return
+	}
+	delete(c.storage, key)
+	c.stats.evictions++
 }
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEviction(t *testing.T) {
+	tests := []struct {
+		name    string
+		algo    evictionAlgo[string]
+		evicted []string
+	}{
+		{"fifo", newFifo[string](), []string{"a", "b"}},
+		{"lru", newLru[string](), []string{"b", "a"}},
+		{"lfu", newLfu[string](), []string{"b", "d"}},
+		{"arc", newArc[string](), []string{"b", "d"}},
+		{"ttl", newTtl[string](time.Hour, nil), []string{"b", "a"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := initCache[string, int](test.algo, 3)
+			c.add("a", 1)
+			c.add("b", 2)
+			c.add("c", 3)
+			c.get("a")
+			c.get("c")
+			c.add("d", 4)
+			c.add("c", 30) // overwriting does not evict
+			c.get("c")
+			c.add("e", 5)
+
+			assert.Equal(t, 3, c.len())
+			for _, key := range test.evicted {
+				_, ok := c.get(key)
+				assert.False(t, ok, key)
+			}
+			assert.Equal(t, 2, c.getStats().evictions)
+		})
+	}
+}
+
+func TestGetDoesNotRemove(t *testing.T) {
+	c := initCache[string, string](newLru[string](), 2)
+	c.add("a", "1")
+
+	for i := 0; i < 3; i++ {
+		value, ok := c.get("a")
+		assert.True(t, ok)
+		assert.Equal(t, "1", value)
+	}
+	assert.Equal(t, cacheStats{hits: 3}, c.getStats())
+
+	assert.True(t, c.remove("a"))
+	assert.False(t, c.remove("a"))
+	_, ok := c.get("a")
+	assert.False(t, ok)
+}
+
+func TestSetEvictionAlgoKeepsEntries(t *testing.T) {
+	c := initCache[int, int](newLfu[int](), 4)
+	for i := 0; i < 4; i++ {
+		c.add(i, i)
+		for j := 0; j < i; j++ {
+			c.get(i)
+		}
+	}
+
+	c.setEvictionAlgo(newFifo[int]())
+	assert.Equal(t, 4, c.len())
+
+	// fifo took over the order of lfu, the least frequently used first
+	c.add(4, 4)
+	_, ok := c.get(0)
+	assert.False(t, ok)
+	for i := 1; i < 5; i++ {
+		_, ok := c.get(i)
+		assert.True(t, ok, i)
+	}
+}
+
+func TestTtl(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := initCache[string, int](newTtl[string](time.Minute, func() time.Time { return now }), 10)
+	c.add("a", 1)
+	c.add("b", 2)
+
+	now = now.Add(40 * time.Second)
+	c.get("a") // moves the deadline of a
+
+	now = now.Add(40 * time.Second)
+	_, ok := c.get("a")
+	assert.True(t, ok)
+	_, ok = c.get("b")
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.len())
+
+	now = now.Add(time.Minute)
+	c.add("a", 3) // expired keys are added again rather than overwritten
+	value, ok := c.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 3, value)
+}
+
+func TestArcResistsScans(t *testing.T) {
+	hot := func(c *cache[int, int]) int {
+		hits := 0
+		for i := 0; i < 50; i++ {
+			if _, ok := c.get(i); ok {
+				hits++
+			}
+		}
+		return hits
+	}
+	run := func(algo evictionAlgo[int]) int {
+		c := initCache[int, int](algo, 100)
+		for round := 0; round < 3; round++ {
+			for i := 0; i < 50; i++ {
+				if _, ok := c.get(i); !ok {
+					c.add(i, i)
+				}
+			}
+		}
+		for i := 1000; i < 1200; i++ {
+			c.add(i, i)
+		}
+		return hot(c)
+	}
+
+	assert.Equal(t, 0, run(newLru[int]()))
+	assert.Equal(t, 50, run(newArc[int]()))
+}
+
+func TestHitRates(t *testing.T) {
+	rate := func(algo evictionAlgo[int], wl workload) float64 {
+		keys := wl.keys(rand.New(rand.NewSource(1)), 20_000)
+		return simulate(initCache[int, int](algo, 100), keys).hitRate()
+	}
+
+	for _, wl := range workloads[:2] {
+		lru := rate(newLru[int](), wl)
+		assert.Greater(t, lru, rate(newFifo[int](), wl)-0.01, wl.name)
+		assert.Greater(t, rate(newArc[int](), wl), lru, wl.name)
+		assert.Greater(t, rate(newLfu[int](), wl), lru, wl.name)
+	}
+}
+
+func BenchmarkCache(b *testing.B) {
+	algos := []struct {
+		name string
+		new  func() evictionAlgo[int]
+	}{
+		{"fifo", func() evictionAlgo[int] { return newFifo[int]() }},
+		{"lru", func() evictionAlgo[int] { return newLru[int]() }},
+		{"lfu", func() evictionAlgo[int] { return newLfu[int]() }},
+		{"arc", func() evictionAlgo[int] { return newArc[int]() }},
+		{"ttl", func() evictionAlgo[int] { return newTtl[int](time.Hour, nil) }},
+	}
+	for _, wl := range workloads {
+		keys := wl.keys(rand.New(rand.NewSource(1)), 1<<16)
+		for _, algo := range algos {
+			b.Run(wl.name+"/"+algo.name, func(b *testing.B) {
+				c := initCache[int, int](algo.new(), capacity)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					key := keys[i%len(keys)]
+					if _, ok := c.get(key); !ok {
+						c.add(key, key)
+					}
+				}
+				b.ReportMetric(100*c.getStats().hitRate(), "%hit")
+			})
+		}
+	}
+}
+
+func TestArcHoldsAsManyKeysAsTheCache(t *testing.T) {
+	a := newArc[int]()
+	c := initCache[int, int](newLru[int](), 10)
+	c.setEvictionAlgo(a)
+
+	keys := workloads[0].keys(rand.New(rand.NewSource(1)), 5_000)
+	simulate(c, keys)
+
+	assert.Equal(t, 10, c.len())
+	assert.Equal(t, c.len(), a.t1.Len()+a.t2.Len())
+	assert.LessOrEqual(t, a.t1.Len()+a.b1.Len(), 10)
+	assert.LessOrEqual(t, a.t1.Len()+a.t2.Len()+a.b1.Len()+a.b2.Len(), 20)
+}
@@ -1,5 +1,23 @@
 package main
 
-type evictionAlgo interface {
-	evict(c *cache)
+// evictionAlgo decides which key leaves the cache when it is full.
+// The cache tells it about every key it stores, uses and removes, so that algorithms keep their own bookkeeping in O(1).
+type evictionAlgo[K comparable] interface {
+	add(key K)    // key was stored
+	access(key K) // key was read or overwritten
+	remove(key K) // key was deleted from the cache
+	// victim picks the key to evict to make room for incoming, and forgets it
+	victim(incoming K) (K, bool)
+	// keys lists the keys tracked, the next to be evicted first, so that another algorithm can take over
+	keys() []K
+}
+
+// expirer is implemented by algorithms whose keys expire on their own
+type expirer[K comparable] interface {
+	expired(key K) bool
+}
+
+// sizer is implemented by algorithms that size their bookkeeping after the capacity of the cache
+type sizer interface {
+	setCapacity(capacity int)
 }
@@ -1,10 +1,54 @@
 package main
 
-import "fmt"
+import "container/list"
 
-type fifo struct {
+// fifo evicts the oldest key, however much it is used
+type fifo[K comparable] struct {
+	order    *list.List // oldest first
+	elements map[K]*list.Element
 }
 
-func (l *fifo) evict(c *cache) {
-	fmt.Println("Evicting by fifo strtegy")
+func newFifo[K comparable]() *fifo[K] {
+	return &fifo[K]{order: list.New(), elements: map[K]*list.Element{}}
+}
+
+func (f *fifo[K]) add(key K) {
+	f.elements[key] = f.order.PushBack(key)
+}
+
+func (f *fifo[K]) access(key K) {
+}
+
+func (f *fifo[K]) remove(key K) {
+	if e, ok := f.elements[key]; ok {
+		f.order.Remove(e)
+		delete(f.elements, key)
+	}
+}
+
+func (f *fifo[K]) victim(incoming K) (K, bool) {
+	return popKey(f.order, f.order.Front(), f.elements)
+}
+
+func (f *fifo[K]) keys() []K {
+	return listKeys[K](f.order)
+}
+
+// popKey removes the element holding a key from both the list and the index
+func popKey[K comparable](l *list.List, e *list.Element, elements map[K]*list.Element) (K, bool) {
+	if e == nil {
+		var zero K
+		return zero, false
+	}
+	key := l.Remove(e).(K)
+	delete(elements, key)
+	return key, true
+}
+
+func listKeys[K comparable](l *list.List) []K {
+	keys := make([]K, 0, l.Len())
+	for e := l.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(K))
+	}
+	return keys
 }
@@ -1,10 +1,83 @@
 package main
 
-import "fmt"
+import "container/list"
 
-type lfu struct {
+// lfu evicts the key used the fewest times, the least recently used among equals.
+// Keys are kept in buckets of equal frequency, sorted by frequency, so that every operation is O(1).
+type lfu[K comparable] struct {
+	buckets *list.List // of *lfuBucket, lowest frequency first
+	entries map[K]*lfuEntry[K]
 }
 
-func (l *lfu) evict(c *cache) {
-	fmt.Println("Evicting by lfu strtegy")
+type lfuBucket struct {
+	frequency int
+	keys      *list.List // least recently used first
+}
+
+type lfuEntry[K comparable] struct {
+	bucket  *list.Element
+	element *list.Element
+}
+
+func newLfu[K comparable]() *lfu[K] {
+	return &lfu[K]{buckets: list.New(), entries: map[K]*lfuEntry[K]{}}
+}
+
+func (l *lfu[K]) add(key K) {
+	first := l.buckets.Front()
+	if first == nil || first.Value.(*lfuBucket).frequency != 1 {
+		first = l.buckets.PushFront(&lfuBucket{frequency: 1, keys: list.New()})
+	}
+	l.entries[key] = &lfuEntry[K]{bucket: first, element: first.Value.(*lfuBucket).keys.PushBack(key)}
+}
+
+func (l *lfu[K]) access(key K) {
+	entry, ok := l.entries[key]
+	if !ok {
+		return
+	}
+	current := entry.bucket.Value.(*lfuBucket)
+	next := entry.bucket.Next()
+	if next == nil || next.Value.(*lfuBucket).frequency != current.frequency+1 {
+		next = l.buckets.InsertAfter(&lfuBucket{frequency: current.frequency + 1, keys: list.New()}, entry.bucket)
+	}
+	current.keys.Remove(entry.element)
+	l.dropIfEmpty(entry.bucket)
+	entry.bucket = next
+	entry.element = next.Value.(*lfuBucket).keys.PushBack(key)
+}
+
+func (l *lfu[K]) remove(key K) {
+	entry, ok := l.entries[key]
+	if !ok {
+		return
+	}
+	entry.bucket.Value.(*lfuBucket).keys.Remove(entry.element)
+	l.dropIfEmpty(entry.bucket)
+	delete(l.entries, key)
+}
+
+func (l *lfu[K]) dropIfEmpty(bucket *list.Element) {
+	if bucket.Value.(*lfuBucket).keys.Len() == 0 {
+		l.buckets.Remove(bucket)
+	}
+}
+
+func (l *lfu[K]) victim(incoming K) (K, bool) {
+	first := l.buckets.Front()
+	if first == nil {
+		var zero K
+		return zero, false
+	}
+	key := first.Value.(*lfuBucket).keys.Front().Value.(K)
+	l.remove(key)
+	return key, true
+}
+
+func (l *lfu[K]) keys() []K {
+	keys := make([]K, 0, len(l.entries))
+	for b := l.buckets.Front(); b != nil; b = b.Next() {
+		keys = append(keys, listKeys[K](b.Value.(*lfuBucket).keys)...)
+	}
+	return keys
 }
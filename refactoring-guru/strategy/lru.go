@@ -1,10 +1,38 @@
 package main
 
-import "fmt"
+import "container/list"
 
-type lru struct {
+// lru evicts the key that was used the longest time ago
+type lru[K comparable] struct {
+	order    *list.List // least recently used first
+	elements map[K]*list.Element
 }
 
-func (l *lru) evict(c *cache) {
-	fmt.Println("Evicting by lru strtegy")
+func newLru[K comparable]() *lru[K] {
+	return &lru[K]{order: list.New(), elements: map[K]*list.Element{}}
+}
+
+func (l *lru[K]) add(key K) {
+	l.elements[key] = l.order.PushBack(key)
+}
+
+func (l *lru[K]) access(key K) {
+	if e, ok := l.elements[key]; ok {
+		l.order.MoveToBack(e)
+	}
+}
+
+func (l *lru[K]) remove(key K) {
+	if e, ok := l.elements[key]; ok {
+		l.order.Remove(e)
+		delete(l.elements, key)
+	}
+}
+
+func (l *lru[K]) victim(incoming K) (K, bool) {
+	return popKey(l.order, l.order.Front(), l.elements)
+}
+
+func (l *lru[K]) keys() []K {
+	return listKeys[K](l.order)
 }
@@ -1,22 +1,73 @@
 package main
 
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+const capacity = 100
+
 func main() {
-	lfu := &lfu{}
-	cache := initCache(lfu)
+	cache := initCache[string, string](newLfu[string](), 2)
 
 	cache.add("a", "1")
 	cache.add("b", "2")
+	cache.get("a")
+
+	cache.add("c", "3") // b is the least frequently used
+	printCache(cache, "a", "b", "c")
 
-	cache.add("c", "3")
+	cache.setEvictionAlgo(newLru[string]())
+	cache.get("a")
 
-	lru := &lru{}
-	cache.setEvictionAlgo(lru)
+	cache.add("d", "4") // c is the least recently used
+	printCache(cache, "a", "c", "d")
 
-	cache.add("d", "4")
+	cache.setEvictionAlgo(newFifo[string]())
 
-	fifo := &fifo{}
-	cache.setEvictionAlgo(fifo)
+	cache.add("e", "5") // a was the first in
+	printCache(cache, "a", "d", "e")
 
-	cache.add("e", "5")
+	fmt.Println()
+
+	algos := []struct {
+		name string
+		new  func() evictionAlgo[int]
+	}{
+		{"fifo", func() evictionAlgo[int] { return newFifo[int]() }},
+		{"lru", func() evictionAlgo[int] { return newLru[int]() }},
+		{"lfu", func() evictionAlgo[int] { return newLfu[int]() }},
+		{"arc", func() evictionAlgo[int] { return newArc[int]() }},
+		{"ttl", func() evictionAlgo[int] { return newTtl[int](time.Hour, nil) }},
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(w, "hit rate\t")
+	for _, wl := range workloads {
+		fmt.Fprintf(w, "%s\t", wl.name)
+	}
+	fmt.Fprintln(w)
+	for _, algo := range algos {
+		fmt.Fprintf(w, "%s\t", algo.name)
+		for _, wl := range workloads {
+			keys := wl.keys(rand.New(rand.NewSource(1)), 100_000)
+			stats := simulate(initCache[int, int](algo.new(), capacity), keys)
+			fmt.Fprintf(w, "%.1f%%\t", 100*stats.hitRate())
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
 
+func printCache(c *cache[string, string], keys ...string) {
+	for _, key := range keys {
+		if value, ok := c.get(key); ok {
+			fmt.Printf("%s=%s ", key, value)
+		} else {
+			fmt.Printf("%s evicted ", key)
+		}
+	}
+	fmt.Println()
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"container/list"
+	"time"
+)
+
+// ttl expires keys that were not used for a while, and evicts the key closest to expiring when the cache is full.
+// Every use moves the deadline of a key by the same duration, so keys stay sorted by deadline in a list.
+type ttl[K comparable] struct {
+	duration time.Duration
+	now      func() time.Time
+	order    *list.List // of *ttlEntry, earliest deadline first
+	elements map[K]*list.Element
+}
+
+type ttlEntry[K comparable] struct {
+	key      K
+	deadline time.Time
+}
+
+func newTtl[K comparable](duration time.Duration, now func() time.Time) *ttl[K] {
+	if now == nil {
+		now = time.Now
+	}
+	return &ttl[K]{duration: duration, now: now, order: list.New(), elements: map[K]*list.Element{}}
+}
+
+func (t *ttl[K]) add(key K) {
+	t.elements[key] = t.order.PushBack(&ttlEntry[K]{key: key, deadline: t.now().Add(t.duration)})
+}
+
+func (t *ttl[K]) access(key K) {
+	if e, ok := t.elements[key]; ok {
+		e.Value.(*ttlEntry[K]).deadline = t.now().Add(t.duration)
+		t.order.MoveToBack(e)
+	}
+}
+
+func (t *ttl[K]) remove(key K) {
+	if e, ok := t.elements[key]; ok {
+		t.order.Remove(e)
+		delete(t.elements, key)
+	}
+}
+
+func (t *ttl[K]) expired(key K) bool {
+	e, ok := t.elements[key]
+	return ok && !t.now().Before(e.Value.(*ttlEntry[K]).deadline)
+}
+
+func (t *ttl[K]) victim(incoming K) (K, bool) {
+	first := t.order.Front()
+	if first == nil {
+		var zero K
+		return zero, false
+	}
+	key := t.order.Remove(first).(*ttlEntry[K]).key
+	delete(t.elements, key)
+	return key, true
+}
+
+func (t *ttl[K]) keys() []K {
+	keys := make([]K, 0, t.order.Len())
+	for e := t.order.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(*ttlEntry[K]).key)
+	}
+	return keys
+}
@@ -0,0 +1,54 @@
+package main
+
+import "math/rand"
+
+// Synthetic workloads, to compare the hit rates of the algorithms
+type workload struct {
+	name string
+	keys func(r *rand.Rand, n int) []int
+}
+
+var workloads = []workload{
+	// a few keys are very popular, most are rarely used
+	{name: "zipf", keys: func(r *rand.Rand, n int) []int {
+		zipf := rand.NewZipf(r, 1.2, 1, 999)
+		keys := make([]int, n)
+		for i := range keys {
+			keys[i] = int(zipf.Uint64())
+		}
+		return keys
+	}},
+	// a hot set used all the time, interrupted by long scans of keys used only once
+	{name: "scan", keys: func(r *rand.Rand, n int) []int {
+		keys := make([]int, 0, n)
+		next := 1000
+		for len(keys) < n {
+			for i := 0; i < 200 && len(keys) < n; i++ {
+				keys = append(keys, r.Intn(50))
+			}
+			for i := 0; i < 100 && len(keys) < n; i++ {
+				keys = append(keys, next)
+				next++
+			}
+		}
+		return keys
+	}},
+	// the same keys over and over, a few more of them than the cache holds
+	{name: "loop", keys: func(r *rand.Rand, n int) []int {
+		keys := make([]int, n)
+		for i := range keys {
+			keys[i] = i % 120
+		}
+		return keys
+	}},
+}
+
+// simulate reads every key through the cache, adding it on a miss
+func simulate(c *cache[int, int], keys []int) cacheStats {
+	for _, key := range keys {
+		if _, ok := c.get(key); !ok {
+			c.add(key, key)
+		}
+	}
+	return c.getStats()
+}
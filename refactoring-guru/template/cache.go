@@ -0,0 +1,76 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// otpCache keeps the last otp sent to each recipient until it is used, expires or is guessed at too many times
+type otpCache struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	maxAttempts int
+	now         func() time.Time
+	entries     map[string]*otpEntry
+}
+
+type otpEntry struct {
+	otp      string
+	expires  time.Time
+	attempts int
+}
+
+// defaultMaxOTPAttempts is used when newOTPCache is given no positive maximum, which would reject even the first attempt
+const defaultMaxOTPAttempts = 3
+
+func newOTPCache(ttl time.Duration, maxAttempts int, now func() time.Time) *otpCache {
+	if now == nil {
+		now = time.Now
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxOTPAttempts
+	}
+	return &otpCache{ttl: ttl, maxAttempts: maxAttempts, now: now, entries: map[string]*otpEntry{}}
+}
+
+// save replaces any otp previously sent to the recipient
+func (c *otpCache) save(recipient, otp string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.purge()
+	c.entries[recipient] = &otpEntry{otp: otp, expires: c.now().Add(c.ttl)}
+}
+
+// verify counts an attempt at the otp sent to the recipient, which matches tells whether it succeeded
+func (c *otpCache) verify(recipient string, matches func(sent string) bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[recipient]
+	if !ok {
+		return errNoOTP
+	}
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, recipient)
+		return errOTPExpired
+	}
+	entry.attempts++
+	if matches(entry.otp) {
+		delete(c.entries, recipient)
+		return nil
+	}
+	if entry.attempts >= c.maxAttempts {
+		delete(c.entries, recipient)
+		return errTooManyOTPAttempts
+	}
+	return errWrongOTP
+}
+
+// purge drops the expired otps, so that recipients who never verify do not fill the cache
+func (c *otpCache) purge() {
+	now := c.now()
+	for recipient, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, recipient)
+		}
+	}
+}
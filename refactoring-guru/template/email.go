@@ -1,30 +1,18 @@
 package main
 
-import "fmt"
-
 type email struct {
-	otp
-}
-
-func (s *email) genRandomOTP(len int) string {
-	randomOTP := "1234"
-	fmt.Printf("EMAIL: generating random otp %s\n", randomOTP)
-	return randomOTP
+	otpSteps
+	sender sender
 }
 
-func (s *email) saveOTPCache(otp string) {
-	fmt.Printf("EMAIL: saving otp: %s to cache\n", otp)
+func newEmail(generator codeGenerator, cache *otpCache, sender sender, hooks ...metricHook) *email {
+	return &email{otpSteps: otpSteps{channel: "email", generator: generator, cache: cache, hooks: hooks}, sender: sender}
 }
 
 func (s *email) getMessage(otp string) string {
 	return "EMAIL OTP for login is " + otp
 }
 
-func (s *email) sendNotification(message string) error {
-	fmt.Printf("EMAIL: sending email: %s\n", message)
-	return nil
-}
-
-func (s *email) publishMetric() {
-	fmt.Printf("EMAIL: publishing metrics\n")
+func (s *email) sendNotification(recipient, message string) error {
+	return s.sender.send(recipient, "Subject: Your login code\n\n"+message)
 }
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+var (
+	errInvalidCodeLength = errors.New("the code length must be positive")
+	errInvalidPeriod     = errors.New("the period must be at least a second")
+	errUnsupportedLength = errors.New("the code length differs from the digits of the generator")
+	errUnknownRecipient  = errors.New("no secret is shared with this recipient")
+)
+
+// codeGenerator generates the otp sent to a recipient
+type codeGenerator interface {
+	generate(recipient string, length int) (string, error)
+}
+
+// codeVerifier is implemented by generators that also verify codes themselves, on top of comparing them with the code sent
+type codeVerifier interface {
+	verify(recipient, code string) bool
+}
+
+const (
+	digits        = "0123456789"
+	defaultDigits = 6 // of HOTP and TOTP codes, as most authenticators show them
+)
+
+// randomCode draws every character uniformly from the alphabet with a cryptographic random source
+type randomCode struct {
+	alphabet string // digits when empty
+}
+
+func (g randomCode) generate(recipient string, length int) (string, error) {
+	if length <= 0 {
+		return "", errInvalidCodeLength
+	}
+	alphabet := g.alphabet
+	if alphabet == "" {
+		alphabet = digits
+	}
+	max := big.NewInt(int64(len(alphabet)))
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = alphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// hotpCode is the HMAC-based one-time password of RFC 4226
+func hotpCode(secret []byte, counter uint64, length int) string {
+	mac := hmac.New(sha1.New, secret)
+	binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0xf
+	value := uint64(binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff)
+	modulo := uint64(1)
+	for i := 0; i < length; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", length, value%modulo)
+}
+
+// codeDigits is the number of digits configured for an HOTP or TOTP generator, defaultDigits when zero
+func codeDigits(configured int) int {
+	if configured == 0 {
+		return defaultDigits
+	}
+	return configured
+}
+
+// checkLength rejects lengths other than the digits configured, since a shorter code would be checked modulo a smaller power of ten
+func checkLength(length, configured int) error {
+	if length <= 0 {
+		return errInvalidCodeLength
+	}
+	if length != codeDigits(configured) {
+		return errUnsupportedLength
+	}
+	return nil
+}
+
+// hotp generates codes from a counter of each recipient that moves on with every code.
+// The other side keeps its own counter, and catches up within lookAhead codes.
+type hotp struct {
+	mu        sync.Mutex
+	secrets   map[string][]byte // shared with each recipient, as enrolled in their authenticator
+	digits    int               // of every code, defaultDigits when zero
+	lookAhead int
+	counters  map[string]*hotpCounter
+}
+
+type hotpCounter struct {
+	issued   uint64 // counter of the next code generated
+	verified uint64 // counter of the first code accepted, which is the last one issued
+}
+
+func (g *hotp) counter(recipient string) *hotpCounter {
+	if g.counters == nil {
+		g.counters = map[string]*hotpCounter{}
+	}
+	c, ok := g.counters[recipient]
+	if !ok {
+		c = &hotpCounter{}
+		g.counters[recipient] = c
+	}
+	return c
+}
+
+// generate issues the code of the next counter, and invalidates every code issued before it
+func (g *hotp) generate(recipient string, length int) (string, error) {
+	if err := checkLength(length, g.digits); err != nil {
+		return "", err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	secret, ok := g.secrets[recipient]
+	if !ok {
+		return "", errUnknownRecipient
+	}
+	c := g.counter(recipient)
+	if c.verified < c.issued {
+		c.verified = c.issued
+	}
+	code := hotpCode(secret, c.issued, length)
+	c.issued++
+	return code, nil
+}
+
+// verify accepts the code of the last counter issued or of up to lookAhead counters after it,
+// and moves the counter past it
+func (g *hotp) verify(recipient, code string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	secret, ok := g.secrets[recipient]
+	if !ok || checkLength(len(code), g.digits) != nil {
+		return false
+	}
+	c := g.counter(recipient)
+	for n := c.verified; n <= c.verified+uint64(g.lookAhead); n++ {
+		if hmac.Equal([]byte(hotpCode(secret, n, len(code))), []byte(code)) {
+			c.verified = n + 1
+			if c.issued < c.verified {
+				c.issued = c.verified
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// totp is the time-based one-time password of RFC 6238: the counter is the number of periods since the Unix epoch
+type totp struct {
+	secrets map[string][]byte // shared with each recipient, as enrolled in their authenticator
+	digits  int               // of every code, defaultDigits when zero
+	period  time.Duration     // 30 seconds when zero
+	skew    int               // periods accepted before and after the current one, for clocks out of sync
+	now     func() time.Time
+}
+
+func (g totp) counter(t time.Time) (uint64, error) {
+	period := g.period
+	if period == 0 {
+		period = 30 * time.Second
+	}
+	if period < time.Second {
+		return 0, errInvalidPeriod
+	}
+	return uint64(t.Unix() / int64(period/time.Second)), nil
+}
+
+func (g totp) time() time.Time {
+	if g.now == nil {
+		return time.Now()
+	}
+	return g.now()
+}
+
+func (g totp) generate(recipient string, length int) (string, error) {
+	if err := checkLength(length, g.digits); err != nil {
+		return "", err
+	}
+	secret, ok := g.secrets[recipient]
+	if !ok {
+		return "", errUnknownRecipient
+	}
+	counter, err := g.counter(g.time())
+	if err != nil {
+		return "", err
+	}
+	return hotpCode(secret, counter, length), nil
+}
+
+func (g totp) verify(recipient, code string) bool {
+	secret, ok := g.secrets[recipient]
+	if !ok || checkLength(len(code), g.digits) != nil {
+		return false
+	}
+	current, err := g.counter(g.time())
+	if err != nil {
+		return false
+	}
+	for d := -g.skew; d <= g.skew; d++ {
+		if hmac.Equal([]byte(hotpCode(secret, current+uint64(d), len(code))), []byte(code)) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,32 +1,45 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 func main() {
-	// otp := otp{}
-
-	// smsOTP := &sms{
-	// 	otp: otp,
-	// }
-
-	// smsOTP.genAndSendOTP(smsOTP, 4)
+	cache := newOTPCache(5*time.Minute, 3, nil)
+	printMetric := func(m metric) {
+		if m.err != nil {
+			fmt.Printf("%s: metric %s failed: %v\n", strings.ToUpper(m.channel), m.event, m.err)
+			return
+		}
+		fmt.Printf("%s: metric %s\n", strings.ToUpper(m.channel), m.event)
+	}
 
-	// emailOTP := &email{
-	// 	otp: otp,
-	// }
-	// emailOTP.genAndSendOTP(emailOTP, 4)
-	// fmt.Scanln()
-	smsOTP := &sms{}
+	// RFC 4226 test secret, as enrolled in the authenticator of each recipient
+	secret := []byte("12345678901234567890")
+	const phone = "+55 51 99999-9999"
 	o := otp{
-		iOtp: smsOTP,
+		iOtp: newSms(&hotp{secrets: map[string][]byte{phone: secret}, lookAhead: 3}, cache, consoleSender{channel: "sms"}, printMetric),
 	}
-	o.genAndSendOTP(4)
+	o.genAndSendOTP(phone, 6)
+	fmt.Println(o.verifyOTP(phone, "not it"))
+	code := hotpCode(secret, 0, 6) // as the authenticator of the recipient computes it
+	fmt.Println(o.verifyOTP(phone, code))
+	fmt.Println(o.verifyOTP(phone, code))
 
 	fmt.Println("")
-	emailOTP := &email{}
 	o = otp{
-		iOtp: emailOTP,
+		iOtp: newEmail(&hotp{secrets: map[string][]byte{"someone@example.com": secret}}, cache, consoleSender{channel: "email"}, printMetric),
+	}
+	o.genAndSendOTP("someone@example.com", 6)
+	for i := 0; i < 3; i++ {
+		fmt.Println(o.verifyOTP("someone@example.com", "guess"))
 	}
-	o.genAndSendOTP(4)
 
+	fmt.Println("")
+	// at the time of the first test vector of RFC 6238, which uses the same secret
+	generator := totp{secrets: map[string][]byte{"someone@example.com": secret}, digits: 8, now: func() time.Time { return time.Unix(59, 0) }}
+	code, _ = generator.generate("someone@example.com", 8)
+	fmt.Printf("TOTP at 59s: %s, valid: %v\n", code, generator.verify("someone@example.com", code))
 }
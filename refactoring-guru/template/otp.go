@@ -1,40 +1,93 @@
 package main
 
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+)
+
+var (
+	errNoOTP              = errors.New("no otp was sent to this recipient")
+	errOTPExpired         = errors.New("otp expired")
+	errWrongOTP           = errors.New("wrong otp")
+	errTooManyOTPAttempts = errors.New("too many attempts, request a new otp")
+)
+
+// iOtp are the steps of sending an otp, which differ with the channel
 type iOtp interface {
-	genRandomOTP(int) string
-	saveOTPCache(string)
+	genRandomOTP(recipient string, length int) (string, error)
+	saveOTPCache(recipient, otp string)
 	getMessage(string) string
-	sendNotification(string) error
-	publishMetric()
+	sendNotification(recipient, message string) error
+	publishMetric(m metric)
+	checkOTP(recipient, otp string) error
 }
 
-// type otp struct {
-// }
+type metric struct {
+	channel string
+	event   string // generated, sent or verified
+	err     error
+}
 
-// func (o *otp) genAndSendOTP(iOtp iOtp, otpLength int) error {
-// 	otp := iOtp.genRandomOTP(otpLength)
-// 	iOtp.saveOTPCache(otp)
-// 	message := iOtp.getMessage(otp)
-// 	err := iOtp.sendNotification(message)
-// 	if err != nil {
-// 		return err
-// 	}
-// 	iOtp.publishMetric()
-// 	return nil
-// }
+type metricHook func(metric)
 
 type otp struct {
 	iOtp iOtp
 }
 
-func (o *otp) genAndSendOTP(otpLength int) error {
-	otp := o.iOtp.genRandomOTP(otpLength)
-	o.iOtp.saveOTPCache(otp)
-	message := o.iOtp.getMessage(otp)
-	err := o.iOtp.sendNotification(message)
+func (o *otp) genAndSendOTP(recipient string, otpLength int) error {
+	otp, err := o.iOtp.genRandomOTP(recipient, otpLength)
 	if err != nil {
 		return err
 	}
-	o.iOtp.publishMetric()
+	o.iOtp.publishMetric(metric{event: "generated"})
+	o.iOtp.saveOTPCache(recipient, otp)
+	message := o.iOtp.getMessage(otp)
+	err = o.iOtp.sendNotification(recipient, message)
+	o.iOtp.publishMetric(metric{event: "sent", err: err})
+	if err != nil {
+		return fmt.Errorf("sending otp to %s: %w", recipient, err)
+	}
 	return nil
 }
+
+// verifyOTP checks the otp entered by the recipient. An otp can only be used once.
+func (o *otp) verifyOTP(recipient, otp string) error {
+	err := o.iOtp.checkOTP(recipient, otp)
+	o.iOtp.publishMetric(metric{event: "verified", err: err})
+	return err
+}
+
+// otpSteps are the steps shared by every channel: generating, caching and checking the otp, and publishing metrics
+type otpSteps struct {
+	channel   string
+	generator codeGenerator
+	cache     *otpCache
+	hooks     []metricHook
+}
+
+func (s *otpSteps) genRandomOTP(recipient string, length int) (string, error) {
+	return s.generator.generate(recipient, length)
+}
+
+func (s *otpSteps) saveOTPCache(recipient, otp string) {
+	s.cache.save(recipient, otp)
+}
+
+// checkOTP compares the otp with the one sent, and also has the generator verify it when it can
+func (s *otpSteps) checkOTP(recipient, otp string) error {
+	verifier, verifies := s.generator.(codeVerifier)
+	return s.cache.verify(recipient, func(sent string) bool {
+		if subtle.ConstantTimeCompare([]byte(sent), []byte(otp)) != 1 {
+			return false
+		}
+		return !verifies || verifier.verify(recipient, otp)
+	})
+}
+
+func (s *otpSteps) publishMetric(m metric) {
+	m.channel = s.channel
+	for _, hook := range s.hooks {
+		hook(m)
+	}
+}
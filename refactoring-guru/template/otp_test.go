@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// secret of the test vectors of RFC 4226 and RFC 6238
+var rfcSecret = []byte("12345678901234567890")
+
+func TestHotp(t *testing.T) {
+	vectors := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	g := &hotp{secrets: map[string][]byte{"alice": rfcSecret, "bob": rfcSecret}}
+	for counter, expected := range vectors {
+		code, err := g.generate("alice", 6)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code, "counter %d", counter)
+	}
+	code, _ := g.generate("bob", 6)
+	assert.Equal(t, vectors[0], code, "every recipient has a counter of their own")
+	_, err := g.generate("carol", 6)
+	assert.ErrorIs(t, err, errUnknownRecipient)
+
+	verifier := &hotp{secrets: map[string][]byte{"alice": rfcSecret, "bob": rfcSecret}, lookAhead: 3}
+	assert.True(t, verifier.verify("alice", "755224"))
+	assert.False(t, verifier.verify("alice", "755224"), "codes cannot be used twice")
+	assert.True(t, verifier.verify("bob", "755224"))
+	assert.True(t, verifier.verify("alice", "338314"), "counter 4 is within the look ahead of counter 1")
+	assert.False(t, verifier.verify("alice", "520489"), "counter 9 is beyond the look ahead of counter 5")
+	assert.True(t, verifier.verify("alice", "254676"))
+	assert.False(t, verifier.verify("carol", "755224"))
+	assert.False(t, verifier.verify("bob", "2"), "codes shorter than the digits configured")
+
+	g = &hotp{secrets: map[string][]byte{"alice": rfcSecret}, lookAhead: 1}
+	first, _ := g.generate("alice", 6)
+	second, _ := g.generate("alice", 6)
+	assert.False(t, g.verify("alice", first), "a new code replaces the previous one")
+	assert.False(t, g.verify("alice", vectors[3]), "counter 3 is beyond the look ahead of counter 1")
+	assert.True(t, g.verify("alice", second))
+	_, err = g.generate("alice", 8)
+	assert.ErrorIs(t, err, errUnsupportedLength)
+}
+
+func TestTotp(t *testing.T) {
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for seconds, expected := range vectors {
+		g := totp{secrets: map[string][]byte{"alice": rfcSecret}, digits: 8, now: func() time.Time { return time.Unix(seconds, 0) }}
+		code, err := g.generate("alice", 8)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code, "at %d", seconds)
+	}
+
+	now := time.Unix(1111111111, 0)
+	g := totp{secrets: map[string][]byte{"alice": rfcSecret}, skew: 1, now: func() time.Time { return now }}
+	code, _ := g.generate("alice", 6)
+	now = now.Add(30 * time.Second)
+	assert.True(t, g.verify("alice", code), "one period late")
+	now = now.Add(30 * time.Second)
+	assert.False(t, g.verify("alice", code), "two periods late")
+	now = time.Unix(1111111111, 0)
+	assert.False(t, g.verify("alice", code[5:]), "codes shorter than the digits configured")
+	_, err := g.generate("alice", 8)
+	assert.ErrorIs(t, err, errUnsupportedLength)
+
+	for _, period := range []time.Duration{-30 * time.Second, time.Millisecond} {
+		g := totp{secrets: map[string][]byte{"alice": rfcSecret}, period: period}
+		_, err = g.generate("alice", 6)
+		assert.ErrorIs(t, err, errInvalidPeriod, period)
+		assert.False(t, g.verify("alice", "000000"), period)
+	}
+}
+
+func TestNonPositiveCodeLength(t *testing.T) {
+	generators := map[string]codeGenerator{
+		"random": randomCode{},
+		"hotp":   &hotp{secrets: map[string][]byte{"alice": rfcSecret}},
+		"totp":   totp{secrets: map[string][]byte{"alice": rfcSecret}},
+	}
+	for name, g := range generators {
+		for _, length := range []int{0, -1} {
+			_, err := g.generate("alice", length)
+			assert.ErrorIs(t, err, errInvalidCodeLength, "%s with length %d", name, length)
+		}
+	}
+}
+
+func TestRandomCode(t *testing.T) {
+	code, err := randomCode{}.generate("alice", 6)
+	assert.NoError(t, err)
+	assert.Regexp(t, `^[0-9]{6}$`, code)
+
+	seen := map[rune]bool{}
+	for i := 0; i < 50; i++ {
+		code, err := randomCode{alphabet: "AB"}.generate("alice", 10)
+		assert.NoError(t, err)
+		assert.Len(t, code, 10)
+		for _, r := range code {
+			seen[r] = true
+		}
+	}
+	assert.Equal(t, map[rune]bool{'A': true, 'B': true}, seen)
+}
+
+func TestOTP(t *testing.T) {
+	const recipient = "someone@example.com"
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	setup := func(maxAttempts int) (*otp, *fakeSender, *[]metric) {
+		sender := &fakeSender{}
+		metrics := &[]metric{}
+		cache := newOTPCache(time.Minute, maxAttempts, func() time.Time { return now })
+		o := &otp{iOtp: newEmail(randomCode{}, cache, sender, func(m metric) { *metrics = append(*metrics, m) })}
+		return o, sender, metrics
+	}
+	sentCode := func(sender *fakeSender) string {
+		message := sender.last().message
+		return message[strings.LastIndex(message, " ")+1:]
+	}
+
+	t.Run("Should send and verify an otp once", func(t *testing.T) {
+		o, sender, metrics := setup(3)
+
+		assert.NoError(t, o.genAndSendOTP(recipient, 6))
+		assert.Equal(t, recipient, sender.last().recipient)
+		assert.Contains(t, sender.last().message, "EMAIL OTP for login is ")
+		code := sentCode(sender)
+		assert.Len(t, code, 6)
+
+		assert.NoError(t, o.verifyOTP(recipient, code))
+		assert.ErrorIs(t, o.verifyOTP(recipient, code), errNoOTP)
+		assert.Equal(t, []metric{
+			{channel: "email", event: "generated"},
+			{channel: "email", event: "sent"},
+			{channel: "email", event: "verified"},
+			{channel: "email", event: "verified", err: errNoOTP},
+		}, *metrics)
+	})
+
+	t.Run("Should limit the attempts", func(t *testing.T) {
+		o, sender, _ := setup(3)
+		o.genAndSendOTP(recipient, 6)
+		code := sentCode(sender)
+
+		assert.ErrorIs(t, o.verifyOTP(recipient, "wrong"), errWrongOTP)
+		assert.ErrorIs(t, o.verifyOTP(recipient, "wrong"), errWrongOTP)
+		assert.ErrorIs(t, o.verifyOTP(recipient, "wrong"), errTooManyOTPAttempts)
+		assert.ErrorIs(t, o.verifyOTP(recipient, code), errNoOTP, "the otp was dropped")
+	})
+
+	t.Run("Should allow the default attempts without a maximum", func(t *testing.T) {
+		o, sender, _ := setup(0)
+		o.genAndSendOTP(recipient, 6)
+		code := sentCode(sender)
+
+		assert.ErrorIs(t, o.verifyOTP(recipient, "wrong"), errWrongOTP)
+		assert.NoError(t, o.verifyOTP(recipient, code))
+	})
+
+	t.Run("Should expire otps", func(t *testing.T) {
+		o, sender, _ := setup(3)
+		o.genAndSendOTP(recipient, 6)
+		code := sentCode(sender)
+
+		now = now.Add(time.Minute)
+
+		assert.ErrorIs(t, o.verifyOTP(recipient, code), errOTPExpired)
+	})
+
+	t.Run("Should only accept the last otp sent", func(t *testing.T) {
+		o, sender, _ := setup(3)
+		o.genAndSendOTP(recipient, 6)
+		first := sentCode(sender)
+		o.genAndSendOTP(recipient, 8)
+		second := sentCode(sender)
+
+		assert.ErrorIs(t, o.verifyOTP(recipient, first), errWrongOTP)
+		assert.NoError(t, o.verifyOTP(recipient, second))
+	})
+
+	t.Run("Should report senders failing", func(t *testing.T) {
+		o, sender, metrics := setup(3)
+		errDown := errors.New("mail server down")
+		sender.err = errDown
+
+		assert.ErrorIs(t, o.genAndSendOTP(recipient, 6), errDown)
+		assert.Equal(t, metric{channel: "email", event: "sent", err: errDown}, (*metrics)[1])
+	})
+
+	t.Run("Should verify otps with the generator", func(t *testing.T) {
+		sender := &fakeSender{}
+		cache := newOTPCache(time.Minute, 3, func() time.Time { return now })
+		o := &otp{iOtp: newSms(&hotp{secrets: map[string][]byte{recipient: rfcSecret}, lookAhead: 1}, cache, sender)}
+
+		assert.NoError(t, o.genAndSendOTP(recipient, 6))
+		assert.Equal(t, "755224", sentCode(sender))
+		assert.ErrorIs(t, o.verifyOTP(recipient, hotpCode(rfcSecret, 1, 6)), errWrongOTP, "valid for the generator, but not the code sent")
+		assert.NoError(t, o.verifyOTP(recipient, "755224"))
+
+		assert.NoError(t, o.genAndSendOTP(recipient, 6))
+		assert.Equal(t, hotpCode(rfcSecret, 1, 6), sentCode(sender), "the counter moved past the code verified")
+		now = now.Add(time.Minute)
+		assert.ErrorIs(t, o.verifyOTP(recipient, sentCode(sender)), errOTPExpired, "the generator still accepts the code")
+	})
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type sender interface {
+	send(recipient, message string) error
+}
+
+// consoleSender prints the messages instead of sending them
+type consoleSender struct {
+	channel string
+}
+
+func (s consoleSender) send(recipient, message string) error {
+	fmt.Printf("%s: sending to %s: %s\n", strings.ToUpper(s.channel), recipient, strings.ReplaceAll(message, "\n", " "))
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import "sync"
+
+type sentMessage struct {
+	recipient string
+	message   string
+}
+
+// fakeSender keeps the messages, and fails with err when it is set
+type fakeSender struct {
+	mu   sync.Mutex
+	sent []sentMessage
+	err  error
+}
+
+func (s *fakeSender) send(recipient, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, sentMessage{recipient: recipient, message: message})
+	return nil
+}
+
+func (s *fakeSender) last() sentMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sent[len(s.sent)-1]
+}
@@ -1,30 +1,18 @@
 package main
 
-import "fmt"
-
 type sms struct {
-	otp
-}
-
-func (s *sms) genRandomOTP(len int) string {
-	randomOTP := "1234"
-	fmt.Printf("SMS: generating random otp %s\n", randomOTP)
-	return randomOTP
+	otpSteps
+	sender sender
 }
 
-func (s *sms) saveOTPCache(otp string) {
-	fmt.Printf("SMS: saving otp: %s to cache\n", otp)
+func newSms(generator codeGenerator, cache *otpCache, sender sender, hooks ...metricHook) *sms {
+	return &sms{otpSteps: otpSteps{channel: "sms", generator: generator, cache: cache, hooks: hooks}, sender: sender}
 }
 
 func (s *sms) getMessage(otp string) string {
 	return "SMS OTP for login is " + otp
 }
 
-func (s *sms) sendNotification(message string) error {
-	fmt.Printf("SMS: sending sms: %s\n", message)
-	return nil
-}
-
-func (s *sms) publishMetric() {
-	fmt.Printf("SMS: publishing metrics\n")
+func (s *sms) sendNotification(recipient, message string) error {
+	return s.sender.send(recipient, message)
 }
@@ -1,22 +1,27 @@
 package main
 
-import (
-	"fmt"
-)
+import "math"
 
 type areaCalculator struct {
-	area int
+	area float64
 }
 
 func (a *areaCalculator) visitForSquare(s *square) {
-	// Calculate area for square.
-	// Then assign in to the area instance variable.
-	fmt.Println("Calculating area for square")
+	a.area = s.side * s.side
 }
 
-func (a *areaCalculator) visitForCircle(s *circle) {
-	fmt.Println("Calculating area for circle")
+func (a *areaCalculator) visitForCircle(c *circle) {
+	a.area = math.Pi * c.radius * c.radius
 }
-func (a *areaCalculator) visitForrectangle(s *rectangle) {
-	fmt.Println("Calculating area for rectangle")
+
+func (a *areaCalculator) visitForrectangle(r *rectangle) {
+	a.area = r.l * r.b
+}
+
+func (a *areaCalculator) visitForTriangle(t *triangle) {
+	a.area = math.Abs(signedArea(t.vertices()))
+}
+
+func (a *areaCalculator) visitForPolygon(p *polygon) {
+	a.area = math.Abs(signedArea(p.points))
 }
@@ -0,0 +1,28 @@
+package main
+
+type boundingBox struct {
+	box box
+}
+
+func (a *boundingBox) visitForSquare(s *square) {
+	a.box = box{s.corner, point{s.corner.x + s.side, s.corner.y + s.side}}
+}
+
+func (a *boundingBox) visitForCircle(c *circle) {
+	a.box = box{
+		point{c.center.x - c.radius, c.center.y - c.radius},
+		point{c.center.x + c.radius, c.center.y + c.radius},
+	}
+}
+
+func (a *boundingBox) visitForrectangle(r *rectangle) {
+	a.box = boxOf(r.vertices())
+}
+
+func (a *boundingBox) visitForTriangle(t *triangle) {
+	a.box = boxOf(t.vertices())
+}
+
+func (a *boundingBox) visitForPolygon(p *polygon) {
+	a.box = boxOf(p.points)
+}
@@ -1,7 +1,8 @@
 package main
 
 type circle struct {
-	radius int
+	center point
+	radius float64
 }
 
 func (c *circle) accept(v visitor) {
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownShape = errors.New("unknown shape")
+	ErrInvalidShape = errors.New("invalid shape")
+)
+
+// jsonShape is how every shape is serialized, with the fields its type uses
+type jsonShape struct {
+	Type   string       `json:"type"`
+	Corner *[2]float64  `json:"corner,omitempty"`
+	Center *[2]float64  `json:"center,omitempty"`
+	Side   float64      `json:"side,omitempty"`
+	Radius float64      `json:"radius,omitempty"`
+	Width  float64      `json:"width,omitempty"`
+	Height float64      `json:"height,omitempty"`
+	Points [][2]float64 `json:"points,omitempty"`
+}
+
+func toJSON(p point) *[2]float64 {
+	return &[2]float64{p.x, p.y}
+}
+
+func pointsToJSON(points []point) [][2]float64 {
+	out := make([][2]float64, len(points))
+	for i, p := range points {
+		out[i] = *toJSON(p)
+	}
+	return out
+}
+
+// jsonEncoder collects the shapes it visits, to marshal them as a JSON array
+type jsonEncoder struct {
+	shapes []jsonShape
+}
+
+func (a *jsonEncoder) visitForSquare(s *square) {
+	a.shapes = append(a.shapes, jsonShape{Type: "square", Corner: toJSON(s.corner), Side: s.side})
+}
+
+func (a *jsonEncoder) visitForCircle(c *circle) {
+	a.shapes = append(a.shapes, jsonShape{Type: "circle", Center: toJSON(c.center), Radius: c.radius})
+}
+
+func (a *jsonEncoder) visitForrectangle(r *rectangle) {
+	a.shapes = append(a.shapes, jsonShape{Type: "rectangle", Corner: toJSON(r.corner), Width: r.l, Height: r.b})
+}
+
+func (a *jsonEncoder) visitForTriangle(t *triangle) {
+	a.shapes = append(a.shapes, jsonShape{Type: "triangle", Points: pointsToJSON(t.vertices())})
+}
+
+func (a *jsonEncoder) visitForPolygon(p *polygon) {
+	a.shapes = append(a.shapes, jsonShape{Type: "polygon", Points: pointsToJSON(p.points)})
+}
+
+func (a *jsonEncoder) marshal() ([]byte, error) {
+	if a.shapes == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(a.shapes)
+}
+
+func encodeShapes(shapes ...shape) ([]byte, error) {
+	e := &jsonEncoder{}
+	for _, s := range shapes {
+		s.accept(e)
+	}
+	return e.marshal()
+}
+
+func decodeShapes(data []byte) ([]shape, error) {
+	var decoded []jsonShape
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	shapes := make([]shape, len(decoded))
+	for i, d := range decoded {
+		s, err := d.shape()
+		if err != nil {
+			return nil, fmt.Errorf("shape %d: %w", i, err)
+		}
+		shapes[i] = s
+	}
+	return shapes, nil
+}
+
+func fromJSON(p *[2]float64) point {
+	return point{p[0], p[1]}
+}
+
+func (d jsonShape) shape() (shape, error) {
+	points := make([]point, len(d.Points))
+	for i := range d.Points {
+		points[i] = fromJSON(&d.Points[i])
+	}
+
+	switch d.Type {
+	case "square":
+		if d.Corner == nil {
+			return nil, fmt.Errorf("%w: missing corner", ErrInvalidShape)
+		}
+		if d.Side < 0 {
+			return nil, fmt.Errorf("%w: negative side", ErrInvalidShape)
+		}
+		return &square{corner: fromJSON(d.Corner), side: d.Side}, nil
+	case "circle":
+		if d.Center == nil {
+			return nil, fmt.Errorf("%w: missing center", ErrInvalidShape)
+		}
+		if d.Radius < 0 {
+			return nil, fmt.Errorf("%w: negative radius", ErrInvalidShape)
+		}
+		return &circle{center: fromJSON(d.Center), radius: d.Radius}, nil
+	case "rectangle":
+		if d.Corner == nil {
+			return nil, fmt.Errorf("%w: missing corner", ErrInvalidShape)
+		}
+		if d.Width < 0 || d.Height < 0 {
+			return nil, fmt.Errorf("%w: negative width or height", ErrInvalidShape)
+		}
+		return &rectangle{corner: fromJSON(d.Corner), l: d.Width, b: d.Height}, nil
+	case "triangle":
+		if len(points) != 3 {
+			return nil, fmt.Errorf("%w: a triangle has 3 points, not %d", ErrInvalidShape, len(points))
+		}
+		return &triangle{points[0], points[1], points[2]}, nil
+	case "polygon":
+		if len(points) < 3 {
+			return nil, fmt.Errorf("%w: a polygon has at least 3 points, not %d", ErrInvalidShape, len(points))
+		}
+		return &polygon{points: points}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownShape, d.Type)
+	}
+}
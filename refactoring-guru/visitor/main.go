@@ -4,19 +4,42 @@ import "fmt"
 
 func main() {
 	square := &square{side: 2}
-	circle := &circle{radius: 3}
-	rectangle := &rectangle{l: 2, b: 3}
+	circle := &circle{center: point{5, 1}, radius: 3}
+	rectangle := &rectangle{corner: point{-4, 0}, l: 2, b: 3}
+	triangle := &triangle{point{0, 0}, point{4, 0}, point{0, 3}}
+	polygon := &polygon{points: []point{{0, 0}, {4, 0}, {4, 4}, {2, 6}, {0, 4}}}
+	shapes := []shape{square, circle, rectangle, triangle, polygon}
 
 	areaCalculator := &areaCalculator{}
+	perimeterCalculator := &perimeterCalculator{}
+	boundingBox := &boundingBox{}
+	middleCoordinates := &middleCoordinates{}
+	for _, s := range shapes {
+		s.accept(areaCalculator)
+		s.accept(perimeterCalculator)
+		s.accept(boundingBox)
+		s.accept(middleCoordinates)
+		fmt.Printf("%-9s area %7.3f, perimeter %7.3f, bounding box %v-%v, centroid (%.3f, %.3f)\n",
+			s.getType(), areaCalculator.area, perimeterCalculator.perimeter,
+			boundingBox.box.min, boundingBox.box.max, middleCoordinates.x, middleCoordinates.y)
+	}
 
-	square.accept(areaCalculator)
-	circle.accept(areaCalculator)
-	rectangle.accept(areaCalculator)
+	fmt.Println()
+	svgRenderer := &svgRenderer{}
+	for _, s := range shapes {
+		s.accept(svgRenderer)
+	}
+	fmt.Print(svgRenderer.document())
 
 	fmt.Println()
-	middleCoordinates := &middleCoordinates{}
-	square.accept(middleCoordinates)
-	circle.accept(middleCoordinates)
-	rectangle.accept(middleCoordinates)
+	data, err := encodeShapes(shapes...)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(data))
+	decoded, err := decodeShapes(data)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("Decoded %d shapes\n", len(decoded))
 }
-
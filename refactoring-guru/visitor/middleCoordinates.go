@@ -1,21 +1,31 @@
 package main
 
-import "fmt"
-
+// middleCoordinates is the centroid of a shape
 type middleCoordinates struct {
-	x int
-	y int
+	x float64
+	y float64
+}
+
+func (a *middleCoordinates) set(p point) {
+	a.x, a.y = p.x, p.y
 }
 
 func (a *middleCoordinates) visitForSquare(s *square) {
-	// Calculate middle point coordinates for square.
-	// Then assign in to the x and y instance variable.
-	fmt.Println("Calculating middle point coordinates for square")
+	a.set(point{s.corner.x + s.side/2, s.corner.y + s.side/2})
 }
 
 func (a *middleCoordinates) visitForCircle(c *circle) {
-	fmt.Println("Calculating middle point coordinates for circle")
+	a.set(c.center)
+}
+
+func (a *middleCoordinates) visitForrectangle(r *rectangle) {
+	a.set(point{r.corner.x + r.l/2, r.corner.y + r.b/2})
+}
+
+func (a *middleCoordinates) visitForTriangle(t *triangle) {
+	a.set(centroidOf(t.vertices()))
 }
-func (a *middleCoordinates) visitForrectangle(t *rectangle) {
-	fmt.Println("Calculating middle point coordinates for rectangle")
+
+func (a *middleCoordinates) visitForPolygon(p *polygon) {
+	a.set(centroidOf(p.points))
 }
@@ -0,0 +1,27 @@
+package main
+
+import "math"
+
+type perimeterCalculator struct {
+	perimeter float64
+}
+
+func (a *perimeterCalculator) visitForSquare(s *square) {
+	a.perimeter = 4 * s.side
+}
+
+func (a *perimeterCalculator) visitForCircle(c *circle) {
+	a.perimeter = 2 * math.Pi * c.radius
+}
+
+func (a *perimeterCalculator) visitForrectangle(r *rectangle) {
+	a.perimeter = 2 * (r.l + r.b)
+}
+
+func (a *perimeterCalculator) visitForTriangle(t *triangle) {
+	a.perimeter = perimeterOf(t.vertices())
+}
+
+func (a *perimeterCalculator) visitForPolygon(p *polygon) {
+	a.perimeter = perimeterOf(p.points)
+}
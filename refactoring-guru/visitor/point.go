@@ -0,0 +1,78 @@
+package main
+
+import "math"
+
+type point struct {
+	x, y float64
+}
+
+func (p point) distance(q point) float64 {
+	return math.Hypot(q.x-p.x, q.y-p.y)
+}
+
+// box is an axis-aligned rectangle, from its lower-left to its upper-right corner
+type box struct {
+	min, max point
+}
+
+func (b box) union(o box) box {
+	return box{
+		min: point{math.Min(b.min.x, o.min.x), math.Min(b.min.y, o.min.y)},
+		max: point{math.Max(b.max.x, o.max.x), math.Max(b.max.y, o.max.y)},
+	}
+}
+
+// boxOf is the smallest box around the points, or the empty box at the origin when there are none
+func boxOf(points []point) box {
+	if len(points) == 0 {
+		return box{}
+	}
+	b := box{points[0], points[0]}
+	for _, p := range points[1:] {
+		b = b.union(box{p, p})
+	}
+	return b
+}
+
+// signedArea is positive when the vertices go counterclockwise (shoelace formula)
+func signedArea(points []point) float64 {
+	sum := 0.0
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		sum += p.x*q.y - q.x*p.y
+	}
+	return sum / 2
+}
+
+func perimeterOf(points []point) float64 {
+	sum := 0.0
+	for i, p := range points {
+		sum += p.distance(points[(i+1)%len(points)])
+	}
+	return sum
+}
+
+// centroidOf is the center of mass of the polygon, the mean of its vertices when it has no area,
+// or the origin when it has no vertices
+func centroidOf(points []point) point {
+	if len(points) == 0 {
+		return point{}
+	}
+	a := signedArea(points)
+	if a == 0 {
+		var c point
+		for _, p := range points {
+			c.x += p.x
+			c.y += p.y
+		}
+		return point{c.x / float64(len(points)), c.y / float64(len(points))}
+	}
+	var c point
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		cross := p.x*q.y - q.x*p.y
+		c.x += (p.x + q.x) * cross
+		c.y += (p.y + q.y) * cross
+	}
+	return point{c.x / (6 * a), c.y / (6 * a)}
+}
@@ -0,0 +1,14 @@
+package main
+
+// polygon is a simple polygon: its edges join consecutive points, and the last point to the first
+type polygon struct {
+	points []point
+}
+
+func (p *polygon) accept(v visitor) {
+	v.visitForPolygon(p)
+}
+
+func (p *polygon) getType() string {
+	return "Polygon"
+}
@@ -1,8 +1,10 @@
 package main
 
+// rectangle is placed by its lower-left corner, l along the x axis and b along the y axis
 type rectangle struct {
-	l int
-	b int
+	corner point
+	l      float64
+	b      float64
 }
 
 func (t *rectangle) accept(v visitor) {
@@ -12,3 +14,8 @@ func (t *rectangle) accept(v visitor) {
 func (t *rectangle) getType() string {
 	return "rectangle"
 }
+
+func (t *rectangle) vertices() []point {
+	c := t.corner
+	return []point{c, {c.x + t.l, c.y}, {c.x + t.l, c.y + t.b}, {c.x, c.y + t.b}}
+}
@@ -1,7 +1,9 @@
 package main
 
+// square is placed by its lower-left corner
 type square struct {
-	side int
+	corner point
+	side   float64
 }
 
 func (s *square) accept(v visitor) {
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// svgRenderer draws every shape it visits, and frames them all in document
+type svgRenderer struct {
+	elements []string
+	bounds   box
+}
+
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func (a *svgRenderer) add(s shape, element string) {
+	b := &boundingBox{}
+	s.accept(b)
+	if len(a.elements) == 0 {
+		a.bounds = b.box
+	} else {
+		a.bounds = a.bounds.union(b.box)
+	}
+	a.elements = append(a.elements, element)
+}
+
+// addPolygon skips polygons without points, which have nothing to draw nor any bounds to frame
+func (a *svgRenderer) addPolygon(s shape, points []point) {
+	if len(points) == 0 {
+		return
+	}
+	coords := make([]string, len(points))
+	for i, p := range points {
+		coords[i] = num(p.x) + "," + num(p.y)
+	}
+	a.add(s, fmt.Sprintf(`<polygon points="%s"/>`, strings.Join(coords, " ")))
+}
+
+func (a *svgRenderer) visitForSquare(s *square) {
+	a.add(s, fmt.Sprintf(`<rect x="%s" y="%s" width="%s" height="%s"/>`, num(s.corner.x), num(s.corner.y), num(s.side), num(s.side)))
+}
+
+func (a *svgRenderer) visitForCircle(c *circle) {
+	a.add(c, fmt.Sprintf(`<circle cx="%s" cy="%s" r="%s"/>`, num(c.center.x), num(c.center.y), num(c.radius)))
+}
+
+func (a *svgRenderer) visitForrectangle(r *rectangle) {
+	a.add(r, fmt.Sprintf(`<rect x="%s" y="%s" width="%s" height="%s"/>`, num(r.corner.x), num(r.corner.y), num(r.l), num(r.b)))
+}
+
+func (a *svgRenderer) visitForTriangle(t *triangle) {
+	a.addPolygon(t, t.vertices())
+}
+
+func (a *svgRenderer) visitForPolygon(p *polygon) {
+	a.addPolygon(p, p.points)
+}
+
+// document is an SVG image of the shapes visited so far. Shapes use y pointing up, so the drawing is flipped.
+func (a *svgRenderer) document() string {
+	var sb strings.Builder
+	min, max := a.bounds.min, a.bounds.max
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="%s %s %s %s">`+"\n",
+		num(min.x), num(min.y), num(max.x-min.x), num(max.y-min.y))
+	fmt.Fprintf(&sb, `<g transform="matrix(1 0 0 -1 0 %s)" fill="none" stroke="black" stroke-width="0.1">`+"\n", num(min.y+max.y))
+	for _, e := range a.elements {
+		sb.WriteString(e + "\n")
+	}
+	sb.WriteString("</g>\n</svg>\n")
+	return sb.String()
+}
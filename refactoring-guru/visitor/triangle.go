@@ -0,0 +1,17 @@
+package main
+
+type triangle struct {
+	a, b, c point
+}
+
+func (t *triangle) accept(v visitor) {
+	v.visitForTriangle(t)
+}
+
+func (t *triangle) getType() string {
+	return "Triangle"
+}
+
+func (t *triangle) vertices() []point {
+	return []point{t.a, t.b, t.c}
+}
@@ -4,4 +4,6 @@ type visitor interface {
 	visitForSquare(*square)
 	visitForCircle(*circle)
 	visitForrectangle(*rectangle)
+	visitForTriangle(*triangle)
+	visitForPolygon(*polygon)
 }
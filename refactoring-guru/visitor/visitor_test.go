@@ -0,0 +1,111 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeometry(t *testing.T) {
+	tests := []struct {
+		shape     shape
+		area      float64
+		perimeter float64
+		box       box
+		centroid  point
+	}{
+		{&square{corner: point{1, 1}, side: 2}, 4, 8, box{point{1, 1}, point{3, 3}}, point{2, 2}},
+		{&circle{center: point{5, -1}, radius: 2}, 4 * math.Pi, 4 * math.Pi, box{point{3, -3}, point{7, 1}}, point{5, -1}},
+		{&rectangle{corner: point{-4, 0}, l: 2, b: 3}, 6, 10, box{point{-4, 0}, point{-2, 3}}, point{-3, 1.5}},
+		{&triangle{point{0, 0}, point{4, 0}, point{0, 3}}, 6, 12, box{point{0, 0}, point{4, 3}}, point{4.0 / 3, 1}},
+		// clockwise vertices give the same area
+		{&triangle{point{0, 0}, point{0, 3}, point{4, 0}}, 6, 12, box{point{0, 0}, point{4, 3}}, point{4.0 / 3, 1}},
+		// an L made of a 2x1 and a 1x1 block
+		{&polygon{points: []point{{0, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 2}, {0, 2}}}, 3, 8, box{point{0, 0}, point{2, 2}}, point{5.0 / 6, 5.0 / 6}},
+		// collinear points have no area, and their centroid is the mean of the vertices
+		{&polygon{points: []point{{0, 0}, {1, 0}, {5, 0}}}, 0, 10, box{point{0, 0}, point{5, 0}}, point{2, 0}},
+		// polygons built without the JSON decoder may have too few points
+		{&polygon{points: []point{{3, 4}}}, 0, 0, box{point{3, 4}, point{3, 4}}, point{3, 4}},
+		{&polygon{}, 0, 0, box{}, point{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.shape.getType(), func(t *testing.T) {
+			area, perimeter, bounds, middle := &areaCalculator{}, &perimeterCalculator{}, &boundingBox{}, &middleCoordinates{}
+			for _, v := range []visitor{area, perimeter, bounds, middle} {
+				test.shape.accept(v)
+			}
+
+			assert.InDelta(t, test.area, area.area, 1e-9)
+			assert.InDelta(t, test.perimeter, perimeter.perimeter, 1e-9)
+			assert.Equal(t, test.box, bounds.box)
+			assert.InDelta(t, test.centroid.x, middle.x, 1e-9)
+			assert.InDelta(t, test.centroid.y, middle.y, 1e-9)
+		})
+	}
+}
+
+func TestSVG(t *testing.T) {
+	r := &svgRenderer{}
+	(&square{corner: point{0, 0}, side: 2}).accept(r)
+	(&circle{center: point{5, 1}, radius: 1.5}).accept(r)
+	(&triangle{point{0, 0}, point{1, -1}, point{0, 1}}).accept(r)
+	(&polygon{}).accept(r)
+
+	assert.Equal(t, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 -1 6.5 3.5">
+<g transform="matrix(1 0 0 -1 0 1.5)" fill="none" stroke="black" stroke-width="0.1">
+<rect x="0" y="0" width="2" height="2"/>
+<circle cx="5" cy="1" r="1.5"/>
+<polygon points="0,0 1,-1 0,1"/>
+</g>
+</svg>
+`, r.document())
+}
+
+func TestJSON(t *testing.T) {
+	t.Run("round trips every shape", func(t *testing.T) {
+		shapes := []shape{
+			&square{corner: point{1, 2}, side: 3},
+			&circle{center: point{-1, 0.5}, radius: 2},
+			&rectangle{corner: point{0, 0}, l: 4, b: 1},
+			&triangle{point{0, 0}, point{1, 0}, point{0, 1}},
+			&polygon{points: []point{{0, 0}, {2, 0}, {2, 2}, {0, 2}}},
+		}
+
+		data, err := encodeShapes(shapes...)
+		assert.NoError(t, err)
+		decoded, err := decodeShapes(data)
+
+		assert.NoError(t, err)
+		assert.Equal(t, shapes, decoded)
+	})
+
+	t.Run("encodes no shapes as an empty array", func(t *testing.T) {
+		data, err := encodeShapes()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "[]", string(data))
+	})
+
+	t.Run("rejects invalid shapes", func(t *testing.T) {
+		tests := map[string]error{
+			`[{"type":"hexagon"}]`:                                        ErrUnknownShape,
+			`[{"type":"circle","center":[0,0],"radius":-1}]`:              ErrInvalidShape,
+			`[{"type":"square","corner":[0,0],"side":-1}]`:                ErrInvalidShape,
+			`[{"type":"rectangle","corner":[0,0],"width":1,"height":-1}]`: ErrInvalidShape,
+			`[{"type":"circle","radius":1}]`:                              ErrInvalidShape,
+			`[{"type":"square","side":1}]`:                                ErrInvalidShape,
+			`[{"type":"rectangle","width":1,"height":1}]`:                 ErrInvalidShape,
+			`[{"type":"triangle","points":[[0,0],[1,1]]}]`:                ErrInvalidShape,
+			`[{"type":"polygon","points":[[0,0],[1,1]]}]`:                 ErrInvalidShape,
+		}
+		for data, want := range tests {
+			_, err := decodeShapes([]byte(data))
+			assert.ErrorIs(t, err, want, data)
+		}
+
+		_, err := decodeShapes([]byte(`{"type":"circle"}`))
+		assert.Error(t, err)
+	})
+}